@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/kingxl111/merch-store/internal/config"
+	reconcilesrv "github.com/kingxl111/merch-store/internal/reconcile/service"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+)
+
+// exitDiscrepancies is the exit code when some balances do not add up and
+// were left as they are.
+const exitDiscrepancies = 2
+
+var (
+	configPath string
+	fix        bool
+	yes        bool
+	reason     string
+)
+
+func init() {
+	flag.StringVar(&configPath, "config-path", ".env", "path to config file")
+	flag.BoolVar(&fix, "fix", false, "correct the balances that do not add up")
+	flag.BoolVar(&yes, "yes", false, "correct the balances without asking for confirmation")
+	flag.StringVar(&reason, "reason", "reconciliation", "reason recorded with the corrections")
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	code, err := runMain(ctx)
+	if err != nil {
+		slog.Error("run main", slog.Any("err", err))
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func runMain(ctx context.Context) (int, error) {
+	flag.Parse()
+
+	if err := config.Load(configPath); err != nil {
+		return 0, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	pgConfig, err := config.NewPGConfig()
+	if err != nil {
+		return 0, fmt.Errorf("pg config: %w", err)
+	}
+
+	db, err := postgres.NewDB(
+		pgConfig.Username,
+		pgConfig.Password,
+		pgConfig.Host,
+		pgConfig.Port,
+		pgConfig.DBName,
+		pgConfig.SSLMode,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("db init: %w", err)
+	}
+	defer db.Close()
+
+	srv := reconcilesrv.NewReconcileService(postgres.NewRepository(db))
+
+	report, err := srv.Check(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("check balances: %w", err)
+	}
+	if err := printJSON(report); err != nil {
+		return 0, err
+	}
+	if len(report.Discrepancies) == 0 {
+		return 0, nil
+	}
+	if !fix {
+		return exitDiscrepancies, nil
+	}
+
+	if !yes {
+		confirmed, err := confirm(fmt.Sprintf("Correct the balances of %d users?", len(report.Discrepancies)))
+		if err != nil {
+			return 0, err
+		}
+		if !confirmed {
+			return exitDiscrepancies, nil
+		}
+	}
+
+	corrections := srv.Correct(ctx, report, reason)
+	if err := printJSON(corrections); err != nil {
+		return 0, err
+	}
+	for _, c := range corrections {
+		if c.Error != "" {
+			return exitDiscrepancies, nil
+		}
+	}
+	return 0, nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode output: %w", err)
+	}
+	return nil
+}
+
+// confirm asks the operator on stderr, so that stdout stays valid JSON. No
+// answer, as when stdin is not a terminal, is a no.
+func confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
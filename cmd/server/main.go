@@ -13,10 +13,15 @@ import (
 
 	"golang.org/x/sync/errgroup"
 
+	coinrequests "github.com/kingxl111/merch-store/internal/coinrequest/service"
 	"github.com/kingxl111/merch-store/internal/config"
 	env "github.com/kingxl111/merch-store/internal/environment"
+	escrow "github.com/kingxl111/merch-store/internal/escrow/service"
 	httpserver "github.com/kingxl111/merch-store/internal/gates/http-server"
+	"github.com/kingxl111/merch-store/internal/notifier"
+	reconcile "github.com/kingxl111/merch-store/internal/reconcile/service"
 	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	schedule "github.com/kingxl111/merch-store/internal/schedule/service"
 	shop "github.com/kingxl111/merch-store/internal/shop/service"
 	usrs "github.com/kingxl111/merch-store/internal/users/service"
 	merchstoreapi "github.com/kingxl111/merch-store/pkg/api/merch-store"
@@ -77,18 +82,96 @@ func runMain(ctx context.Context) error {
 	var h slog.Handler = slog.NewTextHandler(os.Stdout, handleOpts)
 	logger := slog.New(h)
 
+	authConfig, err := config.NewAuthConfig()
+	if err != nil {
+		return fmt.Errorf("auth config: %w", err)
+	}
+
+	tokenManager, err := usrs.NewTokenManager(authConfig.SigningKeys(), authConfig.ActiveKeyID(), authConfig.TokenTTL())
+	if err != nil {
+		return fmt.Errorf("token manager: %w", err)
+	}
+
+	notifierConfig, err := config.NewNotifierConfig()
+	if err != nil {
+		return fmt.Errorf("notifier config: %w", err)
+	}
+
+	var userNotifier usrs.Notifier
+	switch notifierConfig.Kind() {
+	case config.NotifierFile:
+		userNotifier = notifier.NewFileNotifier(notifierConfig.FilePath())
+	default:
+		userNotifier = notifier.NewLogNotifier(logger)
+	}
+
+	offboardingConfig, err := config.NewOffboardingConfig()
+	if err != nil {
+		return fmt.Errorf("offboarding config: %w", err)
+	}
+
+	usernameConfig, err := config.NewUsernameConfig()
+	if err != nil {
+		return fmt.Errorf("username config: %w", err)
+	}
+
+	reconcileConfig, err := config.NewReconcileConfig()
+	if err != nil {
+		return fmt.Errorf("reconcile config: %w", err)
+	}
+
+	scheduleConfig, err := config.NewScheduleConfig()
+	if err != nil {
+		return fmt.Errorf("schedule config: %w", err)
+	}
+
+	coinRequestConfig, err := config.NewCoinRequestConfig()
+	if err != nil {
+		return fmt.Errorf("coin request config: %w", err)
+	}
+
+	escrowConfig, err := config.NewEscrowConfig()
+	if err != nil {
+		return fmt.Errorf("escrow config: %w", err)
+	}
+
 	repo := postgres.NewRepository(db)
 	shopSrv := shop.NewShopService(repo)
-	userSrv := usrs.NewUserService(repo, repo)
+	userSrv := usrs.NewUserService(repo, repo, tokenManager, userNotifier, usrs.Config{
+		RefreshTokenTTL:       authConfig.RefreshTokenTTL(),
+		AutoRegister:          authConfig.AutoRegister(),
+		OffboardingSink:       offboardingConfig.SinkUsername(),
+		UsernameCooldown:      usernameConfig.Cooldown(),
+		UsernameGracePeriod:   usernameConfig.GracePeriod(),
+		RouteRenamedTransfers: usernameConfig.RouteRenamed(),
+		TransferHoldWindow:    escrowConfig.HoldWindow(),
+	})
+
+	scheduleSrv := schedule.NewScheduleService(repo, userSrv)
+	coinRequestSrv := coinrequests.NewCoinRequestService(repo, coinRequestConfig.TTL())
+	escrowSrv := escrow.NewEscrowService(repo)
+
+	if err := userSrv.EnsureAdmins(ctx, authConfig.AdminUsernames()); err != nil {
+		return fmt.Errorf("bootstrap admins: %w", err)
+	}
 
 	httpServerConfig, err := config.NewHTTPConfig()
 	if err != nil {
 		return fmt.Errorf("http server config error: %w", err)
 	}
 
+	spec, err := merchstoreapi.GetSwagger()
+	if err != nil {
+		return fmt.Errorf("load openapi spec: %w", err)
+	}
+
 	var opts env.ServerOptions
 	opts.WithLogger(logger)
-	handler := httpserver.NewHandler(userSrv, shopSrv)
+	opts.WithAuthenticator(userSrv)
+	if err := opts.WithOpenAPISpec(spec); err != nil {
+		return fmt.Errorf("http server security: %w", err)
+	}
+	handler := httpserver.NewHandler(userSrv, shopSrv, scheduleSrv, coinRequestSrv, escrowSrv, tokenManager)
 	mux := http.NewServeMux()
 	apiHandler := merchstoreapi.HandlerFromMux(handler, mux)
 	httpServer := opts.NewServer(apiHandler, httpServerConfig.Address())
@@ -103,6 +186,24 @@ func runMain(ctx context.Context) error {
 		return nil
 	})
 
+	eg.Go(func() error {
+		logger.Info("running scheduled transfers every " + scheduleConfig.PollInterval().String())
+		return scheduleSrv.Work(ctx, scheduleConfig.PollInterval())
+	})
+
+	eg.Go(func() error {
+		logger.Info("settling delayed transfers every " + escrowConfig.SettleInterval().String())
+		return escrowSrv.Work(ctx, escrowConfig.SettleInterval())
+	})
+
+	if interval := reconcileConfig.Interval(); interval > 0 {
+		reconcileSrv := reconcile.NewReconcileService(repo)
+		eg.Go(func() error {
+			logger.Info("checking balances every " + interval.String())
+			return reconcileSrv.Watch(ctx, interval)
+		})
+	}
+
 	eg.Go(func() error {
 		<-ctx.Done()
 		logger.Info("shutting down server...")
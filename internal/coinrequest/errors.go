@@ -0,0 +1,17 @@
+package coinrequest
+
+import "errors"
+
+// Errors of the transfer that pays a request, such as insufficient funds,
+// are those of the users package.
+var (
+	ErrorService         = errors.New("coin request service error")
+	ErrorInvalidReason   = errors.New("reason must be 1-200 characters")
+	ErrorSelfRequest     = errors.New("cannot request coins from yourself")
+	ErrorPayerNotFound   = errors.New("payer not found")
+	ErrorPayerClosed     = errors.New("payer account is closed")
+	ErrorTooManyRequests = errors.New("too many pending coin requests")
+	ErrorNotFound        = errors.New("coin request not found")
+	ErrorExpired         = errors.New("coin request has expired")
+	ErrorResolved        = errors.New("coin request is already paid or declined")
+)
@@ -0,0 +1,36 @@
+package coinrequest
+
+import "time"
+
+// Statuses of a coin request.
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusDeclined = "declined"
+	StatusExpired  = "expired"
+)
+
+// MaxReasonLen is the maximum length of the reason of a request, in
+// characters. The reason becomes the message of the transfer that pays it.
+const MaxReasonLen = 200
+
+// CoinRequest asks Payer to send Amount coins to Requester. A pending
+// request expires at ExpiresAt; ResolvedAt is when it was paid or declined.
+type CoinRequest struct {
+	ID         string
+	Requester  string
+	Payer      string
+	Amount     int
+	Reason     string
+	Status     string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ResolvedAt *time.Time
+}
+
+// CreateRequest asks Payer for coins on behalf of the caller.
+type CreateRequest struct {
+	Payer  string
+	Amount int
+	Reason string
+}
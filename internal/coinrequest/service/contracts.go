@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+)
+
+type Repository interface {
+	CreateCoinRequest(ctx context.Context, c *postgres.CoinRequest, maxPending int) error
+	ListIncomingCoinRequests(ctx context.Context, userID string, limit int) ([]postgres.CoinRequest, error)
+	ListOutgoingCoinRequests(ctx context.Context, userID string, limit int) ([]postgres.CoinRequest, error)
+	ApproveCoinRequest(ctx context.Context, payerID, id string) (*postgres.CoinRequest, error)
+	DeclineCoinRequest(ctx context.Context, payerID, id string) (*postgres.CoinRequest, error)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/coinrequest"
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	// maxPendingRequests is how many pending requests a user can have made.
+	maxPendingRequests = 20
+	// listLimit is how many requests are listed.
+	listLimit = 100
+)
+
+type coinRequestService struct {
+	repo Repository
+	ttl  time.Duration
+}
+
+// NewCoinRequestService returns a service whose requests expire ttl after
+// they are made.
+func NewCoinRequestService(repo Repository, ttl time.Duration) *coinRequestService {
+	return &coinRequestService{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Create asks another user for coins on behalf of the caller.
+func (s *coinRequestService) Create(ctx context.Context, principal *users.Principal, req *coinrequest.CreateRequest) (*coinrequest.CoinRequest, error) {
+	if req.Amount <= 0 {
+		return nil, users.ErrorInvalidAmount
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) == 0 || utf8.RuneCountInString(reason) > coinrequest.MaxReasonLen {
+		return nil, coinrequest.ErrorInvalidReason
+	}
+	if strings.EqualFold(req.Payer, principal.Username) {
+		return nil, coinrequest.ErrorSelfRequest
+	}
+
+	c := &postgres.CoinRequest{
+		RequesterID:   principal.UserID,
+		PayerUsername: req.Payer,
+		Amount:        req.Amount,
+		Reason:        reason,
+		ExpiresAt:     time.Now().Add(s.ttl),
+	}
+	if err := s.repo.CreateCoinRequest(ctx, c, maxPendingRequests); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorPayerNotFound):
+			return nil, coinrequest.ErrorPayerNotFound
+		case errors.Is(err, repository.ErrorPayerClosed):
+			return nil, coinrequest.ErrorPayerClosed
+		case errors.Is(err, repository.ErrorTooManyCoinRequests):
+			return nil, coinrequest.ErrorTooManyRequests
+		case errors.Is(err, repository.ErrorAccountClosed):
+			return nil, users.ErrorAccountClosed
+		default:
+			return nil, coinrequest.ErrorService
+		}
+	}
+	return coinRequest(c), nil
+}
+
+// Incoming returns the pending requests the caller is asked to pay, oldest
+// first.
+func (s *coinRequestService) Incoming(ctx context.Context, principal *users.Principal) ([]coinrequest.CoinRequest, error) {
+	list, err := s.repo.ListIncomingCoinRequests(ctx, principal.UserID, listLimit)
+	if err != nil {
+		return nil, coinrequest.ErrorService
+	}
+	return coinRequests(list), nil
+}
+
+// Outgoing returns the latest requests made by the caller, newest first.
+func (s *coinRequestService) Outgoing(ctx context.Context, principal *users.Principal) ([]coinrequest.CoinRequest, error) {
+	list, err := s.repo.ListOutgoingCoinRequests(ctx, principal.UserID, listLimit)
+	if err != nil {
+		return nil, coinrequest.ErrorService
+	}
+	return coinRequests(list), nil
+}
+
+// Approve pays a pending request the caller is asked to pay. The transfer
+// and marking the request paid happen at once, so a request is never paid
+// twice or marked paid without the coins having moved.
+func (s *coinRequestService) Approve(ctx context.Context, principal *users.Principal, id string) (*coinrequest.CoinRequest, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, coinrequest.ErrorNotFound
+	}
+
+	c, err := s.repo.ApproveCoinRequest(ctx, principal.UserID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorInsFunds):
+			return nil, users.ErrorInsufFunds
+		case errors.Is(err, repository.ErrorAccountFrozen):
+			return nil, users.ErrorAccountFrozen
+		case errors.Is(err, repository.ErrorAccountSuspended):
+			return nil, users.ErrorAccountSuspended
+		case errors.Is(err, repository.ErrorAccountClosed):
+			return nil, users.ErrorAccountClosed
+		case errors.Is(err, repository.ErrorReceiverClosed), errors.Is(err, repository.ErrorReceiverNotFound):
+			return nil, users.ErrorReceiverClosed
+		default:
+			return nil, resolveError(err)
+		}
+	}
+
+	slog.Info("coin request paid",
+		slog.String("request", c.ID),
+		slog.String("payer", c.PayerUsername),
+		slog.String("requester", c.RequesterUsername),
+		slog.Int("amount", c.Amount),
+	)
+	return coinRequest(c), nil
+}
+
+// Decline declines a pending request the caller is asked to pay.
+func (s *coinRequestService) Decline(ctx context.Context, principal *users.Principal, id string) (*coinrequest.CoinRequest, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, coinrequest.ErrorNotFound
+	}
+
+	c, err := s.repo.DeclineCoinRequest(ctx, principal.UserID, id)
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	return coinRequest(c), nil
+}
+
+func resolveError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrorCoinRequestNotFound):
+		return coinrequest.ErrorNotFound
+	case errors.Is(err, repository.ErrorCoinRequestExpired):
+		return coinrequest.ErrorExpired
+	case errors.Is(err, repository.ErrorCoinRequestResolved):
+		return coinrequest.ErrorResolved
+	default:
+		return coinrequest.ErrorService
+	}
+}
+
+func coinRequests(list []postgres.CoinRequest) []coinrequest.CoinRequest {
+	requests := make([]coinrequest.CoinRequest, 0, len(list))
+	for i := range list {
+		requests = append(requests, *coinRequest(&list[i]))
+	}
+	return requests
+}
+
+func coinRequest(c *postgres.CoinRequest) *coinrequest.CoinRequest {
+	return &coinrequest.CoinRequest{
+		ID:         c.ID,
+		Requester:  c.RequesterUsername,
+		Payer:      c.PayerUsername,
+		Amount:     c.Amount,
+		Reason:     c.Reason,
+		Status:     c.Status,
+		CreatedAt:  c.CreatedAt,
+		ExpiresAt:  c.ExpiresAt,
+		ResolvedAt: c.ResolvedAt,
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ AuthConfig = (*authConfig)(nil)
+
+const (
+	jwtSigningKeysEnvName = "JWT_SIGNING_KEYS"
+	jwtPrivateKeysEnvName = "JWT_PRIVATE_KEYS"
+	jwtActiveKeyIDEnvName = "JWT_ACTIVE_KEY_ID"
+	jwtTokenTTLEnvName    = "JWT_TOKEN_TTL"
+	jwtRefreshTTLEnvName  = "JWT_REFRESH_TOKEN_TTL"
+	autoRegisterEnvName   = "AUTH_AUTO_REGISTER"
+	adminUsernamesEnvName = "ADMIN_USERNAMES"
+
+	defaultTokenTTL        = time.Minute * 15
+	defaultRefreshTokenTTL = time.Hour * 24 * 30
+
+	// minSecretLen is the shortest HMAC secret accepted, as long as the
+	// output of SHA-256.
+	minSecretLen = 32
+)
+
+// SigningKey is a named key used to sign and verify JWTs. Exactly one of
+// Secret (HMAC) and PrivateKey (Ed25519 or RSA) is set.
+type SigningKey struct {
+	ID         string
+	Secret     []byte
+	PrivateKey crypto.Signer
+}
+
+type AuthConfig interface {
+	SigningKeys() []SigningKey
+	ActiveKeyID() string
+	TokenTTL() time.Duration
+	RefreshTokenTTL() time.Duration
+	AutoRegister() bool
+	AdminUsernames() []string
+}
+
+type authConfig struct {
+	keys            []SigningKey
+	activeKeyID     string
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+	autoRegister    bool
+	adminUsernames  []string
+}
+
+// NewAuthConfig reads the JWT key ring from the environment.
+//
+// JWT_SIGNING_KEYS is a comma separated list of kid:base64-secret pairs for
+// HMAC keys and JWT_PRIVATE_KEYS a list of kid:path pairs pointing to PEM
+// encoded Ed25519 or RSA private keys. JWT_ACTIVE_KEY_ID selects the key used
+// for new tokens and defaults to the first configured key; the remaining keys
+// are only used for verification, so a rotated key can stay configured until
+// the tokens it signed expire.
+func NewAuthConfig() (AuthConfig, error) {
+	secretKeys, err := parseSigningKeys(os.Getenv(jwtSigningKeysEnvName))
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeys, err := parsePrivateKeys(os.Getenv(jwtPrivateKeysEnvName))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := append(privateKeys, secretKeys...)
+	if len(keys) == 0 {
+		return nil, errors.New("jwt signing keys not found")
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate signing key id %q", key.ID)
+		}
+		seen[key.ID] = struct{}{}
+	}
+
+	activeKeyID := os.Getenv(jwtActiveKeyIDEnvName)
+	if len(activeKeyID) == 0 {
+		activeKeyID = keys[0].ID
+	}
+
+	if _, ok := seen[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", activeKeyID)
+	}
+
+	tokenTTL, err := parseDuration(jwtTokenTTLEnvName, defaultTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenTTL, err := parseDuration(jwtRefreshTTLEnvName, defaultRefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown usernames are registered on login unless switched off, so
+	// clients written before /api/register keep working.
+	autoRegister := true
+	if value := os.Getenv(autoRegisterEnvName); len(value) != 0 {
+		autoRegister, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", autoRegisterEnvName, err)
+		}
+	}
+
+	var adminUsernames []string
+	for _, username := range strings.Split(os.Getenv(adminUsernamesEnvName), ",") {
+		if username = strings.TrimSpace(username); len(username) != 0 {
+			adminUsernames = append(adminUsernames, username)
+		}
+	}
+
+	return &authConfig{
+		keys:            keys,
+		activeKeyID:     activeKeyID,
+		tokenTTL:        tokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		autoRegister:    autoRegister,
+		adminUsernames:  adminUsernames,
+	}, nil
+}
+
+// parseDuration reads a positive duration from the environment variable,
+// falling back to def when it is not set.
+func parseDuration(envName string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(envName)
+	if len(value) == 0 {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", envName, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", envName)
+	}
+	return d, nil
+}
+
+func parseSigningKeys(value string) ([]SigningKey, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var keys []SigningKey
+	for _, entry := range strings.Split(value, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || len(id) == 0 || len(encoded) == 0 {
+			return nil, fmt.Errorf("invalid signing key entry %q, expected kid:base64-secret", entry)
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode signing key %q: %w", id, err)
+		}
+		if len(secret) < minSecretLen {
+			return nil, fmt.Errorf("signing key %q is %d bytes long, at least %d are required", id, len(secret), minSecretLen)
+		}
+		keys = append(keys, SigningKey{ID: id, Secret: secret})
+	}
+
+	return keys, nil
+}
+
+func parsePrivateKeys(value string) ([]SigningKey, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var keys []SigningKey
+	for _, entry := range strings.Split(value, ",") {
+		id, path, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || len(id) == 0 || len(path) == 0 {
+			return nil, fmt.Errorf("invalid private key entry %q, expected kid:path", entry)
+		}
+
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load private key %q: %w", id, err)
+		}
+		keys = append(keys, SigningKey{ID: id, PrivateKey: key})
+	}
+
+	return keys, nil
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func (c *authConfig) SigningKeys() []SigningKey {
+	return c.keys
+}
+
+func (c *authConfig) ActiveKeyID() string {
+	return c.activeKeyID
+}
+
+func (c *authConfig) TokenTTL() time.Duration {
+	return c.tokenTTL
+}
+
+func (c *authConfig) RefreshTokenTTL() time.Duration {
+	return c.refreshTokenTTL
+}
+
+func (c *authConfig) AutoRegister() bool {
+	return c.autoRegister
+}
+
+// AdminUsernames lists users that are granted the admin role on startup.
+func (c *authConfig) AdminUsernames() []string {
+	return c.adminUsernames
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+var _ CoinRequestConfig = (*coinRequestConfig)(nil)
+
+const (
+	coinRequestTTLEnvName = "COIN_REQUEST_TTL"
+
+	defaultCoinRequestTTL = 72 * time.Hour
+)
+
+type CoinRequestConfig interface {
+	TTL() time.Duration
+}
+
+type coinRequestConfig struct {
+	ttl time.Duration
+}
+
+// NewCoinRequestConfig reads COIN_REQUEST_TTL, how long a request for coins
+// can be approved before it expires.
+func NewCoinRequestConfig() (CoinRequestConfig, error) {
+	ttl, err := parseDuration(coinRequestTTLEnvName, defaultCoinRequestTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &coinRequestConfig{ttl: ttl}, nil
+}
+
+func (c *coinRequestConfig) TTL() time.Duration {
+	return c.ttl
+}
@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+var _ EscrowConfig = (*escrowConfig)(nil)
+
+const (
+	transferHoldWindowEnvName     = "TRANSFER_HOLD_WINDOW"
+	transferSettleIntervalEnvName = "TRANSFER_SETTLE_INTERVAL"
+
+	defaultTransferHoldWindow     = 10 * time.Minute
+	defaultTransferSettleInterval = time.Minute
+)
+
+type EscrowConfig interface {
+	HoldWindow() time.Duration
+	SettleInterval() time.Duration
+}
+
+type escrowConfig struct {
+	holdWindow     time.Duration
+	settleInterval time.Duration
+}
+
+// NewEscrowConfig reads TRANSFER_HOLD_WINDOW, how long the sender of a
+// delayed transfer can cancel it, and TRANSFER_SETTLE_INTERVAL, how often
+// the server looks for delayed transfers to settle.
+func NewEscrowConfig() (EscrowConfig, error) {
+	window, err := parseDuration(transferHoldWindowEnvName, defaultTransferHoldWindow)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := parseDuration(transferSettleIntervalEnvName, defaultTransferSettleInterval)
+	if err != nil {
+		return nil, err
+	}
+	return &escrowConfig{
+		holdWindow:     window,
+		settleInterval: interval,
+	}, nil
+}
+
+func (c *escrowConfig) HoldWindow() time.Duration {
+	return c.holdWindow
+}
+
+func (c *escrowConfig) SettleInterval() time.Duration {
+	return c.settleInterval
+}
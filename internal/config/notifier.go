@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var _ NotifierConfig = (*notifierConfig)(nil)
+
+const (
+	notifierEnvName         = "NOTIFIER"
+	notifierFilePathEnvName = "NOTIFIER_FILE_PATH"
+
+	NotifierLog  = "log"
+	NotifierFile = "file"
+)
+
+type NotifierConfig interface {
+	Kind() string
+	FilePath() string
+}
+
+type notifierConfig struct {
+	kind     string
+	filePath string
+}
+
+// NewNotifierConfig reads how users are notified, e.g. of password reset
+// tokens. NOTIFIER is "log" (the default) or "file", which appends to
+// NOTIFIER_FILE_PATH.
+func NewNotifierConfig() (NotifierConfig, error) {
+	kind := os.Getenv(notifierEnvName)
+	if len(kind) == 0 {
+		kind = NotifierLog
+	}
+
+	filePath := os.Getenv(notifierFilePathEnvName)
+	switch kind {
+	case NotifierLog:
+	case NotifierFile:
+		if len(filePath) == 0 {
+			return nil, errors.New("notifier file path not found")
+		}
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", kind)
+	}
+
+	return &notifierConfig{
+		kind:     kind,
+		filePath: filePath,
+	}, nil
+}
+
+func (c *notifierConfig) Kind() string {
+	return c.kind
+}
+
+func (c *notifierConfig) FilePath() string {
+	return c.filePath
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+var _ OffboardingConfig = (*offboardingConfig)(nil)
+
+const offboardingSinkEnvName = "OFFBOARDING_SINK_USERNAME"
+
+type OffboardingConfig interface {
+	SinkUsername() string
+}
+
+type offboardingConfig struct {
+	sinkUsername string
+}
+
+// NewOffboardingConfig reads OFFBOARDING_SINK_USERNAME, the account that
+// receives the remaining coins of offboarded users. Offboarding is refused
+// while it is not set.
+func NewOffboardingConfig() (OffboardingConfig, error) {
+	return &offboardingConfig{
+		sinkUsername: strings.TrimSpace(os.Getenv(offboardingSinkEnvName)),
+	}, nil
+}
+
+func (c *offboardingConfig) SinkUsername() string {
+	return c.sinkUsername
+}
@@ -0,0 +1,30 @@
+package config
+
+import "time"
+
+var _ ReconcileConfig = (*reconcileConfig)(nil)
+
+const reconcileIntervalEnvName = "RECONCILE_INTERVAL"
+
+type ReconcileConfig interface {
+	Interval() time.Duration
+}
+
+type reconcileConfig struct {
+	interval time.Duration
+}
+
+// NewReconcileConfig reads RECONCILE_INTERVAL, how often the server checks
+// that balances match the activity of their users. The check is off while it
+// is not set.
+func NewReconcileConfig() (ReconcileConfig, error) {
+	interval, err := parseDuration(reconcileIntervalEnvName, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &reconcileConfig{interval: interval}, nil
+}
+
+func (c *reconcileConfig) Interval() time.Duration {
+	return c.interval
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+var _ ScheduleConfig = (*scheduleConfig)(nil)
+
+const (
+	schedulePollIntervalEnvName = "SCHEDULE_POLL_INTERVAL"
+
+	defaultSchedulePollInterval = time.Minute
+)
+
+type ScheduleConfig interface {
+	PollInterval() time.Duration
+}
+
+type scheduleConfig struct {
+	pollInterval time.Duration
+}
+
+// NewScheduleConfig reads SCHEDULE_POLL_INTERVAL, how often the server looks
+// for scheduled transfers that are due.
+func NewScheduleConfig() (ScheduleConfig, error) {
+	interval, err := parseDuration(schedulePollIntervalEnvName, defaultSchedulePollInterval)
+	if err != nil {
+		return nil, err
+	}
+	return &scheduleConfig{pollInterval: interval}, nil
+}
+
+func (c *scheduleConfig) PollInterval() time.Duration {
+	return c.pollInterval
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+var _ UsernameConfig = (*usernameConfig)(nil)
+
+const (
+	usernameCooldownEnvName     = "USERNAME_CHANGE_COOLDOWN"
+	usernameGracePeriodEnvName  = "USERNAME_GRACE_PERIOD"
+	usernameRouteRenamedEnvName = "USERNAME_ROUTE_RENAMED"
+
+	defaultUsernameCooldown    = time.Hour * 24 * 30
+	defaultUsernameGracePeriod = time.Hour * 24 * 14
+)
+
+type UsernameConfig interface {
+	Cooldown() time.Duration
+	GracePeriod() time.Duration
+	RouteRenamed() bool
+}
+
+type usernameConfig struct {
+	cooldown     time.Duration
+	gracePeriod  time.Duration
+	routeRenamed bool
+}
+
+// NewUsernameConfig reads the rules for renaming users: the minimum time
+// between renames (USERNAME_CHANGE_COOLDOWN, 30 days by default), how long an
+// old username stays reserved for its owner (USERNAME_GRACE_PERIOD, 14 days)
+// and whether coins sent to it meanwhile reach the new one
+// (USERNAME_ROUTE_RENAMED, true) or are refused.
+func NewUsernameConfig() (UsernameConfig, error) {
+	cooldown, err := parseDuration(usernameCooldownEnvName, defaultUsernameCooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod, err := parseDuration(usernameGracePeriodEnvName, defaultUsernameGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	routeRenamed := true
+	if value := os.Getenv(usernameRouteRenamedEnvName); len(value) != 0 {
+		routeRenamed, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", usernameRouteRenamedEnvName, err)
+		}
+	}
+
+	return &usernameConfig{
+		cooldown:     cooldown,
+		gracePeriod:  gracePeriod,
+		routeRenamed: routeRenamed,
+	}, nil
+}
+
+func (c *usernameConfig) Cooldown() time.Duration {
+	return c.cooldown
+}
+
+func (c *usernameConfig) GracePeriod() time.Duration {
+	return c.gracePeriod
+}
+
+func (c *usernameConfig) RouteRenamed() bool {
+	return c.routeRenamed
+}
@@ -3,19 +3,53 @@ package http
 import (
 	"context"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-faster/errors"
-	"github.com/kingxl111/merch-store/internal/users/service"
+
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	UsernameContextKey  = "username"
+	PrincipalContextKey = "principal"
+
+	APIKeyHeader = "X-API-Key"
 )
 
-const UsernameContextKey = "username"
+// Authenticator validates an access token or an API key and returns the
+// caller it was issued to. CountAPIKeyRequest counts an allowed request made
+// with an API key against its quota, TouchSession records the activity of the
+// caller's session and AuditImpersonatedRequest every request made by an
+// admin impersonating the caller.
+type Authenticator interface {
+	VerifyAccessToken(ctx context.Context, accessToken string) (*users.Principal, error)
+	VerifyAPIKey(ctx context.Context, key string) (*users.Principal, error)
+	CountAPIKeyRequest(ctx context.Context, principal *users.Principal) error
+	TouchSession(ctx context.Context, principal *users.Principal, userAgent, clientIP string) error
+	AuditImpersonatedRequest(ctx context.Context, principal *users.Principal, method, path string, status int) error
+}
+
+// ClientIP returns the address of the peer the request came from.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
 
 type ServerOptions struct {
 	logger        *slog.Logger
+	authenticator Authenticator
+	policies      *securityPolicies
 	panicHandler  func(w http.ResponseWriter, r *http.Request, p any)
 	middlewares   []func(http.Handler) http.Handler
 	serverOptions []func(*http.Server)
@@ -25,6 +59,22 @@ func (o *ServerOptions) WithLogger(logger *slog.Logger) {
 	o.logger = logger
 }
 
+func (o *ServerOptions) WithAuthenticator(a Authenticator) {
+	o.authenticator = a
+}
+
+// WithOpenAPISpec makes the auth middleware take the authentication and role
+// requirements of every route from the security section and the x-roles
+// extension of its operation in spec.
+func (o *ServerOptions) WithOpenAPISpec(spec *openapi3.T) error {
+	policies, err := newSecurityPolicies(spec)
+	if err != nil {
+		return err
+	}
+	o.policies = policies
+	return nil
+}
+
 func (o *ServerOptions) WithPanicHandler(h func(w http.ResponseWriter, r *http.Request, p any)) {
 	o.panicHandler = h
 }
@@ -42,6 +92,14 @@ func (o *ServerOptions) NewServer(handler http.Handler, addr string) *http.Serve
 		o.logger = slog.Default()
 	}
 
+	if o.authenticator == nil {
+		panic("http server: authenticator is not configured")
+	}
+
+	if o.policies == nil {
+		panic("http server: openapi spec is not configured")
+	}
+
 	if o.panicHandler == nil {
 		o.panicHandler = func(w http.ResponseWriter, r *http.Request, p any) {
 			o.logger.Error("recovered from panic",
@@ -149,29 +207,127 @@ func (o *ServerOptions) recoveryMiddleware(next http.Handler) http.Handler {
 
 func (o *ServerOptions) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasPrefix(r.URL.String(), "/api/auth") {
+		policy := o.policies.find(r)
+		if policy.public {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		apiKey := r.Header.Get(APIKeyHeader)
+
+		var principal *users.Principal
+		switch {
+		case authHeader != "":
+			token, ok := strings.CutPrefix(authHeader, "Bearer ")
+			if !ok {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			var err error
+			principal, err = o.authenticator.VerifyAccessToken(r.Context(), token)
+			if err != nil {
+				switch {
+				case errors.Is(err, users.ErrorSessionEnded):
+					http.Error(w, "session ended", http.StatusUnauthorized)
+				case errors.Is(err, users.ErrorInvalidToken):
+					http.Error(w, "invalid token", http.StatusUnauthorized)
+				default:
+					o.logger.Error("verify access token", slog.Any("err", err))
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+		case apiKey != "":
+			var err error
+			principal, err = o.authenticator.VerifyAPIKey(r.Context(), apiKey)
+			if err != nil {
+				switch {
+				case errors.Is(err, users.ErrorInvalidAPIKey):
+					http.Error(w, "invalid api key", http.StatusUnauthorized)
+				case errors.Is(err, users.ErrorAccountSuspended):
+					http.Error(w, "account is suspended", http.StatusForbidden)
+				case errors.Is(err, users.ErrorAccountClosed):
+					http.Error(w, "account is closed", http.StatusForbidden)
+				default:
+					o.logger.Error("verify api key", slog.Any("err", err))
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if !policy.allowsAPIKey(principal) {
+				http.Error(w, "insufficient api key scope", http.StatusForbidden)
+				return
+			}
+		default:
 			http.Error(w, "missing token", http.StatusUnauthorized)
 			return
 		}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		username, err := service.ParseToken(token)
-		if err != nil {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+		if imp := principal.Impersonation; imp != nil {
+			aw := &loggingResponseWriter{ResponseWriter: w}
+			w = aw
+			defer o.auditImpersonatedRequest(r, principal, aw)
+
+			if !policy.allowsImpersonated(imp) {
+				http.Error(w, "not allowed while impersonating", http.StatusForbidden)
+				return
+			}
+		}
+
+		if !policy.allows(principal) {
+			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
 
+		// Only requests the key is allowed to make count against its quota.
+		if principal.APIKeyID != "" {
+			if err := o.authenticator.CountAPIKeyRequest(r.Context(), principal); err != nil {
+				var quota *users.QuotaError
+				if errors.As(err, &quota) {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quota.RetryAfter.Seconds()))))
+					http.Error(w, "api key quota exceeded", http.StatusTooManyRequests)
+					return
+				}
+				o.logger.Error("count api key request", slog.Any("err", err))
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if principal.SessionID != "" {
+			if err := o.authenticator.TouchSession(r.Context(), principal, r.UserAgent(), ClientIP(r)); err != nil {
+				o.logger.Warn("touch session", slog.String("session", principal.SessionID), slog.Any("err", err))
+			}
+		}
+
 		ctx := r.Context()
-		ctx = context.WithValue(ctx, UsernameContextKey, username)
+		ctx = context.WithValue(ctx, UsernameContextKey, principal.Username)
+		ctx = context.WithValue(ctx, PrincipalContextKey, principal)
 		o.logger.Info("user: " + ctx.Value(UsernameContextKey).(string))
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (o *ServerOptions) auditImpersonatedRequest(r *http.Request, principal *users.Principal, w *loggingResponseWriter) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// The audit record is written even if the client has gone away.
+	ctx := context.WithoutCancel(r.Context())
+	if err := o.authenticator.AuditImpersonatedRequest(ctx, principal, r.Method, r.URL.Path, status); err != nil {
+		o.logger.Error("audit impersonated request",
+			slog.String("impersonation", principal.Impersonation.ID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Any("err", err),
+		)
+	}
+}
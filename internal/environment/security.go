@@ -0,0 +1,225 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+// rolesExtension lists the roles allowed to call an operation, e.g.
+//
+//	x-roles:
+//	  - admin
+//
+// Operations without it are open to every authenticated caller.
+const rolesExtension = "x-roles"
+
+// impersonationExtension set to false keeps admins impersonating a user out
+// of an operation, e.g. one that manages the user's credentials:
+//
+//	x-impersonation: false
+const impersonationExtension = "x-impersonation"
+
+// mutatingExtension marks whether an operation changes anything, and so is
+// out of reach of read-only impersonations. It defaults to true for every
+// method but GET, HEAD and OPTIONS, and must be set on a GET operation that
+// changes something, e.g. one that spends coins:
+//
+//	x-mutating: true
+const mutatingExtension = "x-mutating"
+
+// routePolicy is what the auth middleware requires of a request to an
+// operation. API keys are only accepted by operations that list an apiKey
+// security scheme, and only if the key has all scopes listed with it in one
+// of the alternative security requirements.
+type routePolicy struct {
+	public        bool
+	roles         []string
+	apiKey        bool
+	apiKeyScopes  [][]string
+	impersonation bool
+	mutating      bool
+}
+
+// securityPolicies maps the operations of an OpenAPI document to the
+// authentication and role requirements declared for them.
+type securityPolicies struct {
+	router   routers.Router
+	policies map[*openapi3.Operation]routePolicy
+}
+
+func newSecurityPolicies(spec *openapi3.T) (*securityPolicies, error) {
+	// Routes are matched on the path only, whatever host the server runs on.
+	doc := *spec
+	doc.Servers = nil
+
+	router, err := legacy.NewRouter(&doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "build openapi router")
+	}
+
+	policies := make(map[*openapi3.Operation]routePolicy)
+	for _, pathItem := range doc.Paths.Map() {
+		for method, op := range pathItem.Operations() {
+			policy, err := operationPolicy(&doc, method, op)
+			if err != nil {
+				return nil, errors.Wrapf(err, "operation %s", op.OperationID)
+			}
+			policies[op] = policy
+		}
+	}
+
+	return &securityPolicies{
+		router:   router,
+		policies: policies,
+	}, nil
+}
+
+// operationPolicy derives the policy of an operation from its security
+// requirements, falling back to the document-wide ones. An empty list, or an
+// empty alternative in it, makes the operation public.
+func operationPolicy(doc *openapi3.T, method string, op *openapi3.Operation) (routePolicy, error) {
+	requirements := doc.Security
+	if op.Security != nil {
+		requirements = *op.Security
+	}
+
+	policy := routePolicy{
+		public:        len(requirements) == 0,
+		impersonation: true,
+		mutating:      !isSafeMethod(method),
+	}
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			policy.public = true
+		}
+		// The schemes of a requirement are all needed, the requirements
+		// themselves are alternatives.
+		var scopes []string
+		hasAPIKey := false
+		for name, schemeScopes := range requirement {
+			if isAPIKeyScheme(doc, name) {
+				hasAPIKey = true
+				scopes = append(scopes, schemeScopes...)
+			}
+		}
+		if hasAPIKey {
+			policy.apiKey = true
+			policy.apiKeyScopes = append(policy.apiKeyScopes, scopes)
+		}
+	}
+
+	if raw, ok := op.Extensions[rolesExtension]; ok {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return routePolicy{}, errors.Errorf("%s must be a list of roles", rolesExtension)
+		}
+		for _, item := range list {
+			role, ok := item.(string)
+			if !ok {
+				return routePolicy{}, errors.Errorf("%s must be a list of roles", rolesExtension)
+			}
+			policy.roles = append(policy.roles, role)
+		}
+	}
+
+	if raw, ok := op.Extensions[impersonationExtension]; ok {
+		allowed, ok := raw.(bool)
+		if !ok {
+			return routePolicy{}, errors.Errorf("%s must be a boolean", impersonationExtension)
+		}
+		policy.impersonation = allowed
+	}
+
+	if raw, ok := op.Extensions[mutatingExtension]; ok {
+		mutating, ok := raw.(bool)
+		if !ok {
+			return routePolicy{}, errors.Errorf("%s must be a boolean", mutatingExtension)
+		}
+		policy.mutating = mutating
+	}
+
+	return policy, nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+func isAPIKeyScheme(doc *openapi3.T, name string) bool {
+	if doc.Components == nil {
+		return false
+	}
+	scheme, ok := doc.Components.SecuritySchemes[name]
+	return ok && scheme.Value != nil && scheme.Value.Type == "apiKey"
+}
+
+// allowsImpersonated reports whether an admin impersonating the principal
+// may call the route. Read-only impersonations are limited to operations that
+// do not change anything.
+func (p routePolicy) allowsImpersonated(imp *users.Impersonation) bool {
+	if !p.impersonation {
+		return false
+	}
+	return !imp.ReadOnly || !p.mutating
+}
+
+// find returns the policy of the operation the request is routed to. Requests
+// that match no operation need an authenticated caller, so that a route
+// missing from the spec is never public by accident.
+func (s *securityPolicies) find(r *http.Request) routePolicy {
+	route, _, err := s.router.FindRoute(r)
+	if err != nil {
+		return routePolicy{}
+	}
+	return s.policies[route.Operation]
+}
+
+// allows reports whether the principal has one of the roles the route
+// requires.
+func (p routePolicy) allows(principal *users.Principal) bool {
+	if len(p.roles) == 0 {
+		return true
+	}
+
+	for _, role := range p.roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAPIKey reports whether the principal, authenticated with an API key,
+// may call the route.
+func (p routePolicy) allowsAPIKey(principal *users.Principal) bool {
+	if !p.apiKey {
+		return false
+	}
+
+	for _, scopes := range p.apiKeyScopes {
+		if hasScopes(principal, scopes) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasScopes(principal *users.Principal, scopes []string) bool {
+	for _, scope := range scopes {
+		if !principal.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/kingxl111/merch-store/internal/users"
+	merchstoreapi "github.com/kingxl111/merch-store/pkg/api/merch-store"
+)
+
+// fakeAuthenticator accepts every access token as principal and every API
+// key as apiKeyPrincipal, and counts the requests made with API keys.
+type fakeAuthenticator struct {
+	principal       *users.Principal
+	apiKeyPrincipal *users.Principal
+	apiKeyRequests  int
+}
+
+func (a *fakeAuthenticator) VerifyAccessToken(context.Context, string) (*users.Principal, error) {
+	return a.principal, nil
+}
+
+func (a *fakeAuthenticator) VerifyAPIKey(context.Context, string) (*users.Principal, error) {
+	if a.apiKeyPrincipal == nil {
+		return nil, users.ErrorInvalidAPIKey
+	}
+	return a.apiKeyPrincipal, nil
+}
+
+func (a *fakeAuthenticator) CountAPIKeyRequest(context.Context, *users.Principal) error {
+	a.apiKeyRequests++
+	return nil
+}
+
+func (a *fakeAuthenticator) TouchSession(context.Context, *users.Principal, string, string) error {
+	return nil
+}
+
+func (a *fakeAuthenticator) AuditImpersonatedRequest(context.Context, *users.Principal, string, string, int) error {
+	return nil
+}
+
+// newTestAuthMiddleware returns the auth middleware of the API in front of a
+// handler that answers every request it gets with 200.
+func newTestAuthMiddleware(t *testing.T, authenticator Authenticator) http.Handler {
+	t.Helper()
+
+	spec, err := merchstoreapi.GetSwagger()
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+
+	o := &ServerOptions{
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		authenticator: authenticator,
+	}
+	if err := o.WithOpenAPISpec(spec); err != nil {
+		t.Fatalf("load policies: %v", err)
+	}
+
+	return o.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestImpersonatedRequests(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		readOnly  bool
+		wantAllow bool
+	}{
+		{http.MethodGet, "/api/info", true, true},
+		{http.MethodGet, "/api/buy/cup", true, false},
+		{http.MethodPost, "/api/sendCoin", true, false},
+		{http.MethodGet, "/api/buy/cup", false, true},
+		{http.MethodPost, "/api/sendCoin", false, true},
+		{http.MethodPost, "/api/password", false, false},
+	}
+	for _, tt := range tests {
+		name := tt.method + " " + tt.path
+		if tt.readOnly {
+			name += " read-only"
+		}
+		t.Run(name, func(t *testing.T) {
+			handler := newTestAuthMiddleware(t, &fakeAuthenticator{principal: &users.Principal{
+				UserID:        "user-id",
+				Username:      "alice",
+				Roles:         []string{"user"},
+				Impersonation: &users.Impersonation{ID: "imp-id", AdminUsername: "admin", ReadOnly: tt.readOnly},
+			}})
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			want := http.StatusForbidden
+			if tt.wantAllow {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Errorf("status = %d, want %d", w.Code, want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRequests(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		scopes       []string
+		wantStatus   int
+		wantRequests int
+	}{
+		{"scope granted", "/api/buy/cup", []string{"shop:buy"}, http.StatusOK, 1},
+		{"scope missing", "/api/buy/cup", []string{"info:read"}, http.StatusForbidden, 0},
+		{"no api key scheme", "/api/sessions", []string{"info:read"}, http.StatusForbidden, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := &fakeAuthenticator{apiKeyPrincipal: &users.Principal{
+				UserID:   "user-id",
+				Username: "alice",
+				Roles:    []string{"user"},
+				APIKeyID: "key-id",
+				Scopes:   tt.scopes,
+			}}
+			handler := newTestAuthMiddleware(t, authenticator)
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set(APIKeyHeader, "key")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if authenticator.apiKeyRequests != tt.wantRequests {
+				t.Errorf("counted requests = %d, want %d", authenticator.apiKeyRequests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// TestAlternativeAPIKeyScopes checks that a key needs the scopes of only one
+// of the alternative security requirements of an operation.
+func TestAlternativeAPIKeyScopes(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: 3.0.0
+info:
+  title: test
+  version: "1"
+components:
+  securitySchemes:
+    ApiKeyAuth:
+      type: apiKey
+      in: header
+      name: X-API-Key
+paths:
+  /items:
+    get:
+      security:
+        - ApiKeyAuth: [items:read]
+        - ApiKeyAuth: [items:admin, audit:read]
+      responses:
+        "200":
+          description: ok
+`))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+
+	policy, err := operationPolicy(spec, http.MethodGet, spec.Paths.Value("/items").Get)
+	if err != nil {
+		t.Fatalf("operation policy: %v", err)
+	}
+
+	tests := []struct {
+		scopes []string
+		want   bool
+	}{
+		{[]string{"items:read"}, true},
+		{[]string{"items:admin", "audit:read"}, true},
+		{[]string{"items:admin"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		got := policy.allowsAPIKey(&users.Principal{Scopes: tt.scopes})
+		if got != tt.want {
+			t.Errorf("allowsAPIKey with scopes %v = %v, want %v", tt.scopes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package escrow
+
+import "errors"
+
+var (
+	ErrorService      = errors.New("escrow service error")
+	ErrorNotFound     = errors.New("pending transfer not found")
+	ErrorResolved     = errors.New("pending transfer is already settled or cancelled")
+	ErrorWindowClosed = errors.New("cancellation window is over")
+)
@@ -0,0 +1,29 @@
+package escrow
+
+import "time"
+
+// Statuses of a pending transfer. A returned transfer could not be settled
+// because the receiver's account had been closed, so the coins went back to
+// the sender.
+const (
+	StatusPending   = "pending"
+	StatusSettled   = "settled"
+	StatusCancelled = "cancelled"
+	StatusReturned  = "returned"
+)
+
+// PendingTransfer is a delayed transfer of Amount coins from FromUser to
+// ToUser. The coins are held until SettlesAt, until which the sender can
+// cancel it.
+type PendingTransfer struct {
+	ID         string
+	FromUser   string
+	ToUser     string
+	Amount     int
+	Message    *string
+	Category   *string
+	Status     string
+	CreatedAt  time.Time
+	SettlesAt  time.Time
+	ResolvedAt *time.Time
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+)
+
+type Repository interface {
+	ListPendingTransfers(ctx context.Context, userID string, limit int) ([]postgres.PendingTransfer, error)
+	CancelPendingTransfer(ctx context.Context, userID, id string) (*postgres.PendingTransfer, error)
+
+	DuePendingTransfers(ctx context.Context, now time.Time, limit int) ([]string, error)
+	SettlePendingTransfer(ctx context.Context, id string) (*postgres.PendingTransfer, error)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/escrow"
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+// listLimit is how many pending transfers are listed.
+const listLimit = 100
+
+type escrowService struct {
+	repo Repository
+}
+
+func NewEscrowService(repo Repository) *escrowService {
+	return &escrowService{repo: repo}
+}
+
+// List returns the transfers sent or received by the caller whose coins are
+// still held, those settling soonest first.
+func (s *escrowService) List(ctx context.Context, principal *users.Principal) ([]escrow.PendingTransfer, error) {
+	list, err := s.repo.ListPendingTransfers(ctx, principal.UserID, listLimit)
+	if err != nil {
+		return nil, escrow.ErrorService
+	}
+
+	transfers := make([]escrow.PendingTransfer, 0, len(list))
+	for i := range list {
+		transfers = append(transfers, *pendingTransfer(&list[i]))
+	}
+	return transfers, nil
+}
+
+// Cancel cancels a pending transfer sent by the caller and gives the held
+// coins back.
+func (s *escrowService) Cancel(ctx context.Context, principal *users.Principal, id string) (*escrow.PendingTransfer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, escrow.ErrorNotFound
+	}
+
+	p, err := s.repo.CancelPendingTransfer(ctx, principal.UserID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorPendingTransferNotFound):
+			return nil, escrow.ErrorNotFound
+		case errors.Is(err, repository.ErrorPendingTransferResolved):
+			return nil, escrow.ErrorResolved
+		case errors.Is(err, repository.ErrorPendingTransferSettling):
+			return nil, escrow.ErrorWindowClosed
+		default:
+			return nil, escrow.ErrorService
+		}
+	}
+	return pendingTransfer(p), nil
+}
+
+func pendingTransfer(p *postgres.PendingTransfer) *escrow.PendingTransfer {
+	return &escrow.PendingTransfer{
+		ID:         p.ID,
+		FromUser:   p.FromUsername,
+		ToUser:     p.ToUsername,
+		Amount:     p.Amount,
+		Message:    p.Message,
+		Category:   p.Category,
+		Status:     p.Status,
+		CreatedAt:  p.CreatedAt,
+		SettlesAt:  p.SettlesAt,
+		ResolvedAt: p.ResolvedAt,
+	}
+}
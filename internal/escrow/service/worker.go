@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+)
+
+// batchSize is how many due transfers are settled per poll.
+const batchSize = 100
+
+// Work settles the pending transfers whose window is over every interval
+// until ctx is done. Several servers can work at once: a transfer is locked
+// while it is settled, and one settled by another server is skipped.
+func (s *escrowService) Work(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.settleDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *escrowService) settleDue(ctx context.Context) {
+	due, err := s.repo.DuePendingTransfers(ctx, time.Now(), batchSize)
+	if err != nil {
+		slog.Error("failed to select due pending transfers", slog.Any("error", err))
+		return
+	}
+
+	for _, id := range due {
+		p, err := s.repo.SettlePendingTransfer(ctx, id)
+		if errors.Is(err, repository.ErrorPendingTransferResolved) {
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to settle pending transfer",
+				slog.String("transfer", id),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		slog.Info("pending transfer "+p.Status,
+			slog.String("transfer", p.ID),
+			slog.String("from", p.FromUsername),
+			slog.String("to", p.ToUsername),
+			slog.Int("amount", p.Amount),
+		)
+	}
+}
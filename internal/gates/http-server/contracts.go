@@ -3,6 +3,10 @@ package http_server
 import (
 	"context"
 
+	"github.com/kingxl111/merch-store/internal/coinrequest"
+	"github.com/kingxl111/merch-store/internal/escrow"
+	"github.com/kingxl111/merch-store/internal/idempotency"
+	"github.com/kingxl111/merch-store/internal/schedule"
 	"github.com/kingxl111/merch-store/internal/shop"
 	"github.com/kingxl111/merch-store/internal/users"
 )
@@ -11,11 +15,61 @@ import (
 type (
 	UserService interface {
 		Authenticate(ctx context.Context, req *users.AuthRequest) (*users.AuthResponse, error)
-		TransferCoins(ctx context.Context, req *users.CoinTransfer) error
+		Register(ctx context.Context, req *users.AuthRequest) (*users.AuthResponse, error)
+		Refresh(ctx context.Context, req *users.RefreshRequest) (*users.AuthResponse, error)
+		Logout(ctx context.Context, principal *users.Principal) error
+		ListSessions(ctx context.Context, principal *users.Principal) ([]users.SessionInfo, error)
+		EndSession(ctx context.Context, principal *users.Principal, sessionID string) error
+		EndOtherSessions(ctx context.Context, principal *users.Principal) error
+		CreateAPIKey(ctx context.Context, principal *users.Principal, req *users.CreateAPIKeyRequest) (*users.APIKey, error)
+		ListAPIKeys(ctx context.Context, principal *users.Principal) ([]users.APIKey, error)
+		RevokeAPIKey(ctx context.Context, principal *users.Principal, keyID string) error
+		ChangePassword(ctx context.Context, principal *users.Principal, req *users.ChangePasswordRequest) error
+		RequestPasswordReset(ctx context.Context, username, clientIP string) error
+		ChangeUsername(ctx context.Context, principal *users.Principal, username string) error
+		ResetPassword(ctx context.Context, req *users.ResetPasswordRequest) error
+		CompleteTwoFactor(ctx context.Context, req *users.TwoFactorLoginRequest) (*users.AuthResponse, error)
+		EnrollTwoFactor(ctx context.Context, principal *users.Principal) (*users.TwoFactorEnrollment, error)
+		ConfirmTwoFactor(ctx context.Context, principal *users.Principal, code string) ([]string, error)
+		DisableTwoFactor(ctx context.Context, principal *users.Principal, code string) error
+		TransferCoins(ctx context.Context, req *users.CoinTransfer, rec *idempotency.Record) error
 		GetUserInfo(ctx context.Context, username string) (*users.UserInfoResponse, error)
+		SetRole(ctx context.Context, req *users.SetRoleRequest) error
+		SetStatus(ctx context.Context, admin *users.Principal, req *users.SetStatusRequest) error
+		GetStatus(ctx context.Context, username string) (*users.AccountStatus, error)
+		Offboard(ctx context.Context, admin *users.Principal, req *users.OffboardRequest) (*users.OffboardingSummary, error)
+		Impersonate(ctx context.Context, admin *users.Principal, req *users.ImpersonateRequest) (*users.ImpersonationToken, error)
+		GrantCoins(ctx context.Context, admin *users.Principal, req *users.GrantRequest) (*users.LedgerEntry, error)
+		RefundPurchase(ctx context.Context, admin *users.Principal, req *users.RefundRequest) (*users.LedgerEntry, error)
+		GetLedger(ctx context.Context, username string) ([]users.LedgerEntry, error)
+		GetLockoutEvents(ctx context.Context) ([]users.LockoutEvent, error)
 	}
 
 	ShopService interface {
-		BuyMerch(ctx context.Context, req shop.InventoryItem) error
+		BuyMerch(ctx context.Context, req shop.InventoryItem, rec *idempotency.Record) error
+	}
+
+	ScheduleService interface {
+		Create(ctx context.Context, principal *users.Principal, req *schedule.CreateRequest) (*schedule.ScheduledTransfer, error)
+		List(ctx context.Context, principal *users.Principal) ([]schedule.ScheduledTransfer, error)
+		Cancel(ctx context.Context, principal *users.Principal, id string) error
+		Runs(ctx context.Context, principal *users.Principal, id string) ([]schedule.Run, error)
+	}
+
+	CoinRequestService interface {
+		Create(ctx context.Context, principal *users.Principal, req *coinrequest.CreateRequest) (*coinrequest.CoinRequest, error)
+		Incoming(ctx context.Context, principal *users.Principal) ([]coinrequest.CoinRequest, error)
+		Outgoing(ctx context.Context, principal *users.Principal) ([]coinrequest.CoinRequest, error)
+		Approve(ctx context.Context, principal *users.Principal, id string) (*coinrequest.CoinRequest, error)
+		Decline(ctx context.Context, principal *users.Principal, id string) (*coinrequest.CoinRequest, error)
+	}
+
+	EscrowService interface {
+		List(ctx context.Context, principal *users.Principal) ([]escrow.PendingTransfer, error)
+		Cancel(ctx context.Context, principal *users.Principal, id string) (*escrow.PendingTransfer, error)
+	}
+
+	KeySet interface {
+		PublicKeys() []users.JSONWebKey
 	}
 )
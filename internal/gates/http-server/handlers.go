@@ -1,12 +1,20 @@
 package http_server
 
 import (
+	"context"
 	"encoding/json"
 	env "github.com/kingxl111/merch-store/internal/environment"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/go-faster/errors"
+	"github.com/kingxl111/merch-store/internal/coinrequest"
+	"github.com/kingxl111/merch-store/internal/escrow"
+	"github.com/kingxl111/merch-store/internal/idempotency"
+	"github.com/kingxl111/merch-store/internal/schedule"
 	"github.com/kingxl111/merch-store/internal/shop"
 	"github.com/kingxl111/merch-store/internal/users"
 	merchstoreapi "github.com/kingxl111/merch-store/pkg/api/merch-store"
@@ -15,14 +23,29 @@ import (
 var _ merchstoreapi.ServerInterface = (*Handler)(nil)
 
 type Handler struct {
-	userService UserService
-	shopService ShopService
+	userService        UserService
+	shopService        ShopService
+	scheduleService    ScheduleService
+	coinRequestService CoinRequestService
+	escrowService      EscrowService
+	keySet             KeySet
 }
 
-func NewHandler(userService UserService, shopService ShopService) *Handler {
+func NewHandler(
+	userService UserService,
+	shopService ShopService,
+	scheduleService ScheduleService,
+	coinRequestService CoinRequestService,
+	escrowService EscrowService,
+	keySet KeySet,
+) *Handler {
 	return &Handler{
-		userService: userService,
-		shopService: shopService,
+		userService:        userService,
+		shopService:        shopService,
+		scheduleService:    scheduleService,
+		coinRequestService: coinRequestService,
+		escrowService:      escrowService,
+		keySet:             keySet,
 	}
 }
 
@@ -34,27 +57,895 @@ func (h *Handler) PostApiAuth(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 	resp, err := h.userService.Authenticate(ctx, &users.AuthRequest{
-		Username: req.Username,
-		Password: req.Password,
+		Username:  req.Username,
+		Password:  req.Password,
+		ClientIP:  env.ClientIP(r),
+		UserAgent: r.UserAgent(),
 	})
 	if err != nil {
 		if errors.Is(err, users.ErrorWrongPassword) {
 			h.respondWithError(w, http.StatusBadRequest, "wrong password")
 			return
 		}
+		if h.respondWithLockout(w, err) || h.respondWithAccountStatus(w, err) {
+			return
+		}
+		h.respondWithRegistrationError(w, err)
+		return
+	}
+	if resp.ChallengeToken != "" {
+		h.respondWithJSON(w, http.StatusAccepted, merchstoreapi.TwoFactorChallenge{
+			ChallengeToken: resp.ChallengeToken,
+			ExpiresIn:      resp.ExpiresIn,
+		})
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, authResponse(resp))
+}
+
+// respondWithLockout answers with 429 and a Retry-After header if err is a
+// login lockout and reports whether it did.
+func (h *Handler) respondWithLockout(w http.ResponseWriter, err error) bool {
+	var lockout *users.LockoutError
+	if !errors.As(err, &lockout) {
+		return false
+	}
+	retryAfter := int(math.Ceil(lockout.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	h.respondWithError(w, http.StatusTooManyRequests, "too many failed login attempts")
+	return true
+}
+
+// respondWithAccountStatus answers with 403 if err says that the status of
+// the caller's account does not allow the request and reports whether it did.
+func (h *Handler) respondWithAccountStatus(w http.ResponseWriter, err error) bool {
+	var message string
+	switch {
+	case errors.Is(err, users.ErrorAccountFrozen):
+		message = "account is frozen"
+	case errors.Is(err, users.ErrorAccountSuspended):
+		message = "account is suspended"
+	case errors.Is(err, users.ErrorAccountClosed):
+		message = "account is closed"
+	default:
+		return false
+	}
+	h.respondWithError(w, http.StatusForbidden, message)
+	return true
+}
+
+func (h *Handler) PostApiAuth2fa(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	resp, err := h.userService.CompleteTwoFactor(ctx, &users.TwoFactorLoginRequest{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		ClientIP:       env.ClientIP(r),
+		UserAgent:      r.UserAgent(),
+	})
+	if err != nil {
+		if h.respondWithLockout(w, err) || h.respondWithAccountStatus(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, users.ErrorInvalidChallenge):
+			h.respondWithError(w, http.StatusUnauthorized, "invalid challenge token")
+		case errors.Is(err, users.ErrorInvalidTwoFactorCode):
+			h.respondWithError(w, http.StatusUnauthorized, "invalid code")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, authResponse(resp))
+}
+
+func (h *Handler) PostApi2faEnroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	enrollment, err := h.userService.EnrollTwoFactor(ctx, principal)
+	if err != nil {
+		h.respondWithTwoFactorError(w, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, merchstoreapi.TwoFactorEnrollment{
+		Secret:     enrollment.Secret,
+		OtpauthUrl: enrollment.URL,
+	})
+}
+
+func (h *Handler) PostApi2faConfirm(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	codes, err := h.userService.ConfirmTwoFactor(ctx, principal, req.Code)
+	if err != nil {
+		h.respondWithTwoFactorError(w, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, merchstoreapi.RecoveryCodes{RecoveryCodes: codes})
+}
+
+func (h *Handler) PostApi2faDisable(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.userService.DisableTwoFactor(ctx, principal, req.Code); err != nil {
+		h.respondWithTwoFactorError(w, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Two-factor authentication disabled")
+}
+
+func (h *Handler) respondWithTwoFactorError(w http.ResponseWriter, err error) {
+	if h.respondWithLockout(w, err) {
+		return
+	}
+	var status int
+	var message string
+	switch {
+	case errors.Is(err, users.ErrorInvalidTwoFactorCode):
+		status, message = http.StatusBadRequest, "invalid code"
+	case errors.Is(err, users.ErrorTwoFactorEnabled):
+		status, message = http.StatusConflict, "two-factor authentication is already enabled"
+	case errors.Is(err, users.ErrorTwoFactorNotEnrolled):
+		status, message = http.StatusConflict, "two-factor authentication is not enrolled"
+	case errors.Is(err, users.ErrorTwoFactorNotEnabled):
+		status, message = http.StatusConflict, "two-factor authentication is not enabled"
+	default:
+		status, message = http.StatusInternalServerError, "internal server error"
+	}
+	h.respondWithError(w, status, message)
+}
+
+func (h *Handler) PostApiRegister(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.AuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	resp, err := h.userService.Register(ctx, &users.AuthRequest{
+		Username:  req.Username,
+		Password:  req.Password,
+		ClientIP:  env.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		h.respondWithRegistrationError(w, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusCreated, authResponse(resp))
+}
+
+func (h *Handler) respondWithRegistrationError(w http.ResponseWriter, err error) {
+	var status int
+	var message string
+	switch {
+	case errors.Is(err, users.ErrorInvalidUsername):
+		status, message = http.StatusBadRequest, "username must be 3-32 characters of latin letters, digits, '.', '-' or '_'"
+	case errors.Is(err, users.ErrorReservedUsername):
+		status, message = http.StatusBadRequest, "username is reserved"
+	case errors.Is(err, users.ErrorInvalidPassword):
+		status, message = http.StatusBadRequest, "password must not be empty"
+	case errors.Is(err, users.ErrorUsernameTaken):
+		status, message = http.StatusConflict, "username is already taken"
+	default:
+		status, message = http.StatusInternalServerError, "internal server error"
+	}
+	h.respondWithError(w, status, message)
+}
+
+func (h *Handler) PostApiAuthRefresh(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	resp, err := h.userService.Refresh(ctx, &users.RefreshRequest{
+		RefreshToken: req.RefreshToken,
+	})
+	if err != nil {
+		if errors.Is(err, users.ErrorInvalidRefreshToken) {
+			h.respondWithError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, authResponse(resp))
+}
+
+func (h *Handler) PostApiAuthLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.userService.Logout(ctx, principal); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Logged out")
+}
+
+func (h *Handler) PostApiPassword(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	err := h.userService.ChangePassword(ctx, principal, &users.ChangePasswordRequest{
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		if h.respondWithLockout(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, users.ErrorWrongPassword):
+			h.respondWithError(w, http.StatusBadRequest, "wrong password")
+		case errors.Is(err, users.ErrorInvalidPassword):
+			h.respondWithError(w, http.StatusBadRequest, "password must not be empty")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Password changed")
+}
+
+func (h *Handler) PutApiUsername(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.ChangeUsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	err := h.userService.ChangeUsername(ctx, principal, req.Username)
+	if err != nil {
+		var cooldown *users.RenameCooldownError
+		if errors.As(err, &cooldown) {
+			retryAfter := int(math.Ceil(cooldown.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			h.respondWithError(w, http.StatusTooManyRequests, "username was changed too recently")
+			return
+		}
+		h.respondWithRegistrationError(w, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Username changed")
+}
+
+func (h *Handler) PostApiPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(r.Context(), req.Username, env.ClientIP(r)); err != nil {
+		var lockout *users.LockoutError
+		if errors.As(err, &lockout) {
+			retryAfter := int(math.Ceil(lockout.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			h.respondWithError(w, http.StatusTooManyRequests, "too many password reset requests")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusAccepted, "Password reset requested")
+}
+
+func (h *Handler) PostApiPasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.userService.ResetPassword(r.Context(), &users.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrorInvalidResetToken):
+			h.respondWithError(w, http.StatusBadRequest, "invalid or expired reset token")
+		case errors.Is(err, users.ErrorInvalidPassword):
+			h.respondWithError(w, http.StatusBadRequest, "password must not be empty")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Password changed")
+}
+
+func (h *Handler) GetApiSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessions, err := h.userService.ListSessions(ctx, principal)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.Session, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, merchstoreapi.Session{
+			Id:         s.ID,
+			UserAgent:  s.UserAgent,
+			Ip:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.Current,
+		})
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) DeleteApiSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.userService.EndOtherSessions(ctx, principal); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Other sessions ended")
+}
+
+func (h *Handler) DeleteApiSessionsId(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.userService.EndSession(ctx, principal, id); err != nil {
+		if errors.Is(err, users.ErrorSessionNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Session ended")
+}
+
+func (h *Handler) GetApiKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	keys, err := h.userService.ListAPIKeys(ctx, principal)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.APIKey, 0, len(keys))
+	for i := range keys {
+		resp = append(resp, apiKeyResponse(&keys[i]))
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) PostApiKeys(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	key, err := h.userService.CreateAPIKey(ctx, principal, &users.CreateAPIKeyRequest{
+		Name:   req.Name,
+		Scopes: scopes,
+		Quota:  req.Quota,
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorInvalidAPIKeyName):
+			status, message = http.StatusBadRequest, "name must be 1-64 characters"
+		case errors.Is(err, users.ErrorInvalidScope):
+			status, message = http.StatusBadRequest, "scopes must be a non-empty list of info:read, coins:send and shop:buy"
+		case errors.Is(err, users.ErrorInvalidQuota):
+			status, message = http.StatusBadRequest, "quota must be positive"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusCreated, apiKeyResponse(key))
+}
+
+func (h *Handler) DeleteApiKeysId(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.userService.RevokeAPIKey(ctx, principal, id); err != nil {
+		if errors.Is(err, users.ErrorAPIKeyNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "api key not found")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "API key revoked")
+}
+
+func apiKeyResponse(key *users.APIKey) merchstoreapi.APIKey {
+	scopes := make([]merchstoreapi.APIKeyScope, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, merchstoreapi.APIKeyScope(scope))
+	}
+
+	return merchstoreapi.APIKey{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Key:        optionalString(key.Key),
+		Scopes:     scopes,
+		Quota:      key.Quota,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+func (h *Handler) GetApiScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	transfers, err := h.scheduleService.List(ctx, principal)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.ScheduledTransfer, 0, len(transfers))
+	for _, t := range transfers {
+		resp = append(resp, scheduledTransferResponse(&t))
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) PostApiScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.CreateScheduledTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	transfer, err := h.scheduleService.Create(ctx, principal, &schedule.CreateRequest{
+		ToUser:   req.ToUser,
+		Amount:   req.Amount,
+		Message:  req.Message,
+		Category: req.Category,
+		RunAt:    req.RunAt,
+		Rule:     req.Rule,
+	})
+	if err != nil {
+		if h.respondWithAccountStatus(w, err) {
+			return
+		}
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorInvalidAmount):
+			status, message = http.StatusBadRequest, "amount must be positive"
+		case errors.Is(err, users.ErrorReceiverNotFound),
+			errors.Is(err, users.ErrorReceiverClosed),
+			errors.Is(err, users.ErrorInvalidMessage),
+			errors.Is(err, users.ErrorInvalidCategory),
+			errors.Is(err, schedule.ErrorSelfTransfer),
+			errors.Is(err, schedule.ErrorInvalidTiming),
+			errors.Is(err, schedule.ErrorRunAtInPast),
+			errors.Is(err, schedule.ErrorInvalidRule):
+			status, message = http.StatusBadRequest, err.Error()
+		case errors.Is(err, schedule.ErrorTooManySchedules):
+			status, message = http.StatusConflict, err.Error()
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusCreated, scheduledTransferResponse(transfer))
+}
+
+func (h *Handler) DeleteApiScheduledTransfersId(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.scheduleService.Cancel(ctx, principal, id); err != nil {
+		switch {
+		case errors.Is(err, schedule.ErrorScheduleNotFound):
+			h.respondWithError(w, http.StatusNotFound, "scheduled transfer not found")
+		case errors.Is(err, schedule.ErrorScheduleNotPending):
+			h.respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Scheduled transfer cancelled")
+}
+
+func (h *Handler) GetApiScheduledTransfersIdRuns(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	runs, err := h.scheduleService.Runs(ctx, principal, id)
+	if err != nil {
+		if errors.Is(err, schedule.ErrorScheduleNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "scheduled transfer not found")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.ScheduledTransferRun, 0, len(runs))
+	for _, run := range runs {
+		resp = append(resp, scheduledRunResponse(&run))
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func scheduledTransferResponse(t *schedule.ScheduledTransfer) merchstoreapi.ScheduledTransfer {
+	resp := merchstoreapi.ScheduledTransfer{
+		Id:          t.ID,
+		ToUser:      t.ToUser,
+		Amount:      t.Amount,
+		Message:     t.Message,
+		Category:    t.Category,
+		RunAt:       t.RunAt,
+		Rule:        t.Rule,
+		NextRunAt:   t.NextRunAt,
+		CreatedAt:   t.CreatedAt,
+		CancelledAt: t.CancelledAt,
+	}
+	if t.LastRun != nil {
+		run := scheduledRunResponse(t.LastRun)
+		resp.LastRun = &run
+	}
+	return resp
+}
+
+func scheduledRunResponse(run *schedule.Run) merchstoreapi.ScheduledTransferRun {
+	return merchstoreapi.ScheduledTransferRun{
+		ScheduledFor: run.ScheduledFor,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+		Status:       merchstoreapi.ScheduledTransferRunStatus(run.Status),
+		Error:        run.Error,
+	}
+}
+
+func (h *Handler) PostApiCoinRequests(w http.ResponseWriter, r *http.Request) {
+	var req merchstoreapi.CreateCoinRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	request, err := h.coinRequestService.Create(ctx, principal, &coinrequest.CreateRequest{
+		Payer:  req.Payer,
+		Amount: req.Amount,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		if h.respondWithAccountStatus(w, err) {
+			return
+		}
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorInvalidAmount):
+			status, message = http.StatusBadRequest, "amount must be positive"
+		case errors.Is(err, coinrequest.ErrorInvalidReason),
+			errors.Is(err, coinrequest.ErrorSelfRequest),
+			errors.Is(err, coinrequest.ErrorPayerNotFound),
+			errors.Is(err, coinrequest.ErrorPayerClosed):
+			status, message = http.StatusBadRequest, err.Error()
+		case errors.Is(err, coinrequest.ErrorTooManyRequests):
+			status, message = http.StatusConflict, err.Error()
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusCreated, coinRequestResponse(request))
+}
+
+func (h *Handler) GetApiCoinRequestsIncoming(w http.ResponseWriter, r *http.Request) {
+	h.listCoinRequests(w, r, h.coinRequestService.Incoming)
+}
+
+func (h *Handler) GetApiCoinRequestsOutgoing(w http.ResponseWriter, r *http.Request) {
+	h.listCoinRequests(w, r, h.coinRequestService.Outgoing)
+}
+
+func (h *Handler) listCoinRequests(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(context.Context, *users.Principal) ([]coinrequest.CoinRequest, error),
+) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	requests, err := list(ctx, principal)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.CoinRequest, 0, len(requests))
+	for _, c := range requests {
+		resp = append(resp, coinRequestResponse(&c))
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) PostApiCoinRequestsIdApprove(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	request, err := h.coinRequestService.Approve(ctx, principal, id)
+	if err != nil {
+		if h.respondWithAccountStatus(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, users.ErrorInsufFunds):
+			h.respondWithError(w, http.StatusBadRequest, "insufficient funds")
+		case errors.Is(err, users.ErrorReceiverClosed):
+			h.respondWithError(w, http.StatusBadRequest, "requester account is closed")
+		default:
+			h.respondWithCoinRequestError(w, err)
+		}
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, coinRequestResponse(request))
+}
+
+func (h *Handler) PostApiCoinRequestsIdDecline(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	request, err := h.coinRequestService.Decline(ctx, principal, id)
+	if err != nil {
+		h.respondWithCoinRequestError(w, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, coinRequestResponse(request))
+}
+
+func (h *Handler) respondWithCoinRequestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, coinrequest.ErrorNotFound):
+		h.respondWithError(w, http.StatusNotFound, "coin request not found")
+	case errors.Is(err, coinrequest.ErrorExpired), errors.Is(err, coinrequest.ErrorResolved):
+		h.respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func coinRequestResponse(c *coinrequest.CoinRequest) merchstoreapi.CoinRequest {
+	return merchstoreapi.CoinRequest{
+		Id:         c.ID,
+		Requester:  c.Requester,
+		Payer:      c.Payer,
+		Amount:     c.Amount,
+		Reason:     c.Reason,
+		Status:     merchstoreapi.CoinRequestStatus(c.Status),
+		CreatedAt:  c.CreatedAt,
+		ExpiresAt:  c.ExpiresAt,
+		ResolvedAt: c.ResolvedAt,
+	}
+}
+
+func (h *Handler) GetApiPendingTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	transfers, err := h.escrowService.List(ctx, principal)
+	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
 		return
 	}
-	h.respondWithJSON(w, http.StatusOK, merchstoreapi.AuthResponse{Token: &resp.Token})
+
+	resp := make([]merchstoreapi.PendingTransfer, 0, len(transfers))
+	for i := range transfers {
+		resp = append(resp, pendingTransferResponse(&transfers[i]))
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) PostApiPendingTransfersIdCancel(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	transfer, err := h.escrowService.Cancel(ctx, principal, id)
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, escrow.ErrorNotFound):
+			status, message = http.StatusNotFound, "pending transfer not found"
+		case errors.Is(err, escrow.ErrorResolved), errors.Is(err, escrow.ErrorWindowClosed):
+			status, message = http.StatusConflict, err.Error()
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, pendingTransferResponse(transfer))
+}
+
+func pendingTransferResponse(p *escrow.PendingTransfer) merchstoreapi.PendingTransfer {
+	return merchstoreapi.PendingTransfer{
+		Id:         p.ID,
+		FromUser:   p.FromUser,
+		ToUser:     p.ToUser,
+		Amount:     p.Amount,
+		Message:    p.Message,
+		Category:   p.Category,
+		Status:     merchstoreapi.PendingTransferStatus(p.Status),
+		CreatedAt:  p.CreatedAt,
+		SettlesAt:  p.SettlesAt,
+		ResolvedAt: p.ResolvedAt,
+	}
+}
+
+func authResponse(resp *users.AuthResponse) merchstoreapi.AuthResponse {
+	return merchstoreapi.AuthResponse{
+		Token:        &resp.Token,
+		RefreshToken: &resp.RefreshToken,
+		ExpiresIn:    &resp.ExpiresIn,
+	}
 }
 
-func (h *Handler) GetApiBuyItem(w http.ResponseWriter, r *http.Request, item string) {
+func (h *Handler) GetApiBuyItem(w http.ResponseWriter, r *http.Request, item string, params merchstoreapi.GetApiBuyItemParams) {
+	rec, err := idempotencyRecord(r, params.IdempotencyKey, nil, http.StatusOK, "Item purchased")
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	ctx := r.Context()
-	err := h.shopService.BuyMerch(ctx, shop.InventoryItem{
+	err = h.shopService.BuyMerch(ctx, shop.InventoryItem{
 		Type:     item,
 		Quantity: 1,
-	})
+	}, rec)
 	if err != nil {
+		if h.respondWithKeyReused(w, err) {
+			return
+		}
 		var status int
 		var message string
 		switch {
@@ -64,6 +955,12 @@ func (h *Handler) GetApiBuyItem(w http.ResponseWriter, r *http.Request, item str
 			status, message = http.StatusNotFound, "item not found"
 		case errors.Is(err, shop.ErrInsufficientFunds):
 			status, message = http.StatusPaymentRequired, "not enough money"
+		case errors.Is(err, shop.ErrAccountFrozen):
+			status, message = http.StatusForbidden, "account is frozen"
+		case errors.Is(err, shop.ErrAccountSuspended):
+			status, message = http.StatusForbidden, "account is suspended"
+		case errors.Is(err, shop.ErrAccountClosed):
+			status, message = http.StatusForbidden, "account is closed"
 		default:
 			slog.Error("Unexpected error in BuyMerch", slog.Any("error", err))
 			status, message = http.StatusInternalServerError, "internal server error"
@@ -71,9 +968,59 @@ func (h *Handler) GetApiBuyItem(w http.ResponseWriter, r *http.Request, item str
 		h.respondWithError(w, status, message)
 		return
 	}
+	if rec != nil {
+		h.respondWithRecord(w, rec)
+		return
+	}
 	h.respondWithJSON(w, http.StatusOK, "Item purchased")
 }
 
+// idempotencyRecord prepares the idempotency record of a request with an
+// Idempotency-Key header, to be answered with payload if it succeeds. It
+// returns nil for requests without the header.
+func idempotencyRecord(r *http.Request, key *string, body []byte, status int, payload interface{}) (*idempotency.Record, error) {
+	if key == nil || *key == "" {
+		return nil, nil
+	}
+	if len(*key) > idempotency.MaxKeyLen {
+		return nil, errors.Errorf("%s must not be longer than %d characters", idempotency.Header, idempotency.MaxKeyLen)
+	}
+
+	response, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &idempotency.Record{
+		Key:         *key,
+		RequestHash: idempotency.Hash(r.Method, r.URL.Path, body),
+		Status:      status,
+		Response:    append(response, '\n'),
+	}, nil
+}
+
+// respondWithRecord sends the response stored in the idempotency record,
+// marking it if it is a replay of an earlier request.
+func (h *Handler) respondWithRecord(w http.ResponseWriter, rec *idempotency.Record) {
+	w.Header().Set("Content-Type", "application/json")
+	if rec.Replayed {
+		w.Header().Set(idempotency.ReplayedHeader, "true")
+	}
+	w.WriteHeader(rec.Status)
+	if _, err := w.Write(rec.Response); err != nil {
+		slog.Error("Failed to write stored response", slog.Any("error", err))
+	}
+}
+
+// respondWithKeyReused answers with 422 if err says that the idempotency key
+// was used for a different request and reports whether it did.
+func (h *Handler) respondWithKeyReused(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, idempotency.ErrKeyReused) {
+		return false
+	}
+	h.respondWithError(w, http.StatusUnprocessableEntity, "idempotency key was used for a different request")
+	return true
+}
+
 func (h *Handler) GetApiInfo(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	username, ok := ctx.Value(env.UsernameContextKey).(string)
@@ -91,9 +1038,24 @@ func (h *Handler) GetApiInfo(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, userInfo)
 }
 
-func (h *Handler) PostApiSendCoin(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) PostApiSendCoin(w http.ResponseWriter, r *http.Request, params merchstoreapi.PostApiSendCoinParams) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	var req merchstoreapi.SendCoinRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	delayed := req.Delayed != nil && *req.Delayed
+	status, response := http.StatusOK, "Coins sent"
+	if delayed {
+		status, response = http.StatusAccepted, "Coins held"
+	}
+	rec, err := idempotencyRecord(r, params.IdempotencyKey, body, status, response)
+	if err != nil {
 		h.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -104,18 +1066,34 @@ func (h *Handler) PostApiSendCoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.userService.TransferCoins(ctx, &users.CoinTransfer{
+	err = h.userService.TransferCoins(ctx, &users.CoinTransfer{
 		FromUser: fromUser,
 		ToUser:   req.ToUser,
 		Amount:   req.Amount,
-	})
+		Message:  req.Message,
+		Category: req.Category,
+		Delayed:  delayed,
+	}, rec)
 	if err != nil {
+		if h.respondWithAccountStatus(w, err) || h.respondWithKeyReused(w, err) {
+			return
+		}
 		var status int
 		var message string
 		if errors.Is(err, users.ErrorInsufFunds) {
 			status, message = http.StatusBadRequest, "insufficient funds"
+		} else if errors.Is(err, users.ErrorReceiverClosed) {
+			status, message = http.StatusBadRequest, "receiver account is closed"
+		} else if errors.Is(err, users.ErrorReceiverNotFound) {
+			status, message = http.StatusBadRequest, "receiver not found"
+		} else if errors.Is(err, users.ErrorUserRenamed) {
+			status, message = http.StatusConflict, err.Error()
 		} else if errors.Is(err, users.ErrorInvalidAmount) {
 			status, message = http.StatusBadRequest, "wrong amount format"
+		} else if errors.Is(err, users.ErrorSelfTransfer) {
+			status, message = http.StatusBadRequest, err.Error()
+		} else if errors.Is(err, users.ErrorInvalidMessage) || errors.Is(err, users.ErrorInvalidCategory) {
+			status, message = http.StatusBadRequest, err.Error()
 		} else {
 			status, message = http.StatusInternalServerError, "internal server error"
 		}
@@ -123,14 +1101,375 @@ func (h *Handler) PostApiSendCoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, "Coins sent")
+	if rec != nil {
+		h.respondWithRecord(w, rec)
+		return
+	}
+	h.respondWithJSON(w, status, response)
+}
+
+func (h *Handler) PutApiAdminUsersUsernameRole(w http.ResponseWriter, r *http.Request, username string) {
+	var req merchstoreapi.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	err := h.userService.SetRole(ctx, &users.SetRoleRequest{
+		Username: username,
+		Role:     string(req.Role),
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorInvalidRole):
+			status, message = http.StatusBadRequest, "invalid role"
+		case errors.Is(err, users.ErrorUserNotFound):
+			status, message = http.StatusNotFound, "user not found"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Role updated")
+}
+
+func (h *Handler) GetApiAdminUsersUsernameStatus(w http.ResponseWriter, r *http.Request, username string) {
+	status, err := h.userService.GetStatus(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, users.ErrorUserNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := merchstoreapi.UserStatus{
+		Status:  merchstoreapi.AccountStatus(status.Status),
+		History: make([]merchstoreapi.StatusChange, 0, len(status.History)),
+	}
+	for _, c := range status.History {
+		resp.History = append(resp.History, merchstoreapi.StatusChange{
+			Status:    merchstoreapi.AccountStatus(c.Status),
+			Reason:    c.Reason,
+			Admin:     optionalString(c.Admin),
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) PutApiAdminUsersUsernameStatus(w http.ResponseWriter, r *http.Request, username string) {
+	var req merchstoreapi.SetStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	err := h.userService.SetStatus(ctx, principal, &users.SetStatusRequest{
+		Username: username,
+		Status:   string(req.Status),
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorInvalidStatus):
+			status, message = http.StatusBadRequest, "invalid status"
+		case errors.Is(err, users.ErrorReasonRequired):
+			status, message = http.StatusBadRequest, "reason is required"
+		case errors.Is(err, users.ErrorUserNotFound):
+			status, message = http.StatusNotFound, "user not found"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, "Status updated")
+}
+
+func (h *Handler) PostApiAdminUsersUsernameOffboard(w http.ResponseWriter, r *http.Request, username string) {
+	var req merchstoreapi.OffboardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	summary, err := h.userService.Offboard(ctx, principal, &users.OffboardRequest{
+		Username: username,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorReasonRequired):
+			status, message = http.StatusBadRequest, "reason is required"
+		case errors.Is(err, users.ErrorCannotOffboard):
+			status, message = http.StatusBadRequest, "user cannot be offboarded"
+		case errors.Is(err, users.ErrorUserNotFound):
+			status, message = http.StatusNotFound, "user not found"
+		case errors.Is(err, users.ErrorAccountClosed):
+			status, message = http.StatusConflict, "account is already closed"
+		case errors.Is(err, users.ErrorSinkNotConfigured):
+			status, message = http.StatusConflict, "sink account is not configured"
+		case errors.Is(err, users.ErrorSinkUnavailable):
+			status, message = http.StatusConflict, "sink account not found or closed"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+
+	inventory := make([]merchstoreapi.InventoryItem, 0, len(summary.Inventory))
+	for _, item := range summary.Inventory {
+		inventory = append(inventory, merchstoreapi.InventoryItem{
+			Type:     item.Type,
+			Quantity: item.Quantity,
+		})
+	}
+	h.respondWithJSON(w, http.StatusOK, merchstoreapi.OffboardingSummary{
+		UserId:               summary.UserID,
+		Username:             summary.Username,
+		AnonymizedUsername:   summary.AnonymizedUsername,
+		SinkAccount:          summary.SinkAccount,
+		SweptCoins:           summary.SweptCoins,
+		Inventory:            inventory,
+		SentTransactions:     summary.SentTransactions,
+		ReceivedTransactions: summary.ReceivedTransactions,
+		SessionsRevoked:      summary.SessionsRevoked,
+		ApiKeysRevoked:       summary.APIKeysRevoked,
+		Reason:               summary.Reason,
+		ClosedBy:             summary.ClosedBy,
+		ClosedAt:             summary.ClosedAt,
+	})
+}
+
+func (h *Handler) GetApiAdminUsersUsernameLedger(w http.ResponseWriter, r *http.Request, username string) {
+	entries, err := h.userService.GetLedger(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, users.ErrorUserNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.LedgerEntry, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, ledgerEntry(&e))
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) PostApiAdminUsersUsernameGrant(w http.ResponseWriter, r *http.Request, username string) {
+	var req merchstoreapi.GrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	entry, err := h.userService.GrantCoins(ctx, principal, &users.GrantRequest{
+		Username: username,
+		Amount:   req.Amount,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorInvalidAmount):
+			status, message = http.StatusBadRequest, "invalid amount"
+		case errors.Is(err, users.ErrorReasonRequired):
+			status, message = http.StatusBadRequest, "reason is required"
+		case errors.Is(err, users.ErrorUserNotFound):
+			status, message = http.StatusNotFound, "user not found"
+		case errors.Is(err, users.ErrorAccountClosed):
+			status, message = http.StatusConflict, "account is closed"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, ledgerEntry(entry))
+}
+
+func (h *Handler) PostApiAdminLedgerIdRefund(w http.ResponseWriter, r *http.Request, id int64) {
+	var req merchstoreapi.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	entry, err := h.userService.RefundPurchase(ctx, principal, &users.RefundRequest{
+		EntryID: id,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorReasonRequired):
+			status, message = http.StatusBadRequest, "reason is required"
+		case errors.Is(err, users.ErrorPurchaseNotFound):
+			status, message = http.StatusNotFound, "purchase not found"
+		case errors.Is(err, users.ErrorAlreadyRefunded):
+			status, message = http.StatusConflict, "purchase already refunded"
+		case errors.Is(err, users.ErrorItemNotInInventory):
+			status, message = http.StatusConflict, "item is no longer in the inventory"
+		case errors.Is(err, users.ErrorAccountClosed):
+			status, message = http.StatusConflict, "account is closed"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, ledgerEntry(entry))
+}
+
+func ledgerEntry(e *users.LedgerEntry) merchstoreapi.LedgerEntry {
+	return merchstoreapi.LedgerEntry{
+		Id:           e.ID,
+		Kind:         merchstoreapi.LedgerEntryKind(e.Kind),
+		Amount:       e.Amount,
+		Counterparty: e.Counterparty,
+		Item:         e.Item,
+		Quantity:     e.Quantity,
+		RefundOf:     e.RefundOf,
+		Description:  e.Description,
+		CreatedBy:    e.CreatedBy,
+		CreatedAt:    e.CreatedAt,
+	}
+}
+
+func (h *Handler) PostApiAdminUsersUsernameImpersonate(w http.ResponseWriter, r *http.Request, username string) {
+	var req merchstoreapi.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	principal, ok := ctx.Value(env.PrincipalContextKey).(*users.Principal)
+	if !ok {
+		h.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	token, err := h.userService.Impersonate(ctx, principal, &users.ImpersonateRequest{
+		Username:    username,
+		Reason:      req.Reason,
+		AllowWrites: req.AllowWrites != nil && *req.AllowWrites,
+	})
+	if err != nil {
+		var status int
+		var message string
+		switch {
+		case errors.Is(err, users.ErrorReasonRequired):
+			status, message = http.StatusBadRequest, "reason is required"
+		case errors.Is(err, users.ErrorCannotImpersonate):
+			status, message = http.StatusBadRequest, "user cannot be impersonated"
+		case errors.Is(err, users.ErrorUserNotFound):
+			status, message = http.StatusNotFound, "user not found"
+		default:
+			status, message = http.StatusInternalServerError, "internal server error"
+		}
+		h.respondWithError(w, status, message)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, merchstoreapi.ImpersonationToken{
+		Token:     token.Token,
+		ExpiresIn: token.ExpiresIn,
+		ReadOnly:  token.ReadOnly,
+	})
+}
+
+func (h *Handler) GetApiAdminLockouts(w http.ResponseWriter, r *http.Request) {
+	events, err := h.userService.GetLockoutEvents(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := make([]merchstoreapi.LockoutEvent, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, merchstoreapi.LockoutEvent{
+			Scope:       merchstoreapi.LockoutEventScope(e.Scope),
+			Subject:     e.Subject,
+			Failures:    e.Failures,
+			LockedUntil: e.LockedUntil,
+			CreatedAt:   e.CreatedAt,
+		})
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetWellKnownJwksJson(w http.ResponseWriter, r *http.Request) {
+	keys := h.keySet.PublicKeys()
+
+	resp := merchstoreapi.JWKSet{Keys: make([]merchstoreapi.JWK, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, merchstoreapi.JWK{
+			Kid: key.KeyID,
+			Kty: key.KeyType,
+			Alg: optionalString(key.Algorithm),
+			Use: optionalString(key.Use),
+			Crv: optionalString(key.Curve),
+			X:   optionalString(key.X),
+			N:   optionalString(key.N),
+			E:   optionalString(key.E),
+		})
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
 
 func (h *Handler) respondWithError(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(merchstoreapi.ErrorResponse{Errors: &message}); err != nil {
-		slog.Error("Failed to encode error response: %v", err)
+		slog.Error("Failed to encode error response", slog.Any("error", err))
 	}
 }
 
@@ -138,6 +1477,6 @@ func (h *Handler) respondWithJSON(w http.ResponseWriter, statusCode int, payload
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		slog.Error("Failed to marshal JSON response: %v", err)
+		slog.Error("Failed to marshal JSON response", slog.Any("error", err))
 	}
 }
@@ -0,0 +1,47 @@
+// Package idempotency describes how money-moving requests are made safe to
+// retry. A client sends an Idempotency-Key header, and the first request with
+// the key stores its response together with the changes it makes, so that a
+// retry replays the response instead of repeating the operation.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	// Header is the request header carrying the key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed for a retried key.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// MaxKeyLen is the maximum length of a key.
+	MaxKeyLen = 255
+)
+
+// ErrKeyReused is returned when a key is sent again with a different request.
+var ErrKeyReused = errors.New("idempotency key reused with a different request")
+
+// Record is the stored outcome of a request made with a key. The caller sets
+// the key, the hash of the request and the response it will send if the
+// operation succeeds. If the key has been used before, Replayed is set and
+// Status and Response hold the response to the first request instead.
+type Record struct {
+	Key         string
+	RequestHash string
+	Status      int
+	Response    []byte
+	Replayed    bool
+}
+
+// Hash identifies a request by its method, path and body.
+func Hash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,27 @@
+package idempotency
+
+import "github.com/kingxl111/merch-store/internal/repository/postgres"
+
+// StoredKey returns the key the repository stores together with the changes
+// of the request, or nil if the request was made without a key.
+func StoredKey(rec *Record) *postgres.IdempotencyKey {
+	if rec == nil {
+		return nil
+	}
+	return &postgres.IdempotencyKey{
+		Key:         rec.Key,
+		RequestHash: rec.RequestHash,
+		Status:      rec.Status,
+		Response:    rec.Response,
+	}
+}
+
+// Replay copies the stored response to rec if the key had been used before.
+func Replay(rec *Record, key *postgres.IdempotencyKey) {
+	if key == nil || !key.Replayed {
+		return
+	}
+	rec.Replayed = true
+	rec.Status = key.Status
+	rec.Response = key.Response
+}
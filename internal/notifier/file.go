@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// fileNotifier appends notifications as JSON lines to a file, so that tests
+// and local setups can pick them up. Like logNotifier it is meant for
+// development only.
+type fileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *fileNotifier {
+	return &fileNotifier{path: path}
+}
+
+type fileNotification struct {
+	Kind      string    `json:"kind"`
+	Username  string    `json:"username"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (n *fileNotifier) NotifyPasswordReset(_ context.Context, username, token string, expiresAt time.Time) error {
+	return n.write(fileNotification{
+		Kind:      "password_reset",
+		Username:  username,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (n *fileNotifier) write(notification fileNotification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return errors.Wrap(err, "marshal notification")
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "open notification file")
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(err, "write notification")
+	}
+	return nil
+}
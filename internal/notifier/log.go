@@ -0,0 +1,26 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logNotifier writes notifications to the log. It exposes secrets such as
+// reset tokens to whoever reads the logs and is meant for development only.
+type logNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *logNotifier {
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) NotifyPasswordReset(ctx context.Context, username, token string, expiresAt time.Time) error {
+	n.logger.InfoContext(ctx, "password reset requested",
+		slog.String("user", username),
+		slog.String("token", token),
+		slog.Time("expires_at", expiresAt),
+	)
+	return nil
+}
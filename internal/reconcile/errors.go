@@ -0,0 +1,9 @@
+package reconcile
+
+import "errors"
+
+var (
+	ErrorService        = errors.New("reconcile service error")
+	ErrorBalanceChanged = errors.New("balance changed since it was checked, check again")
+	ErrorNegativeResult = errors.New("expected balance is negative")
+)
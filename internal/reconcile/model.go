@@ -0,0 +1,33 @@
+package reconcile
+
+import "time"
+
+// Report lists the users whose balance does not add up.
+type Report struct {
+	CheckedAt     time.Time     `json:"checkedAt"`
+	UsersChecked  int           `json:"usersChecked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Discrepancy is a user whose balance differs from the balance its activity
+// adds up to, or from its balance in the journal. Difference is what has to
+// be added to the balance to make it right; it is negative if the user has
+// too many coins.
+type Discrepancy struct {
+	UserID          string `json:"userId"`
+	Username        string `json:"username"`
+	Balance         int    `json:"balance"`
+	JournalBalance  int    `json:"journalBalance"`
+	ExpectedBalance int    `json:"expectedBalance"`
+	Difference      int    `json:"difference"`
+}
+
+// Correction is the outcome of correcting a discrepancy. EntryID is the
+// adjustment written to the journal, if the journal needed one, and Error
+// says why the discrepancy could not be corrected.
+type Correction struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	EntryID  *int64 `json:"entryId,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,12 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+)
+
+type Repository interface {
+	ListBalanceChecks(ctx context.Context) ([]postgres.BalanceCheck, error)
+	CorrectBalance(ctx context.Context, found *postgres.BalanceCheck, reason string) (*postgres.JournalEntry, error)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/kingxl111/merch-store/internal/reconcile"
+	repo "github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+)
+
+type reconcileService struct {
+	repo Repository
+}
+
+func NewReconcileService(repo Repository) *reconcileService {
+	return &reconcileService{
+		repo: repo,
+	}
+}
+
+// Check recomputes the balance of every user from its activity and its
+// journal and reports the users whose balance matches neither.
+func (s *reconcileService) Check(ctx context.Context) (*reconcile.Report, error) {
+	checkedAt := time.Now()
+	checks, err := s.repo.ListBalanceChecks(ctx)
+	if err != nil {
+		return nil, reconcile.ErrorService
+	}
+
+	report := &reconcile.Report{
+		CheckedAt:     checkedAt,
+		UsersChecked:  len(checks),
+		Discrepancies: []reconcile.Discrepancy{},
+	}
+	for _, c := range checks {
+		if c.Balance == c.ExpectedBalance && c.Balance == c.JournalBalance {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, reconcile.Discrepancy{
+			UserID:          c.UserID,
+			Username:        c.Username,
+			Balance:         c.Balance,
+			JournalBalance:  c.JournalBalance,
+			ExpectedBalance: c.ExpectedBalance,
+			Difference:      c.ExpectedBalance - c.Balance,
+		})
+	}
+	return report, nil
+}
+
+// Correct brings the balances in the report in line with the activity of
+// their users, writing adjustments to the journal where needed. Users that
+// have been active since the report was made are left alone and should be
+// checked again.
+func (s *reconcileService) Correct(ctx context.Context, report *reconcile.Report, reason string) []reconcile.Correction {
+	corrections := make([]reconcile.Correction, 0, len(report.Discrepancies))
+	for _, d := range report.Discrepancies {
+		correction := reconcile.Correction{
+			UserID:   d.UserID,
+			Username: d.Username,
+		}
+
+		entry, err := s.repo.CorrectBalance(ctx, &postgres.BalanceCheck{
+			UserID:          d.UserID,
+			Username:        d.Username,
+			Balance:         d.Balance,
+			JournalBalance:  d.JournalBalance,
+			ExpectedBalance: d.ExpectedBalance,
+		}, reason)
+		switch {
+		case errors.Is(err, repo.ErrorBalanceChanged):
+			correction.Error = reconcile.ErrorBalanceChanged.Error()
+		case errors.Is(err, repo.ErrorInsFunds):
+			correction.Error = reconcile.ErrorNegativeResult.Error()
+		case err != nil:
+			correction.Error = reconcile.ErrorService.Error()
+		case entry != nil:
+			correction.EntryID = &entry.ID
+		}
+
+		if correction.Error == "" {
+			slog.Warn("balance corrected",
+				slog.String("user", d.Username),
+				slog.Int("balance", d.Balance),
+				slog.Int("expected", d.ExpectedBalance),
+				slog.String("reason", reason),
+			)
+		}
+		corrections = append(corrections, correction)
+	}
+	return corrections
+}
+
+// Watch checks the balances every interval until ctx is done and logs the
+// report as JSON whenever a balance does not add up. It never corrects them.
+func (s *reconcileService) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		report, err := s.Check(ctx)
+		if err != nil {
+			slog.Error("failed to check balances", slog.Any("error", err))
+			continue
+		}
+		if len(report.Discrepancies) == 0 {
+			slog.Debug("balances add up", slog.Int("users", report.UsersChecked))
+			continue
+		}
+
+		b, err := json.Marshal(report)
+		if err != nil {
+			slog.Error("failed to encode reconciliation report", slog.Any("error", err))
+			continue
+		}
+		slog.Warn("balances do not add up",
+			slog.Int("discrepancies", len(report.Discrepancies)),
+			slog.String("report", string(b)),
+		)
+	}
+}
@@ -4,22 +4,37 @@ import "errors"
 
 var (
 	ErrorInsertUser          = errors.New("insert user error")
+	ErrorUserAlreadyExists   = errors.New("user already exists")
 	ErrorSelectUser          = errors.New("select user error")
 	ErrorDatabase            = errors.New("database error")
 	ErrorBuildingSelectQuery = errors.New("build select query error")
 	ErrorBuildingInsertQuery = errors.New("build insert query error")
 	ErrorUserPasswordCombine = errors.New("user password combine error")
 
+	ErrorBuildPasswordUpdateQuery = errors.New("failed to build password update query")
+	ErrorUpdatePassword           = errors.New("failed to update password")
+	ErrorBuildRoleUpdateQuery     = errors.New("failed to build role update query")
+	ErrorUpdateRole               = errors.New("failed to update role")
+
 	ErrorInsFunds = errors.New("insufficient funds")
 
-	ErrorTxBegin  = errors.New("failed to begin transaction")
-	ErrorTxCommit = errors.New("failed to commit transaction")
+	ErrorAccountFrozen    = errors.New("account is frozen")
+	ErrorAccountSuspended = errors.New("account is suspended")
+	ErrorAccountClosed    = errors.New("account is closed")
+	ErrorReceiverClosed   = errors.New("receiver account is closed")
+
+	ErrorTxBegin    = errors.New("failed to begin transaction")
+	ErrorTxCommit   = errors.New("failed to commit transaction")
+	ErrorTxConflict = errors.New("transaction kept conflicting with concurrent ones")
 
 	ErrorBuildSenderSelectQuery   = errors.New("failed to build sender select query")
 	ErrorSenderNotFound           = errors.New("sender not found")
 	ErrorBuildReceiverSelectQuery = errors.New("failed to build receiver select query")
 	ErrorReceiverNotFound         = errors.New("receiver not found")
 
+	ErrorBuildTransferQuery = errors.New("failed to build transfer query")
+	ErrorTransferCoins      = errors.New("failed to transfer coins")
+
 	ErrorBuildSenderUpdateQuery = errors.New("failed to build sender update query")
 	ErrorUpdateSenderBalance    = errors.New("failed to update sender balance")
 
@@ -33,6 +48,7 @@ var (
 	ErrorSelectInventory           = errors.New("failed to select inventory")
 
 	ErrorBuildItemSelectQuery      = errors.New("failed to build shop item select query")
+	ErrorSelectItem                = errors.New("failed to select shop item")
 	ErrorItemNotFound              = errors.New("shop item not found")
 	ErrorNotEnoughCoins            = errors.New("not enough coins to purchase item")
 	ErrorBuildInventoryUpdateQuery = errors.New("failed to build inventory update query")
@@ -50,4 +66,113 @@ var (
 	ErrorBuildBalanceSelectQuery = errors.New("failed to build balance select query")
 	ErrorSelectBalance           = errors.New("failed to execute balance select query")
 	ErrorScanBalance             = errors.New("failed to scan user balance")
+
+	ErrorBuildSessionQuery      = errors.New("failed to build session query")
+	ErrorInsertSession          = errors.New("failed to insert session")
+	ErrorSelectSession          = errors.New("failed to select session")
+	ErrorUpdateSession          = errors.New("failed to update session")
+	ErrorSessionNotFound        = errors.New("session not found")
+	ErrorBuildRefreshTokenQuery = errors.New("failed to build refresh token query")
+	ErrorInsertRefreshToken     = errors.New("failed to insert refresh token")
+	ErrorSelectRefreshToken     = errors.New("failed to select refresh token")
+	ErrorUpdateRefreshToken     = errors.New("failed to update refresh token")
+	ErrorRefreshTokenNotFound   = errors.New("refresh token not found")
+	ErrorRefreshTokenReused     = errors.New("refresh token reused")
+
+	ErrorBuildAuthFailureQuery = errors.New("failed to build auth failure query")
+	ErrorSelectAuthFailure     = errors.New("failed to select auth failures")
+	ErrorUpdateAuthFailure     = errors.New("failed to update auth failures")
+	ErrorInsertLockoutEvent    = errors.New("failed to insert lockout event")
+	ErrorSelectLockoutEvents   = errors.New("failed to select lockout events")
+
+	ErrorBuildTOTPQuery       = errors.New("failed to build totp query")
+	ErrorSelectTOTP           = errors.New("failed to select totp")
+	ErrorUpdateTOTP           = errors.New("failed to update totp")
+	ErrorTOTPNotFound         = errors.New("totp not found")
+	ErrorTOTPStepUsed         = errors.New("totp code already used")
+	ErrorInsertRecoveryCode   = errors.New("failed to insert recovery code")
+	ErrorUpdateRecoveryCode   = errors.New("failed to update recovery code")
+	ErrorRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrorInsertChallenge      = errors.New("failed to insert two-factor challenge")
+	ErrorUpdateChallenge      = errors.New("failed to update two-factor challenge")
+	ErrorChallengeNotFound    = errors.New("two-factor challenge not found or already used")
+
+	ErrorBuildPasswordResetQuery = errors.New("failed to build password reset query")
+	ErrorInsertPasswordReset     = errors.New("failed to insert password reset token")
+	ErrorSelectPasswordReset     = errors.New("failed to select password reset token")
+	ErrorUpdatePasswordReset     = errors.New("failed to update password reset token")
+	ErrorPasswordResetNotFound   = errors.New("password reset token not found")
+
+	ErrorBuildAPIKeyQuery = errors.New("failed to build api key query")
+	ErrorInsertAPIKey     = errors.New("failed to insert api key")
+	ErrorSelectAPIKey     = errors.New("failed to select api key")
+	ErrorUpdateAPIKey     = errors.New("failed to update api key")
+	ErrorAPIKeyNotFound   = errors.New("api key not found")
+
+	ErrorBuildImpersonationQuery  = errors.New("failed to build impersonation query")
+	ErrorInsertImpersonation      = errors.New("failed to insert impersonation")
+	ErrorSelectImpersonations     = errors.New("failed to select impersonations")
+	ErrorInsertImpersonationAudit = errors.New("failed to insert impersonation audit record")
+
+	ErrorBuildStatusQuery   = errors.New("failed to build user status query")
+	ErrorUpdateStatus       = errors.New("failed to update user status")
+	ErrorInsertStatusEvent  = errors.New("failed to insert user status event")
+	ErrorSelectStatusEvents = errors.New("failed to select user status events")
+
+	ErrorBuildOffboardingQuery = errors.New("failed to build offboarding query")
+	ErrorSinkNotFound          = errors.New("sink account not found")
+	ErrorAnonymizeUser         = errors.New("failed to anonymize user")
+	ErrorRevokeCredentials     = errors.New("failed to revoke credentials")
+
+	ErrorBuildRenameQuery  = errors.New("failed to build rename query")
+	ErrorRenameUser        = errors.New("failed to rename user")
+	ErrorRenameCooldown    = errors.New("username was changed too recently")
+	ErrorSelectUsernameLog = errors.New("failed to select username history")
+
+	ErrorBuildIdempotencyQuery = errors.New("failed to build idempotency key query")
+	ErrorInsertIdempotencyKey  = errors.New("failed to insert idempotency key")
+	ErrorSelectIdempotencyKey  = errors.New("failed to select idempotency key")
+	ErrorIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+
+	ErrorBuildLedgerQuery   = errors.New("failed to build ledger query")
+	ErrorInsertJournalEntry = errors.New("failed to insert journal entry")
+	ErrorSelectJournal      = errors.New("failed to select journal entries")
+	ErrorPurchaseNotFound   = errors.New("purchase not found")
+	ErrorAlreadyRefunded    = errors.New("purchase already refunded")
+	ErrorItemNotInInventory = errors.New("item is no longer in the inventory")
+
+	ErrorBuildReconcileQuery = errors.New("failed to build reconciliation query")
+	ErrorSelectBalanceChecks = errors.New("failed to select balance checks")
+	ErrorBalanceChanged      = errors.New("balance changed since it was checked")
+
+	ErrorBuildScheduleQuery  = errors.New("failed to build scheduled transfer query")
+	ErrorInsertSchedule      = errors.New("failed to insert scheduled transfer")
+	ErrorSelectSchedules     = errors.New("failed to select scheduled transfers")
+	ErrorUpdateSchedule      = errors.New("failed to update scheduled transfer")
+	ErrorInsertScheduledRun  = errors.New("failed to insert scheduled transfer run")
+	ErrorSelectScheduledRuns = errors.New("failed to select scheduled transfer runs")
+	ErrorUpdateScheduledRun  = errors.New("failed to update scheduled transfer run")
+	ErrorScheduleNotFound    = errors.New("scheduled transfer not found")
+	ErrorScheduleNotPending  = errors.New("scheduled transfer is already finished or cancelled")
+	ErrorScheduleClaimed     = errors.New("scheduled transfer run already claimed")
+	ErrorTooManySchedules    = errors.New("too many active scheduled transfers")
+
+	ErrorBuildCoinRequestQuery = errors.New("failed to build coin request query")
+	ErrorInsertCoinRequest     = errors.New("failed to insert coin request")
+	ErrorSelectCoinRequests    = errors.New("failed to select coin requests")
+	ErrorUpdateCoinRequest     = errors.New("failed to update coin request")
+	ErrorCoinRequestNotFound   = errors.New("coin request not found")
+	ErrorCoinRequestExpired    = errors.New("coin request has expired")
+	ErrorCoinRequestResolved   = errors.New("coin request is already paid or declined")
+	ErrorTooManyCoinRequests   = errors.New("too many pending coin requests")
+	ErrorPayerNotFound         = errors.New("payer not found")
+	ErrorPayerClosed           = errors.New("payer account is closed")
+
+	ErrorBuildPendingTransferQuery = errors.New("failed to build pending transfer query")
+	ErrorInsertPendingTransfer     = errors.New("failed to insert pending transfer")
+	ErrorSelectPendingTransfers    = errors.New("failed to select pending transfers")
+	ErrorUpdatePendingTransfer     = errors.New("failed to update pending transfer")
+	ErrorPendingTransferNotFound   = errors.New("pending transfer not found")
+	ErrorPendingTransferResolved   = errors.New("pending transfer is already settled or cancelled")
+	ErrorPendingTransferSettling   = errors.New("pending transfer is past its cancellation window")
 )
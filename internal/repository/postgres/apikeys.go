@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	apiKeysTable     = "api_keys"
+	apiKeyUsageTable = "api_key_usage"
+
+	nameColumn       = "name"
+	prefixColumn     = "prefix"
+	keyHashColumn    = "key_hash"
+	scopesColumn     = "scopes"
+	quotaColumn      = "quota"
+	lastUsedAtColumn = "last_used_at"
+
+	// apiKeyTouchInterval limits how often the last-used time of a key is
+	// written.
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKey stores a new key. The generated id and creation time are
+// written back to key.
+func (r *repository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	builder := sq.Insert(apiKeysTable).
+		Columns(userIDColumn, nameColumn, prefixColumn, keyHashColumn, scopesColumn, quotaColumn, createdAtColumn).
+		Values(key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.Quota, time.Now()).
+		Suffix("RETURNING " + idColumn + ", " + createdAtColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildAPIKeyQuery
+	}
+
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return repo.ErrorInsertAPIKey
+	}
+	return nil
+}
+
+// ListAPIKeys returns the keys of the user that have not been revoked.
+func (r *repository) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	builder := sq.Select(idColumn, userIDColumn, nameColumn, prefixColumn, scopesColumn, quotaColumn, createdAtColumn, lastUsedAtColumn).
+		From(apiKeysTable).
+		Where(sq.Eq{userIDColumn: userID, revokedAtColumn: nil}).
+		OrderBy(createdAtColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildAPIKeyQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectAPIKey
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.Quota, &k.CreatedAt, &k.LastUsedAt); err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of the user.
+func (r *repository) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	builder := sq.Update(apiKeysTable).
+		Set(revokedAtColumn, time.Now()).
+		Where(sq.Eq{idColumn: keyID, userIDColumn: userID, revokedAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildAPIKeyQuery
+	}
+
+	tag, err := r.db.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return repo.ErrorUpdateAPIKey
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrorAPIKeyNotFound
+	}
+	return nil
+}
+
+// GetAPIKey looks up an active key by its hash.
+func (r *repository) GetAPIKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	selectKey := sq.Select("k."+idColumn, "k."+userIDColumn, "u."+usernameColumn, "u."+roleColumn, "u."+statusColumn, "k."+nameColumn, "k."+scopesColumn, "k."+quotaColumn, "k."+lastUsedAtColumn).
+		From(apiKeysTable + " k").
+		Join(usersTable + " u ON u.id = k.user_id").
+		Where(sq.Eq{"k." + keyHashColumn: keyHash, "k." + revokedAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectKey.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildAPIKeyQuery
+	}
+
+	var key APIKey
+	err = r.db.pool.QueryRow(ctx, query, args...).Scan(
+		&key.ID, &key.UserID, &key.Username, &key.Role, &key.Status, &key.Name, &key.Scopes, &key.Quota, &key.LastUsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorAPIKeyNotFound
+		}
+		return nil, repo.ErrorSelectAPIKey
+	}
+	return &key, nil
+}
+
+// CountAPIKeyRequest counts a request against the key and returns the number
+// of requests made with it today (UTC), this one included, and its quota.
+func (r *repository) CountAPIKeyRequest(ctx context.Context, keyID string) (int, *int, error) {
+	countRequest := sq.Insert(apiKeyUsageTable).
+		Columns("key_id", "day", "requests").
+		Values(keyID, sq.Expr("(NOW() AT TIME ZONE 'UTC')::date"), 1).
+		Suffix(`ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+			RETURNING requests, (SELECT quota FROM api_keys WHERE id = api_key_usage.key_id)`).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := countRequest.ToSql()
+	if err != nil {
+		return 0, nil, repo.ErrorBuildAPIKeyQuery
+	}
+
+	var requests int
+	var quota *int
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&requests, &quota); err != nil {
+		return 0, nil, repo.ErrorUpdateAPIKey
+	}
+
+	now := time.Now()
+	touch := sq.Update(apiKeysTable).
+		Set(lastUsedAtColumn, now).
+		Where(sq.Eq{idColumn: keyID}).
+		Where(sq.Or{sq.Eq{lastUsedAtColumn: nil}, sq.Lt{lastUsedAtColumn: now.Add(-apiKeyTouchInterval)}}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = touch.ToSql()
+	if err != nil {
+		return 0, nil, repo.ErrorBuildAPIKeyQuery
+	}
+
+	if _, err = r.db.pool.Exec(ctx, query, args...); err != nil {
+		return 0, nil, repo.ErrorUpdateAPIKey
+	}
+
+	return requests, quota, nil
+}
@@ -0,0 +1,294 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	coinRequestsTable = "coin_requests"
+
+	requesterIDColumn = "requester_id"
+	payerIDColumn     = "payer_id"
+	resolvedAtColumn  = "resolved_at"
+
+	requestPending  = "pending"
+	requestPaid     = "paid"
+	requestDeclined = "declined"
+	requestExpired  = "expired"
+)
+
+// selectCoinRequests selects coin requests with the current usernames of
+// both sides. A pending request past its expiry is reported as expired.
+func selectCoinRequests() sq.SelectBuilder {
+	return sq.Select(
+		"c."+idColumn, "c."+requesterIDColumn, "q."+usernameColumn, "c."+payerIDColumn, "p."+usernameColumn,
+		"c."+amountColumn, "c."+reasonColumn,
+	).
+		Column(sq.Expr("CASE WHEN c."+statusColumn+" = ? AND c."+expiresAtColumn+" <= NOW() THEN ? ELSE c."+statusColumn+" END",
+			requestPending, requestExpired)).
+		Columns("c."+createdAtColumn, "c."+expiresAtColumn, "c."+resolvedAtColumn).
+		From(coinRequestsTable + " c").
+		Join(usersTable + " q ON q.id = c." + requesterIDColumn).
+		Join(usersTable + " p ON p.id = c." + payerIDColumn).
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanCoinRequest(row pgx.Row) (*CoinRequest, error) {
+	var c CoinRequest
+	err := row.Scan(&c.ID, &c.RequesterID, &c.RequesterUsername, &c.PayerID, &c.PayerUsername,
+		&c.Amount, &c.Reason, &c.Status, &c.CreatedAt, &c.ExpiresAt, &c.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCoinRequest stores a request of the user with the id c.RequesterID
+// for coins from the user named c.PayerUsername. The payer must not be
+// closed and the requester may have at most maxPending pending requests.
+// The generated id and creation time are written back to c.
+func (r *repository) CreateCoinRequest(ctx context.Context, c *CoinRequest, maxPending int) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		// Locking the requester makes concurrent requests wait for each
+		// other, so the limit below holds.
+		requester, err := selectUserForUpdate(ctx, tx, sq.Eq{idColumn: c.RequesterID})
+		if err != nil {
+			return err
+		}
+		if requester.Status == statusClosed {
+			return repo.ErrorAccountClosed
+		}
+
+		selectPayer := sq.Select(idColumn, usernameColumn, statusColumn).
+			From(usersTable).
+			Where(usernameIs(usernameColumn, c.PayerUsername)).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := selectPayer.ToSql()
+		if err != nil {
+			return repo.ErrorBuildCoinRequestQuery
+		}
+
+		var payerStatus string
+		err = tx.QueryRow(ctx, query, args...).Scan(&c.PayerID, &c.PayerUsername, &payerStatus)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return repo.ErrorPayerNotFound
+			}
+			return txError(err, repo.ErrorSelectUser)
+		}
+		if payerStatus == statusClosed {
+			return repo.ErrorPayerClosed
+		}
+
+		countPending := sq.Select("COUNT(*)").
+			From(coinRequestsTable).
+			Where(sq.Eq{requesterIDColumn: c.RequesterID, statusColumn: requestPending}).
+			Where(sq.Expr(expiresAtColumn + " > NOW()")).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = countPending.ToSql()
+		if err != nil {
+			return repo.ErrorBuildCoinRequestQuery
+		}
+
+		var pending int
+		if err := tx.QueryRow(ctx, query, args...).Scan(&pending); err != nil {
+			return txError(err, repo.ErrorSelectCoinRequests)
+		}
+		if pending >= maxPending {
+			return repo.ErrorTooManyCoinRequests
+		}
+
+		insert := sq.Insert(coinRequestsTable).
+			Columns(requesterIDColumn, payerIDColumn, amountColumn, reasonColumn, statusColumn, createdAtColumn, expiresAtColumn).
+			Values(c.RequesterID, c.PayerID, c.Amount, c.Reason, requestPending, time.Now(), c.ExpiresAt).
+			Suffix("RETURNING " + idColumn + ", " + createdAtColumn).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return repo.ErrorBuildCoinRequestQuery
+		}
+
+		if err := tx.QueryRow(ctx, query, args...).Scan(&c.ID, &c.CreatedAt); err != nil {
+			return txError(err, repo.ErrorInsertCoinRequest)
+		}
+		c.RequesterUsername = requester.Username
+		c.Status = requestPending
+		return nil
+	})
+}
+
+// ListIncomingCoinRequests returns the pending requests the user is asked to
+// pay, oldest first, so that those expiring soonest come first.
+func (r *repository) ListIncomingCoinRequests(ctx context.Context, userID string, limit int) ([]CoinRequest, error) {
+	return r.listCoinRequests(ctx, selectCoinRequests().
+		Where(sq.Eq{"c." + payerIDColumn: userID, "c." + statusColumn: requestPending}).
+		Where(sq.Expr("c."+expiresAtColumn+" > NOW()")).
+		OrderBy("c."+createdAtColumn, "c."+idColumn).
+		Limit(uint64(limit)))
+}
+
+// ListOutgoingCoinRequests returns the latest requests made by the user,
+// newest first, whatever their status.
+func (r *repository) ListOutgoingCoinRequests(ctx context.Context, userID string, limit int) ([]CoinRequest, error) {
+	return r.listCoinRequests(ctx, selectCoinRequests().
+		Where(sq.Eq{"c." + requesterIDColumn: userID}).
+		OrderBy("c."+createdAtColumn+" DESC", "c."+idColumn).
+		Limit(uint64(limit)))
+}
+
+func (r *repository) listCoinRequests(ctx context.Context, builder sq.SelectBuilder) ([]CoinRequest, error) {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildCoinRequestQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectCoinRequests
+	}
+	defer rows.Close()
+
+	var requests []CoinRequest
+	for rows.Next() {
+		c, err := scanCoinRequest(rows)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		requests = append(requests, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectCoinRequests
+	}
+	return requests, nil
+}
+
+// lockPendingCoinRequest locks the request the user is asked to pay and
+// checks that it is still pending.
+func lockPendingCoinRequest(ctx context.Context, tx pgx.Tx, payerID, id string) (*CoinRequest, error) {
+	query, args, err := selectCoinRequests().
+		Where(sq.Eq{"c." + idColumn: id, "c." + payerIDColumn: payerID}).
+		Suffix("FOR UPDATE OF c").
+		ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildCoinRequestQuery
+	}
+
+	c, err := scanCoinRequest(tx.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorCoinRequestNotFound
+		}
+		return nil, txError(err, repo.ErrorSelectCoinRequests)
+	}
+
+	switch c.Status {
+	case requestPending:
+		return c, nil
+	case requestExpired:
+		return nil, repo.ErrorCoinRequestExpired
+	default:
+		return nil, repo.ErrorCoinRequestResolved
+	}
+}
+
+func resolveCoinRequest(ctx context.Context, tx pgx.Tx, c *CoinRequest, status string) error {
+	now := time.Now()
+	query, args, err := sq.Update(coinRequestsTable).
+		Set(statusColumn, status).
+		Set(resolvedAtColumn, now).
+		Where(sq.Eq{idColumn: c.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return repo.ErrorBuildCoinRequestQuery
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return txError(err, repo.ErrorUpdateCoinRequest)
+	}
+	c.Status = status
+	c.ResolvedAt = &now
+	return nil
+}
+
+// ApproveCoinRequest pays a pending request the user is asked to pay: the
+// coins are transferred, with the reason of the request as the message, and
+// the request is marked paid in the same transaction. The errors of the
+// transfer are those of TransferCoins.
+func (r *repository) ApproveCoinRequest(ctx context.Context, payerID, id string) (*CoinRequest, error) {
+	var request *CoinRequest
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		c, err := lockPendingCoinRequest(ctx, tx, payerID, id)
+		if err != nil {
+			return err
+		}
+
+		err = transferCoins(ctx, tx, &CoinTransaction{
+			FromUsername: c.PayerUsername,
+			ToUsername:   c.RequesterUsername,
+			Amount:       c.Amount,
+			Message:      &c.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := resolveCoinRequest(ctx, tx, c, requestPaid); err != nil {
+			return err
+		}
+		request = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// DeclineCoinRequest declines a pending request the user is asked to pay.
+func (r *repository) DeclineCoinRequest(ctx context.Context, payerID, id string) (*CoinRequest, error) {
+	var request *CoinRequest
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		c, err := lockPendingCoinRequest(ctx, tx, payerID, id)
+		if err != nil {
+			return err
+		}
+		if err := resolveCoinRequest(ctx, tx, c, requestDeclined); err != nil {
+			return err
+		}
+		request = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// declineCoinRequests declines the pending requests made by or asked of the
+// user, so that none are left over once the account is closed.
+func declineCoinRequests(ctx context.Context, db execer, userID string, at time.Time) error {
+	query, args, err := sq.Update(coinRequestsTable).
+		Set(statusColumn, requestDeclined).
+		Set(resolvedAtColumn, at).
+		Where(sq.Or{sq.Eq{requesterIDColumn: userID}, sq.Eq{payerIDColumn: userID}}).
+		Where(sq.Eq{statusColumn: requestPending}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return repo.ErrorBuildCoinRequestQuery
+	}
+	if _, err := db.Exec(ctx, query, args...); err != nil {
+		return txError(err, repo.ErrorUpdateCoinRequest)
+	}
+	return nil
+}
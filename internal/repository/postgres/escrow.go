@@ -0,0 +1,499 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	pendingTransfersTable = "pending_transfers"
+
+	settlesAtColumn = "settles_at"
+
+	transferPending   = "pending"
+	transferSettled   = "settled"
+	transferCancelled = "cancelled"
+	transferReturned  = "returned"
+
+	entryHold    = "hold"
+	entryRelease = "release"
+
+	// escrowAccount holds the coins of delayed transfers until they are
+	// settled or cancelled.
+	escrowAccount = "escrow"
+)
+
+func selectPendingTransfers() sq.SelectBuilder {
+	return sq.Select(
+		"p."+idColumn, "p."+senderIDColumn, "f."+usernameColumn, "p."+receiverIDColumn, "t."+usernameColumn,
+		"p."+amountColumn, "p."+messageColumn, "p."+categoryColumn, "p."+statusColumn, "p."+transactionIDColumn,
+		"p."+createdAtColumn, "p."+settlesAtColumn, "p."+resolvedAtColumn,
+	).
+		From(pendingTransfersTable + " p").
+		Join(usersTable + " f ON f.id = p." + senderIDColumn).
+		Join(usersTable + " t ON t.id = p." + receiverIDColumn).
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanPendingTransfer(row pgx.Row) (*PendingTransfer, error) {
+	var p PendingTransfer
+	err := row.Scan(&p.ID, &p.FromUserID, &p.FromUsername, &p.ToUserID, &p.ToUsername,
+		&p.Amount, &p.Message, &p.Category, &p.Status, &p.TransactionID,
+		&p.CreatedAt, &p.SettlesAt, &p.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// HoldCoins makes a delayed transfer between the users named in p: the
+// coins leave the balance of the sender for the escrow account right away
+// and reach the receiver when the transfer is settled after p.SettlesAt. The
+// checks are those of TransferCoins, and so are the errors and the handling
+// of the idempotency key. The generated id and creation time are written
+// back to p.
+func (r *repository) HoldCoins(ctx context.Context, p *PendingTransfer, idem *IdempotencyKey) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		if idem != nil {
+			claimed, err := claimUserIdempotencyKey(ctx, tx, p.FromUsername, idem)
+			if err != nil {
+				if errors.Is(err, repo.ErrorUserNotFound) {
+					return repo.ErrorSenderNotFound
+				}
+				return err
+			}
+			if !claimed {
+				return nil
+			}
+		}
+		return holdCoins(ctx, tx, p)
+	})
+}
+
+func holdCoins(ctx context.Context, tx pgx.Tx, p *PendingTransfer) error {
+	// Both rows are locked at once and in id order, as transfer_coins does,
+	// so that holds and transfers between the same users cannot deadlock.
+	selectUsers := sq.Select(idColumn, usernameColumn, balanceColumn, statusColumn).
+		From(usersTable).
+		Where(sq.Or{
+			usernameIs(usernameColumn, p.FromUsername),
+			usernameIs(usernameColumn, p.ToUsername),
+		}).
+		OrderBy(idColumn).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectUsers.ToSql()
+	if err != nil {
+		return repo.ErrorBuildPendingTransferQuery
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return txError(err, repo.ErrorSelectUser)
+	}
+	var sender, receiver *User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Coins, &u.Status); err != nil {
+			rows.Close()
+			return repo.ErrorScanQuery
+		}
+		if strings.EqualFold(u.Username, p.FromUsername) {
+			sender = &u
+		}
+		if strings.EqualFold(u.Username, p.ToUsername) {
+			receiver = &u
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return txError(err, repo.ErrorSelectUser)
+	}
+
+	switch {
+	case sender == nil:
+		return repo.ErrorSenderNotFound
+	case sender.Status != statusActive:
+		return transferResults[sender.Status]
+	case receiver == nil:
+		return repo.ErrorReceiverNotFound
+	case receiver.Status == statusClosed:
+		return repo.ErrorReceiverClosed
+	case sender.Coins < p.Amount:
+		return repo.ErrorInsFunds
+	}
+
+	insert := sq.Insert(pendingTransfersTable).
+		Columns(senderIDColumn, receiverIDColumn, amountColumn, messageColumn, categoryColumn,
+			statusColumn, createdAtColumn, settlesAtColumn).
+		Values(sender.ID, receiver.ID, p.Amount, p.Message, p.Category,
+			transferPending, time.Now(), p.SettlesAt).
+		Suffix("RETURNING " + idColumn + ", " + createdAtColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = insert.ToSql()
+	if err != nil {
+		return repo.ErrorBuildPendingTransferQuery
+	}
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(&p.ID, &p.CreatedAt); err != nil {
+		return txError(err, repo.ErrorInsertPendingTransfer)
+	}
+	p.FromUserID, p.FromUsername = sender.ID, sender.Username
+	p.ToUserID, p.ToUsername = receiver.ID, receiver.Username
+	p.Status = transferPending
+
+	return postEntry(ctx, tx, &JournalEntry{
+		Kind:          entryHold,
+		DebitUserID:   &sender.ID,
+		CreditAccount: systemAccount(escrowAccount),
+		Amount:        p.Amount,
+		Description:   "held for delayed transfer " + p.ID,
+	})
+}
+
+// ListPendingTransfers returns the delayed transfers sent or received by the
+// user that are still held, those settling soonest first.
+func (r *repository) ListPendingTransfers(ctx context.Context, userID string, limit int) ([]PendingTransfer, error) {
+	builder := selectPendingTransfers().
+		Where(sq.Or{sq.Eq{"p." + senderIDColumn: userID}, sq.Eq{"p." + receiverIDColumn: userID}}).
+		Where(sq.Eq{"p." + statusColumn: transferPending}).
+		OrderBy("p."+settlesAtColumn, "p."+idColumn).
+		Limit(uint64(limit))
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildPendingTransferQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectPendingTransfers
+	}
+	defer rows.Close()
+
+	var transfers []PendingTransfer
+	for rows.Next() {
+		p, err := scanPendingTransfer(rows)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		transfers = append(transfers, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectPendingTransfers
+	}
+	return transfers, nil
+}
+
+// GetHeldCoins returns how many coins of the user are held in its delayed
+// transfers.
+func (r *repository) GetHeldCoins(ctx context.Context, username string) (int, error) {
+	builder := sq.Select("COALESCE(SUM(p." + amountColumn + "), 0)").
+		From(pendingTransfersTable + " p").
+		Join(usersTable + " u ON u.id = p." + senderIDColumn).
+		Where(usernameIs("u."+usernameColumn, username)).
+		Where(sq.Eq{"p." + statusColumn: transferPending}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, repo.ErrorBuildPendingTransferQuery
+	}
+
+	var held int
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&held); err != nil {
+		return 0, repo.ErrorSelectPendingTransfers
+	}
+	return held, nil
+}
+
+// DuePendingTransfers returns the ids of the held transfers that are due to
+// be settled at now, oldest first.
+func (r *repository) DuePendingTransfers(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	builder := sq.Select(idColumn).
+		From(pendingTransfersTable).
+		Where(sq.Eq{statusColumn: transferPending}).
+		Where(sq.LtOrEq{settlesAtColumn: now}).
+		OrderBy(settlesAtColumn, idColumn).
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildPendingTransferQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectPendingTransfers
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectPendingTransfers
+	}
+	return ids, nil
+}
+
+// lockPendingTransfer locks the transfer matching where and checks that it
+// is still held.
+func lockPendingTransfer(ctx context.Context, tx pgx.Tx, where sq.Sqlizer) (*PendingTransfer, error) {
+	query, args, err := selectPendingTransfers().
+		Where(where).
+		Suffix("FOR UPDATE OF p").
+		ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildPendingTransferQuery
+	}
+
+	p, err := scanPendingTransfer(tx.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorPendingTransferNotFound
+		}
+		return nil, txError(err, repo.ErrorSelectPendingTransfers)
+	}
+	if p.Status != transferPending {
+		return nil, repo.ErrorPendingTransferResolved
+	}
+	return p, nil
+}
+
+// lockPendingParties locks the sender and the receiver of the transfer. Both
+// rows are locked at once and in id order, as holdCoins does, so that
+// settling cannot deadlock with holds and transfers between the same users.
+func lockPendingParties(ctx context.Context, tx pgx.Tx, p *PendingTransfer) (sender, receiver *User, err error) {
+	selectUsers := sq.Select(idColumn, usernameColumn, balanceColumn, statusColumn).
+		From(usersTable).
+		Where(sq.Eq{idColumn: []string{p.FromUserID, p.ToUserID}}).
+		OrderBy(idColumn).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectUsers.ToSql()
+	if err != nil {
+		return nil, nil, repo.ErrorBuildPendingTransferQuery
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, txError(err, repo.ErrorSelectUser)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Coins, &u.Status); err != nil {
+			return nil, nil, repo.ErrorScanQuery
+		}
+		if u.ID == p.FromUserID {
+			sender = &u
+		}
+		if u.ID == p.ToUserID {
+			receiver = &u
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, txError(err, repo.ErrorSelectUser)
+	}
+	if sender == nil || receiver == nil {
+		return nil, nil, repo.ErrorUserNotFound
+	}
+	return sender, receiver, nil
+}
+
+// releaseHold gives the coins held for the transfer back to its sender.
+func releaseHold(ctx context.Context, tx pgx.Tx, p *PendingTransfer, description string) error {
+	return postEntry(ctx, tx, &JournalEntry{
+		Kind:         entryRelease,
+		DebitAccount: systemAccount(escrowAccount),
+		CreditUserID: &p.FromUserID,
+		Amount:       p.Amount,
+		Description:  description,
+	})
+}
+
+func resolvePendingTransfer(ctx context.Context, tx pgx.Tx, p *PendingTransfer, status string) error {
+	now := time.Now()
+	query, args, err := sq.Update(pendingTransfersTable).
+		Set(statusColumn, status).
+		Set(transactionIDColumn, p.TransactionID).
+		Set(resolvedAtColumn, now).
+		Where(sq.Eq{idColumn: p.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return repo.ErrorBuildPendingTransferQuery
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return txError(err, repo.ErrorUpdatePendingTransfer)
+	}
+	p.Status = status
+	p.ResolvedAt = &now
+	return nil
+}
+
+// cancelHeldTransfers cancels all held transfers of the sender with the
+// userID and gives the coins back. It returns how many coins were given back.
+func cancelHeldTransfers(ctx context.Context, tx pgx.Tx, userID, description string) (int, error) {
+	query, args, err := selectPendingTransfers().
+		Where(sq.Eq{"p." + senderIDColumn: userID, "p." + statusColumn: transferPending}).
+		OrderBy("p." + idColumn).
+		Suffix("FOR UPDATE OF p").
+		ToSql()
+	if err != nil {
+		return 0, repo.ErrorBuildPendingTransferQuery
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return 0, txError(err, repo.ErrorSelectPendingTransfers)
+	}
+	var transfers []*PendingTransfer
+	for rows.Next() {
+		p, err := scanPendingTransfer(rows)
+		if err != nil {
+			rows.Close()
+			return 0, repo.ErrorScanQuery
+		}
+		transfers = append(transfers, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, txError(err, repo.ErrorSelectPendingTransfers)
+	}
+
+	released := 0
+	for _, p := range transfers {
+		if err := releaseHold(ctx, tx, p, description); err != nil {
+			return 0, err
+		}
+		if err := resolvePendingTransfer(ctx, tx, p, transferCancelled); err != nil {
+			return 0, err
+		}
+		released += p.Amount
+	}
+	return released, nil
+}
+
+// CancelPendingTransfer cancels a held transfer of the sender with the
+// userID and gives the coins back. A transfer can only be cancelled before
+// its SettlesAt, even if it has not been settled yet.
+func (r *repository) CancelPendingTransfer(ctx context.Context, userID, id string) (*PendingTransfer, error) {
+	var transfer *PendingTransfer
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		p, err := lockPendingTransfer(ctx, tx, sq.Eq{"p." + idColumn: id, "p." + senderIDColumn: userID})
+		if err != nil {
+			return err
+		}
+		if !time.Now().Before(p.SettlesAt) {
+			return repo.ErrorPendingTransferSettling
+		}
+
+		if err := releaseHold(ctx, tx, p, "cancelled delayed transfer "+p.ID); err != nil {
+			return err
+		}
+		if err := resolvePendingTransfer(ctx, tx, p, transferCancelled); err != nil {
+			return err
+		}
+		transfer = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// SettlePendingTransfer makes a held transfer whose window is over. The hold
+// is released and the coins are sent as a regular transfer, so the transfer
+// shows up in the history and the journal like any other. If the sender can
+// no longer spend coins or the receiver has been closed in the meantime, the
+// coins go back to the sender and the transfer is marked returned. A
+// transfer settled or cancelled concurrently yields
+// ErrorPendingTransferResolved.
+func (r *repository) SettlePendingTransfer(ctx context.Context, id string) (*PendingTransfer, error) {
+	var transfer *PendingTransfer
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		p, err := lockPendingTransfer(ctx, tx, sq.Eq{"p." + idColumn: id})
+		if err != nil {
+			return err
+		}
+
+		sender, receiver, err := lockPendingParties(ctx, tx, p)
+		if err != nil {
+			return err
+		}
+		if sender.Status != statusActive || receiver.Status == statusClosed {
+			if err := releaseHold(ctx, tx, p, "returned delayed transfer "+p.ID); err != nil {
+				return err
+			}
+			if err := resolvePendingTransfer(ctx, tx, p, transferReturned); err != nil {
+				return err
+			}
+			transfer = p
+			return nil
+		}
+
+		if err := releaseHold(ctx, tx, p, "settled delayed transfer "+p.ID); err != nil {
+			return err
+		}
+
+		insertTransaction := sq.Insert(transactionsTable).
+			Columns(senderIDColumn, receiverIDColumn, amountColumn, messageColumn, categoryColumn, createdAtColumn).
+			Values(p.FromUserID, p.ToUserID, p.Amount, p.Message, p.Category, time.Now()).
+			Suffix("RETURNING " + idColumn).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := insertTransaction.ToSql()
+		if err != nil {
+			return repo.ErrorBuildInsertTransactionQuery
+		}
+
+		var transactionID int
+		if err := tx.QueryRow(ctx, query, args...).Scan(&transactionID); err != nil {
+			return txError(err, repo.ErrorInsertTransactionRecord)
+		}
+
+		entry := &JournalEntry{
+			Kind:          entryTransfer,
+			DebitUserID:   &p.FromUserID,
+			CreditUserID:  &p.ToUserID,
+			Amount:        p.Amount,
+			TransactionID: &transactionID,
+		}
+		if p.Message != nil {
+			entry.Description = *p.Message
+		}
+		if err := postEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		p.TransactionID = &transactionID
+		if err := resolvePendingTransfer(ctx, tx, p, transferSettled); err != nil {
+			return err
+		}
+		transfer = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	idempotencyKeysTable = "idempotency_keys"
+
+	keyColumn         = "key"
+	requestHashColumn = "request_hash"
+	responseColumn    = "response"
+
+	// idempotencyKeyTTL is how long a key is remembered. Afterwards it can
+	// be used for a new request.
+	idempotencyKeyTTL = 24 * time.Hour
+)
+
+// claimIdempotencyKey stores the key with the response to the request in the
+// transaction of the operation, so that the key is only kept if the operation
+// commits. A concurrent request with the same key waits for that transaction
+// to finish. If the key is already stored, the stored response is copied to
+// key, Replayed is set and claimIdempotencyKey reports false; the caller must
+// then skip the operation. A key stored for a different request yields
+// ErrorIdempotencyKeyReused.
+func claimIdempotencyKey(ctx context.Context, tx queryRower, key *IdempotencyKey) (bool, error) {
+	insert := sq.Insert(idempotencyKeysTable).
+		Columns(userIDColumn, keyColumn, requestHashColumn, statusColumn, responseColumn, createdAtColumn).
+		Values(key.UserID, key.Key, key.RequestHash, key.Status, key.Response, time.Now()).
+		Suffix(`ON CONFLICT (user_id, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status = EXCLUDED.status,
+			response = EXCLUDED.response,
+			created_at = EXCLUDED.created_at
+			WHERE idempotency_keys.created_at < NOW() - make_interval(secs => ?)
+			RETURNING user_id`, idempotencyKeyTTL.Seconds()).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return false, repo.ErrorBuildIdempotencyQuery
+	}
+
+	var userID string
+	err = tx.QueryRow(ctx, query, args...).Scan(&userID)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, txError(err, repo.ErrorInsertIdempotencyKey)
+	}
+
+	selectStored := sq.Select(requestHashColumn, statusColumn, responseColumn).
+		From(idempotencyKeysTable).
+		Where(sq.Eq{userIDColumn: key.UserID, keyColumn: key.Key}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = selectStored.ToSql()
+	if err != nil {
+		return false, repo.ErrorBuildIdempotencyQuery
+	}
+
+	var stored IdempotencyKey
+	err = tx.QueryRow(ctx, query, args...).Scan(&stored.RequestHash, &stored.Status, &stored.Response)
+	if err != nil {
+		return false, txError(err, repo.ErrorSelectIdempotencyKey)
+	}
+	if stored.RequestHash != key.RequestHash {
+		return false, repo.ErrorIdempotencyKeyReused
+	}
+
+	key.Status = stored.Status
+	key.Response = stored.Response
+	key.Replayed = true
+	return false, nil
+}
+
+// claimUserIdempotencyKey claims the key for the user with the username, see
+// claimIdempotencyKey. The user is looked up without locking the row, so that
+// a retry waits for the key rather than for the user.
+func claimUserIdempotencyKey(ctx context.Context, tx queryRower, username string, key *IdempotencyKey) (bool, error) {
+	selectUser := sq.Select(idColumn).
+		From(usersTable).
+		Where(usernameIs(usernameColumn, username)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectUser.ToSql()
+	if err != nil {
+		return false, repo.ErrorBuildIdempotencyQuery
+	}
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(&key.UserID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, repo.ErrorUserNotFound
+		}
+		return false, txError(err, repo.ErrorSelectUser)
+	}
+	return claimIdempotencyKey(ctx, tx, key)
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	impersonationsTable        = "impersonations"
+	impersonationRequestsTable = "impersonation_requests"
+
+	adminIDColumn  = "admin_id"
+	reasonColumn   = "reason"
+	readOnlyColumn = "read_only"
+)
+
+// CreateImpersonation stores a new impersonation. The generated id and
+// creation time are written back to imp.
+func (r *repository) CreateImpersonation(ctx context.Context, imp *Impersonation) error {
+	builder := sq.Insert(impersonationsTable).
+		Columns(adminIDColumn, userIDColumn, sessionIDColumn, reasonColumn, readOnlyColumn, createdAtColumn, expiresAtColumn).
+		Values(imp.AdminID, imp.UserID, imp.SessionID, imp.Reason, imp.ReadOnly, time.Now(), imp.ExpiresAt).
+		Suffix("RETURNING " + idColumn + ", " + createdAtColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildImpersonationQuery
+	}
+
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&imp.ID, &imp.CreatedAt); err != nil {
+		return repo.ErrorInsertImpersonation
+	}
+	return nil
+}
+
+// RecordImpersonatedRequest adds a request made under an impersonation to
+// the audit trail.
+func (r *repository) RecordImpersonatedRequest(ctx context.Context, impersonationID, method, path string, status int) error {
+	builder := sq.Insert(impersonationRequestsTable).
+		Columns("impersonation_id", "method", "path", "status", createdAtColumn).
+		Values(impersonationID, method, path, status, time.Now()).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildImpersonationQuery
+	}
+
+	if _, err = r.db.pool.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorInsertImpersonationAudit
+	}
+	return nil
+}
+
+// ListUserImpersonations returns the latest impersonations of the user,
+// newest first.
+func (r *repository) ListUserImpersonations(ctx context.Context, username string, limit int) ([]Impersonation, error) {
+	builder := sq.Select(
+		"i."+idColumn, "i."+adminIDColumn, "a."+usernameColumn, "i."+userIDColumn, "i."+reasonColumn,
+		"i."+readOnlyColumn, "i."+createdAtColumn, "i."+expiresAtColumn,
+	).
+		From(impersonationsTable + " i").
+		Join(usersTable + " u ON u.id = i.user_id").
+		Join(usersTable + " a ON a.id = i.admin_id").
+		Where(usernameIs("u."+usernameColumn, username)).
+		OrderBy("i." + createdAtColumn + " DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildImpersonationQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectImpersonations
+	}
+	defer rows.Close()
+
+	var imps []Impersonation
+	for rows.Next() {
+		var imp Impersonation
+		err := rows.Scan(&imp.ID, &imp.AdminID, &imp.AdminUsername, &imp.UserID, &imp.Reason, &imp.ReadOnly, &imp.CreatedAt, &imp.ExpiresAt)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		imps = append(imps, imp)
+	}
+	return imps, nil
+}
@@ -0,0 +1,328 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	journalEntriesTable = "journal_entries"
+
+	kindColumn            = "kind"
+	debitUserIDColumn     = "debit_user_id"
+	debitAccountColumn    = "debit_account"
+	creditUserIDColumn    = "credit_user_id"
+	creditAccountColumn   = "credit_account"
+	transactionIDColumn   = "transaction_id"
+	reversesEntryIDColumn = "reverses_entry_id"
+	descriptionColumn     = "description"
+	createdByColumn       = "created_by"
+
+	entryGrant    = "grant"
+	entryTransfer = "transfer"
+	entryPurchase = "purchase"
+	entryRefund   = "refund"
+
+	// issuanceAccount is where granted coins come from.
+	issuanceAccount = "issuance"
+	// shopAccount is where coins spent on merch go.
+	shopAccount = "shop"
+
+	// welcomeBonus is granted to every new user.
+	welcomeBonus = 1000
+
+	checkViolationCode = "23514"
+)
+
+func systemAccount(name string) *string {
+	return &name
+}
+
+// postEntry records the journal entry and updates the cached balances of the
+// users on both of its sides. It must run in the transaction that makes the
+// rest of the change, so that users.coins never disagrees with the journal.
+// A debit that would leave the user with a negative balance yields
+// ErrorInsFunds.
+func postEntry(ctx context.Context, tx pgx.Tx, e *JournalEntry) error {
+	insert := sq.Insert(journalEntriesTable).
+		Columns(
+			kindColumn, debitUserIDColumn, debitAccountColumn, creditUserIDColumn, creditAccountColumn,
+			amountColumn, transactionIDColumn, itemTypeColumn, quantityColumn, reversesEntryIDColumn,
+			descriptionColumn, createdByColumn, createdAtColumn,
+		).
+		Values(
+			e.Kind, e.DebitUserID, e.DebitAccount, e.CreditUserID, e.CreditAccount,
+			e.Amount, e.TransactionID, e.ItemType, e.Quantity, e.ReversesEntryID,
+			e.Description, e.CreatedBy, time.Now(),
+		).
+		Suffix("RETURNING " + idColumn + ", " + createdAtColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return repo.ErrorBuildLedgerQuery
+	}
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(&e.ID, &e.CreatedAt); err != nil {
+		return txError(err, repo.ErrorInsertJournalEntry)
+	}
+
+	if e.DebitUserID != nil {
+		if err := changeBalance(ctx, tx, *e.DebitUserID, -e.Amount); err != nil {
+			return err
+		}
+	}
+	if e.CreditUserID != nil {
+		if err := changeBalance(ctx, tx, *e.CreditUserID, e.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func changeBalance(ctx context.Context, db execer, userID string, delta int) error {
+	update := sq.Update(usersTable).
+		Set(balanceColumn, sq.Expr(balanceColumn+" + ?", delta)).
+		Where(sq.Eq{idColumn: userID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := update.ToSql()
+	if err != nil {
+		return repo.ErrorBuildBalanceUpdateQuery
+	}
+
+	if _, err := db.Exec(ctx, query, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == checkViolationCode {
+			return repo.ErrorInsFunds
+		}
+		return txError(err, repo.ErrorUpdateUserBalance)
+	}
+	return nil
+}
+
+// selectUserForUpdate returns the user matching where with its row locked.
+func selectUserForUpdate(ctx context.Context, tx pgx.Tx, where sq.Sqlizer) (*User, error) {
+	builder := sq.Select(idColumn, usernameColumn, balanceColumn, statusColumn).
+		From(usersTable).
+		Where(where).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildingSelectQuery
+	}
+
+	var user User
+	if err := tx.QueryRow(ctx, query, args...).Scan(&user.ID, &user.Username, &user.Coins, &user.Status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorUserNotFound
+		}
+		return nil, txError(err, repo.ErrorSelectUser)
+	}
+	return &user, nil
+}
+
+// GrantCoins credits the user with coins from the issuance account on behalf
+// of the admin.
+func (r *repository) GrantCoins(ctx context.Context, username, adminID string, amount int, reason string) (*JournalEntry, error) {
+	var entry *JournalEntry
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		user, err := selectUserForUpdate(ctx, tx, usernameIs(usernameColumn, username))
+		if err != nil {
+			return err
+		}
+		if user.Status == statusClosed {
+			return repo.ErrorAccountClosed
+		}
+
+		entry = &JournalEntry{
+			Kind:         entryGrant,
+			DebitAccount: systemAccount(issuanceAccount),
+			CreditUserID: &user.ID,
+			Amount:       amount,
+			Description:  reason,
+			CreatedBy:    &adminID,
+		}
+		return postEntry(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RefundPurchase reverses the purchase recorded by the journal entry on
+// behalf of the admin: the items go back out of the inventory and the coins
+// paid for them back to the buyer. A purchase can be refunded only once.
+func (r *repository) RefundPurchase(ctx context.Context, entryID int64, adminID, reason string) (*JournalEntry, error) {
+	var entry *JournalEntry
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		// Locking the purchase makes concurrent refunds of it wait for each
+		// other, so the check below cannot pass twice.
+		selectPurchase := sq.Select(idColumn, debitUserIDColumn, amountColumn, itemTypeColumn, quantityColumn).
+			From(journalEntriesTable).
+			Where(sq.Eq{idColumn: entryID, kindColumn: entryPurchase}).
+			Suffix("FOR UPDATE").
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := selectPurchase.ToSql()
+		if err != nil {
+			return repo.ErrorBuildLedgerQuery
+		}
+
+		var purchase JournalEntry
+		err = tx.QueryRow(ctx, query, args...).
+			Scan(&purchase.ID, &purchase.DebitUserID, &purchase.Amount, &purchase.ItemType, &purchase.Quantity)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return repo.ErrorPurchaseNotFound
+			}
+			return txError(err, repo.ErrorSelectJournal)
+		}
+
+		selectRefund := sq.Select("1").
+			Prefix("SELECT EXISTS (").
+			From(journalEntriesTable).
+			Where(sq.Eq{reversesEntryIDColumn: purchase.ID}).
+			Suffix(")").
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = selectRefund.ToSql()
+		if err != nil {
+			return repo.ErrorBuildLedgerQuery
+		}
+
+		var refunded bool
+		if err := tx.QueryRow(ctx, query, args...).Scan(&refunded); err != nil {
+			return txError(err, repo.ErrorSelectJournal)
+		}
+		if refunded {
+			return repo.ErrorAlreadyRefunded
+		}
+
+		user, err := selectUserForUpdate(ctx, tx, sq.Eq{idColumn: *purchase.DebitUserID})
+		if err != nil {
+			return err
+		}
+		if user.Status == statusClosed {
+			return repo.ErrorAccountClosed
+		}
+
+		if err := takeFromInventory(ctx, tx, user.ID, *purchase.ItemType, *purchase.Quantity); err != nil {
+			return err
+		}
+
+		entry = &JournalEntry{
+			Kind:            entryRefund,
+			DebitAccount:    systemAccount(shopAccount),
+			CreditUserID:    &user.ID,
+			Amount:          purchase.Amount,
+			ItemType:        purchase.ItemType,
+			Quantity:        purchase.Quantity,
+			ReversesEntryID: &purchase.ID,
+			Description:     reason,
+			CreatedBy:       &adminID,
+		}
+		return postEntry(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// takeFromInventory removes quantity items of the type from the inventory of
+// the user, or yields ErrorItemNotInInventory if the user has fewer.
+func takeFromInventory(ctx context.Context, tx pgx.Tx, userID, itemType string, quantity int) error {
+	update := sq.Update(inventoryTable).
+		Set(quantityColumn, sq.Expr(quantityColumn+" - ?", quantity)).
+		Where(sq.Eq{userIDColumn: userID, itemTypeColumn: itemType}).
+		Where(sq.GtOrEq{quantityColumn: quantity}).
+		Suffix("RETURNING " + quantityColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := update.ToSql()
+	if err != nil {
+		return repo.ErrorBuildInventoryUpdateQuery
+	}
+
+	var left int
+	if err := tx.QueryRow(ctx, query, args...).Scan(&left); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repo.ErrorItemNotInInventory
+		}
+		return txError(err, repo.ErrorUpdateInventory)
+	}
+	if left > 0 {
+		return nil
+	}
+
+	query, args, err = sq.Delete(inventoryTable).
+		Where(sq.Eq{userIDColumn: userID, itemTypeColumn: itemType}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return repo.ErrorBuildInventoryUpdateQuery
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return txError(err, repo.ErrorUpdateInventory)
+	}
+	return nil
+}
+
+// GetLedger returns the latest journal entries on the account of the user,
+// newest first.
+func (r *repository) GetLedger(ctx context.Context, userID string, limit int) ([]LedgerLine, error) {
+	builder := sq.Select("e."+idColumn, "e."+kindColumn).
+		Column(sq.Expr("CASE WHEN e."+creditUserIDColumn+" = ? THEN e."+amountColumn+" ELSE -e."+amountColumn+" END", userID)).
+		Column(sq.Expr("CASE WHEN e."+creditUserIDColumn+" = ? "+
+			"THEN COALESCE(d."+usernameColumn+", e."+debitAccountColumn+") "+
+			"ELSE COALESCE(c."+usernameColumn+", e."+creditAccountColumn+") END", userID)).
+		Columns(
+			"e."+itemTypeColumn, "e."+quantityColumn, "e."+reversesEntryIDColumn,
+			"e."+descriptionColumn, "a."+usernameColumn, "e."+createdAtColumn,
+		).
+		From(journalEntriesTable+" e").
+		LeftJoin(usersTable+" d ON d.id = e."+debitUserIDColumn).
+		LeftJoin(usersTable+" c ON c.id = e."+creditUserIDColumn).
+		LeftJoin(usersTable+" a ON a.id = e."+createdByColumn).
+		Where(sq.Or{sq.Eq{"e." + debitUserIDColumn: userID}, sq.Eq{"e." + creditUserIDColumn: userID}}).
+		OrderBy("e."+createdAtColumn+" DESC", "e."+idColumn+" DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildLedgerQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectJournal
+	}
+	defer rows.Close()
+
+	var lines []LedgerLine
+	for rows.Next() {
+		var l LedgerLine
+		err := rows.Scan(&l.EntryID, &l.Kind, &l.Change, &l.Counterparty, &l.ItemType, &l.Quantity,
+			&l.ReversesEntryID, &l.Description, &l.CreatedBy, &l.CreatedAt)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectJournal
+	}
+	return lines, nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	authFailuresTable  = "auth_failures"
+	lockoutEventsTable = "auth_lockout_events"
+
+	scopeColumn         = "scope"
+	subjectColumn       = "subject"
+	failuresColumn      = "failures"
+	lastFailureAtColumn = "last_failure_at"
+	lockedUntilColumn   = "locked_until"
+)
+
+func authFailureKeysFilter(keys []AuthFailureKey) sq.Or {
+	filter := make(sq.Or, 0, len(keys))
+	for _, key := range keys {
+		filter = append(filter, sq.Eq{scopeColumn: key.Scope, subjectColumn: key.Subject})
+	}
+	return filter
+}
+
+// GetLockedUntil returns the latest time until which any of the keys is
+// locked, or nil when none of them is locked.
+func (r *repository) GetLockedUntil(ctx context.Context, keys []AuthFailureKey) (*time.Time, error) {
+	builder := sq.Select("MAX(" + lockedUntilColumn + ")").
+		From(authFailuresTable).
+		Where(authFailureKeysFilter(keys)).
+		Where(sq.Expr(lockedUntilColumn + " > NOW()")).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildAuthFailureQuery
+	}
+
+	var lockedUntil *time.Time
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&lockedUntil); err != nil {
+		return nil, repo.ErrorSelectAuthFailure
+	}
+	return lockedUntil, nil
+}
+
+// RecordAuthFailure increments the failure counter of the key and returns
+// its new value. Counters whose last failure is older than window start over.
+func (r *repository) RecordAuthFailure(ctx context.Context, key AuthFailureKey, window time.Duration) (int, error) {
+	builder := sq.Insert(authFailuresTable).
+		Columns(scopeColumn, subjectColumn, failuresColumn, lastFailureAtColumn).
+		Values(key.Scope, key.Subject, 1, sq.Expr("NOW()")).
+		Suffix(`ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE
+				WHEN auth_failures.last_failure_at < NOW() - make_interval(secs => ?) THEN 1
+				ELSE auth_failures.failures + 1
+			END,
+			last_failure_at = NOW()
+			RETURNING failures`, window.Seconds()).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, repo.ErrorBuildAuthFailureQuery
+	}
+
+	var failures int
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&failures); err != nil {
+		return 0, repo.ErrorUpdateAuthFailure
+	}
+	return failures, nil
+}
+
+// LockAuth locks the key until the given time and records a lockout event.
+func (r *repository) LockAuth(ctx context.Context, key AuthFailureKey, failures int, until time.Time) error {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	lock := sq.Update(authFailuresTable).
+		Set(lockedUntilColumn, until).
+		Where(sq.Eq{scopeColumn: key.Scope, subjectColumn: key.Subject}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := lock.ToSql()
+	if err != nil {
+		return repo.ErrorBuildAuthFailureQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdateAuthFailure
+	}
+
+	insertEvent := sq.Insert(lockoutEventsTable).
+		Columns(scopeColumn, subjectColumn, failuresColumn, lockedUntilColumn, createdAtColumn).
+		Values(key.Scope, key.Subject, failures, until, time.Now()).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = insertEvent.ToSql()
+	if err != nil {
+		return repo.ErrorBuildAuthFailureQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorInsertLockoutEvent
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return repo.ErrorTxCommit
+	}
+	return nil
+}
+
+func (r *repository) ResetAuthFailures(ctx context.Context, key AuthFailureKey) error {
+	builder := sq.Delete(authFailuresTable).
+		Where(sq.Eq{scopeColumn: key.Scope, subjectColumn: key.Subject}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildAuthFailureQuery
+	}
+
+	if _, err = r.db.pool.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdateAuthFailure
+	}
+	return nil
+}
+
+// GetLockoutEvents returns the most recent lockout events, newest first.
+func (r *repository) GetLockoutEvents(ctx context.Context, limit int) ([]LockoutEvent, error) {
+	builder := sq.Select(idColumn, scopeColumn, subjectColumn, failuresColumn, lockedUntilColumn, createdAtColumn).
+		From(lockoutEventsTable).
+		OrderBy(createdAtColumn + " DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildAuthFailureQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectLockoutEvents
+	}
+	defer rows.Close()
+
+	var events []LockoutEvent
+	for rows.Next() {
+		var e LockoutEvent
+		if err := rows.Scan(&e.ID, &e.Scope, &e.Subject, &e.Failures, &e.LockedUntil, &e.CreatedAt); err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
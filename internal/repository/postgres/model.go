@@ -9,9 +9,66 @@ type User struct {
 	Username  string    `db:"username"`
 	Password  string    `db:"password"`
 	Coins     int       `db:"coins"`
+	Role      string    `db:"role"`
+	Status    string    `db:"status"`
 	CreatedAt time.Time `db:"created_at"`
 }
 
+// UserStatusEvent records a change of a user's status by an admin.
+type UserStatusEvent struct {
+	ID            int       `db:"id"`
+	UserID        string    `db:"user_id"`
+	AdminID       *string   `db:"admin_id"`
+	AdminUsername *string   `db:"admin_username"`
+	Status        string    `db:"status"`
+	Reason        string    `db:"reason"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// Offboarding describes the closure of an account by OffboardUser. The
+// caller sets the account, the sink for its coins, the admin, the reason and
+// the password hash that replaces the user's; the rest is filled in.
+type Offboarding struct {
+	Username     string
+	SinkUsername string
+	AdminID      string
+	Reason       string
+	Password     string
+
+	UserID               string
+	AnonymizedUsername   string
+	SweptCoins           int
+	Inventory            []InventoryItem
+	SentTransactions     int
+	ReceivedTransactions int
+	SessionsRevoked      int64
+	APIKeysRevoked       int64
+	ClosedAt             time.Time
+}
+
+// UsernameChange records that a user gave up Username for
+// CurrentUsername.
+type UsernameChange struct {
+	UserID          string    `db:"user_id"`
+	Username        string    `db:"username"`
+	CurrentUsername string    `db:"current_username"`
+	ChangedAt       time.Time `db:"changed_at"`
+}
+
+// IdempotencyKey stores the response to the first request a user made with
+// a key. UserID is filled in by the operation the key is passed to.
+type IdempotencyKey struct {
+	UserID      string    `db:"user_id"`
+	Key         string    `db:"key"`
+	RequestHash string    `db:"request_hash"`
+	Status      int       `db:"status"`
+	Response    []byte    `db:"response"`
+	CreatedAt   time.Time `db:"created_at"`
+	// Replayed is set when the key had been used before and Status and
+	// Response hold the stored response.
+	Replayed bool `db:"-"`
+}
+
 type InventoryItem struct {
 	ID       int    `db:"id"`
 	UserID   string `db:"user_id"`
@@ -21,11 +78,15 @@ type InventoryItem struct {
 }
 
 type CoinTransaction struct {
-	ID         int       `db:"id"`
-	FromUserID string    `db:"from_user_id"`
-	ToUserID   string    `db:"to_user_id"`
-	Amount     int       `db:"amount"`
-	CreatedAt  time.Time `db:"created_at"`
+	ID           int       `db:"id"`
+	FromUserID   string    `db:"from_user_id"`
+	FromUsername string    `db:"from_username"`
+	ToUserID     string    `db:"to_user_id"`
+	ToUsername   string    `db:"to_username"`
+	Amount       int       `db:"amount"`
+	Message      *string   `db:"message"`
+	Category     *string   `db:"category"`
+	CreatedAt    time.Time `db:"created_at"`
 }
 
 type ShopItem struct {
@@ -40,3 +101,202 @@ type UserInfo struct {
 	SentTransactions     []CoinTransaction
 	ReceivedTransactions []CoinTransaction
 }
+
+type Session struct {
+	ID          string     `db:"id"`
+	UserID      string     `db:"user_id"`
+	Username    string     `db:"username"`
+	Role        string     `db:"role"`
+	UserAgent   string     `db:"user_agent"`
+	IP          string     `db:"ip"`
+	LastTokenID string     `db:"last_token_id"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LastSeenAt  time.Time  `db:"last_seen_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	RevokedAt   *time.Time `db:"revoked_at"`
+}
+
+type RefreshToken struct {
+	TokenHash string     `db:"token_hash"`
+	SessionID string     `db:"session_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// AuthFailureKey identifies a failed login counter: a username or a client
+// address.
+type AuthFailureKey struct {
+	Scope   string `db:"scope"`
+	Subject string `db:"subject"`
+}
+
+type LockoutEvent struct {
+	ID          int       `db:"id"`
+	Scope       string    `db:"scope"`
+	Subject     string    `db:"subject"`
+	Failures    int       `db:"failures"`
+	LockedUntil time.Time `db:"locked_until"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// TOTP is the second factor of a user. It is pending until EnabledAt is set
+// by a confirmed code.
+type TOTP struct {
+	UserID       string     `db:"user_id"`
+	Secret       string     `db:"secret"`
+	EnabledAt    *time.Time `db:"enabled_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// TwoFactorChallenge is the id of a challenge token, which completes at most
+// one login before ExpiresAt.
+type TwoFactorChallenge struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+type PasswordResetToken struct {
+	TokenHash string     `db:"token_hash"`
+	UserID    string     `db:"user_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+type APIKey struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	Username   string     `db:"username"`
+	Role       string     `db:"role"`
+	Status     string     `db:"status"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     []string   `db:"scopes"`
+	Quota      *int       `db:"quota"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+type Impersonation struct {
+	ID            string    `db:"id"`
+	AdminID       string    `db:"admin_id"`
+	AdminUsername string    `db:"admin_username"`
+	UserID        string    `db:"user_id"`
+	SessionID     string    `db:"session_id"`
+	Reason        string    `db:"reason"`
+	ReadOnly      bool      `db:"read_only"`
+	CreatedAt     time.Time `db:"created_at"`
+	ExpiresAt     time.Time `db:"expires_at"`
+}
+
+// JournalEntry moves Amount coins from the debit side of the ledger to the
+// credit side. Each side is either a user or a system account.
+type JournalEntry struct {
+	ID              int64     `db:"id"`
+	Kind            string    `db:"kind"`
+	DebitUserID     *string   `db:"debit_user_id"`
+	DebitAccount    *string   `db:"debit_account"`
+	CreditUserID    *string   `db:"credit_user_id"`
+	CreditAccount   *string   `db:"credit_account"`
+	Amount          int       `db:"amount"`
+	TransactionID   *int      `db:"transaction_id"`
+	ItemType        *string   `db:"item_type"`
+	Quantity        *int      `db:"quantity"`
+	ReversesEntryID *int64    `db:"reverses_entry_id"`
+	Description     string    `db:"description"`
+	CreatedBy       *string   `db:"created_by"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// LedgerLine is a journal entry as seen from the account of one user. Change
+// is positive for credits and negative for debits, and Counterparty is the
+// username or system account on the other side.
+type LedgerLine struct {
+	EntryID         int64
+	Kind            string
+	Change          int
+	Counterparty    string
+	ItemType        *string
+	Quantity        *int
+	ReversesEntryID *int64
+	Description     string
+	CreatedBy       *string
+	CreatedAt       time.Time
+}
+
+// BalanceCheck compares the cached balance of a user with its balance in the
+// journal and with the balance its activity adds up to.
+type BalanceCheck struct {
+	UserID          string
+	Username        string
+	Balance         int
+	JournalBalance  int
+	ExpectedBalance int
+}
+
+type ScheduledTransfer struct {
+	ID           string        `db:"id"`
+	UserID       string        `db:"user_id"`
+	FromUsername string        `db:"from_username"`
+	ToUserID     string        `db:"to_user_id"`
+	ToUsername   string        `db:"to_username"`
+	Amount       int           `db:"amount"`
+	Message      *string       `db:"message"`
+	Category     *string       `db:"category"`
+	RunAt        *time.Time    `db:"run_at"`
+	Rule         *string       `db:"rule"`
+	NextRunAt    *time.Time    `db:"next_run_at"`
+	CreatedAt    time.Time     `db:"created_at"`
+	CancelledAt  *time.Time    `db:"cancelled_at"`
+	LastRun      *ScheduledRun `db:"-"`
+}
+
+type ScheduledRun struct {
+	ID                  int64      `db:"id"`
+	ScheduledTransferID string     `db:"scheduled_transfer_id"`
+	ScheduledFor        time.Time  `db:"scheduled_for"`
+	StartedAt           time.Time  `db:"started_at"`
+	FinishedAt          *time.Time `db:"finished_at"`
+	Status              string     `db:"status"`
+	Error               *string    `db:"error"`
+	// Transfer is set for runs returned by RestartStaleScheduledRuns.
+	Transfer *ScheduledTransfer `db:"-"`
+}
+
+type CoinRequest struct {
+	ID                string     `db:"id"`
+	RequesterID       string     `db:"requester_id"`
+	RequesterUsername string     `db:"requester_username"`
+	PayerID           string     `db:"payer_id"`
+	PayerUsername     string     `db:"payer_username"`
+	Amount            int        `db:"amount"`
+	Reason            string     `db:"reason"`
+	Status            string     `db:"status"`
+	CreatedAt         time.Time  `db:"created_at"`
+	ExpiresAt         time.Time  `db:"expires_at"`
+	ResolvedAt        *time.Time `db:"resolved_at"`
+}
+
+// PendingTransfer is a delayed transfer whose coins are held until
+// SettlesAt. TransactionID is set once it has been settled.
+type PendingTransfer struct {
+	ID            string     `db:"id"`
+	FromUserID    string     `db:"from_user_id"`
+	FromUsername  string     `db:"from_username"`
+	ToUserID      string     `db:"to_user_id"`
+	ToUsername    string     `db:"to_username"`
+	Amount        int        `db:"amount"`
+	Message       *string    `db:"message"`
+	Category      *string    `db:"category"`
+	Status        string     `db:"status"`
+	TransactionID *int       `db:"transaction_id"`
+	CreatedAt     time.Time  `db:"created_at"`
+	SettlesAt     time.Time  `db:"settles_at"`
+	ResolvedAt    *time.Time `db:"resolved_at"`
+}
@@ -0,0 +1,242 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+// anonymizedUsernamePrefix is followed by the user id in the username of an
+// offboarded user. The result is longer than a username /api/register
+// accepts, and the id is random, so it cannot clash with a new account.
+const anonymizedUsernamePrefix = "deleted-"
+
+// OffboardUser closes the account in a single transaction: its delayed
+// transfers that are still held are cancelled, its balance, including the
+// coins they held, is moved to the sink account, its username is replaced
+// with an anonymous one and its password with off.Password, its sessions, API
+// keys and second factor are revoked, its scheduled transfers are cancelled,
+// its pending coin requests are declined, its former usernames are forgotten
+// and the closure is recorded as a status change. Rows in coin_transactions
+// and inventory are kept, so the ledger still adds up.
+func (r *repository) OffboardUser(ctx context.Context, off *Offboarding) error {
+	username, sinkUsername := off.Username, off.SinkUsername
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		// The results of an attempt that was rolled back are dropped.
+		off.Username, off.SinkUsername = username, sinkUsername
+		off.Inventory = nil
+		off.SweptCoins = 0
+
+		// Both rows are locked at once and in id order, so that concurrent
+		// offboardings into the same sink cannot deadlock.
+		selectAccounts := sq.Select(idColumn, usernameColumn, balanceColumn, statusColumn).
+			From(usersTable).
+			Where(sq.Or{
+				usernameIs(usernameColumn, off.Username),
+				usernameIs(usernameColumn, off.SinkUsername),
+			}).
+			OrderBy(idColumn).
+			Suffix("FOR UPDATE").
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := selectAccounts.ToSql()
+		if err != nil {
+			return repo.ErrorBuildOffboardingQuery
+		}
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorSelectUser)
+		}
+		var user, sink *User
+		for rows.Next() {
+			var u User
+			if err := rows.Scan(&u.ID, &u.Username, &u.Coins, &u.Status); err != nil {
+				rows.Close()
+				return repo.ErrorScanQuery
+			}
+			switch {
+			case strings.EqualFold(u.Username, off.Username):
+				user = &u
+			case strings.EqualFold(u.Username, off.SinkUsername):
+				sink = &u
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return txError(err, repo.ErrorSelectUser)
+		}
+
+		switch {
+		case user == nil:
+			return repo.ErrorUserNotFound
+		case user.Status == statusClosed:
+			return repo.ErrorAccountClosed
+		case sink == nil:
+			return repo.ErrorSinkNotFound
+		case sink.Status == statusClosed:
+			return repo.ErrorReceiverClosed
+		}
+
+		off.UserID = user.ID
+		off.Username = user.Username
+		off.SinkUsername = sink.Username
+		off.ClosedAt = time.Now()
+
+		countTransactions := sq.Select().
+			Column(sq.Expr("COUNT(*) FILTER (WHERE "+senderIDColumn+" = ?)", user.ID)).
+			Column(sq.Expr("COUNT(*) FILTER (WHERE "+receiverIDColumn+" = ?)", user.ID)).
+			From(transactionsTable).
+			Where(sq.Or{sq.Eq{senderIDColumn: user.ID}, sq.Eq{receiverIDColumn: user.ID}}).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = countTransactions.ToSql()
+		if err != nil {
+			return repo.ErrorBuildOffboardingQuery
+		}
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&off.SentTransactions, &off.ReceivedTransactions)
+		if err != nil {
+			return txError(err, repo.ErrorSelectTransactions)
+		}
+
+		selectInventory := sq.Select(itemTypeColumn, quantityColumn).
+			From(inventoryTable).
+			Where(sq.Eq{userIDColumn: user.ID}).
+			OrderBy(itemTypeColumn).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = selectInventory.ToSql()
+		if err != nil {
+			return repo.ErrorBuildInventorySelectQuery
+		}
+
+		rows, err = tx.Query(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorSelectInventory)
+		}
+		for rows.Next() {
+			item := InventoryItem{UserID: user.ID}
+			if err := rows.Scan(&item.ItemType, &item.Quantity); err != nil {
+				rows.Close()
+				return repo.ErrorScanQuery
+			}
+			off.Inventory = append(off.Inventory, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return txError(err, repo.ErrorSelectInventory)
+		}
+
+		held, err := cancelHeldTransfers(ctx, tx, user.ID, "cancelled at offboarding")
+		if err != nil {
+			return err
+		}
+		user.Coins += held
+
+		if user.Coins > 0 {
+			if err := sweepBalance(ctx, tx, user, sink, off.ClosedAt); err != nil {
+				return err
+			}
+			off.SweptCoins = user.Coins
+		}
+
+		anonymize := sq.Update(usersTable).
+			Set(usernameColumn, anonymizedUsernamePrefix+user.ID).
+			Set(passwordColumn, off.Password).
+			Set(statusColumn, statusClosed).
+			Where(sq.Eq{idColumn: user.ID}).
+			Suffix("RETURNING " + usernameColumn).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = anonymize.ToSql()
+		if err != nil {
+			return repo.ErrorBuildOffboardingQuery
+		}
+
+		if err := tx.QueryRow(ctx, query, args...).Scan(&off.AnonymizedUsername); err != nil {
+			return txError(err, repo.ErrorAnonymizeUser)
+		}
+
+		if err := insertStatusEvent(ctx, tx, user.ID, off.AdminID, statusClosed, off.Reason); err != nil {
+			return err
+		}
+
+		off.SessionsRevoked, err = revokeSessions(ctx, tx, sq.Eq{userIDColumn: user.ID})
+		if err != nil {
+			return err
+		}
+
+		revokeKeys := sq.Update(apiKeysTable).
+			Set(revokedAtColumn, off.ClosedAt).
+			Where(sq.Eq{userIDColumn: user.ID, revokedAtColumn: nil}).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = revokeKeys.ToSql()
+		if err != nil {
+			return repo.ErrorBuildOffboardingQuery
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorRevokeCredentials)
+		}
+		off.APIKeysRevoked = tag.RowsAffected()
+
+		if err := cancelScheduledTransfers(ctx, tx, user.ID, off.ClosedAt); err != nil {
+			return err
+		}
+		if err := declineCoinRequests(ctx, tx, user.ID, off.ClosedAt); err != nil {
+			return err
+		}
+
+		// Former usernames would tie the account to the person as well.
+		for _, table := range []string{totpTable, recoveryCodesTable, passwordResetTokensTable, usernameHistoryTable} {
+			query, args, err := sq.Delete(table).
+				Where(sq.Eq{userIDColumn: user.ID}).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+			if err != nil {
+				return repo.ErrorBuildOffboardingQuery
+			}
+			if _, err := tx.Exec(ctx, query, args...); err != nil {
+				return txError(err, repo.ErrorRevokeCredentials)
+			}
+		}
+		return nil
+	})
+}
+
+// sweepBalance moves the whole balance of the user to the sink account and
+// records it as a regular transfer.
+func sweepBalance(ctx context.Context, tx pgx.Tx, user, sink *User, at time.Time) error {
+	insertTransaction := sq.Insert(transactionsTable).
+		Columns(senderIDColumn, receiverIDColumn, amountColumn, createdAtColumn).
+		Values(user.ID, sink.ID, user.Coins, at).
+		Suffix("RETURNING " + idColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := insertTransaction.ToSql()
+	if err != nil {
+		return repo.ErrorBuildInsertTransactionQuery
+	}
+
+	var transactionID int
+	if err := tx.QueryRow(ctx, query, args...).Scan(&transactionID); err != nil {
+		return txError(err, repo.ErrorInsertTransactionRecord)
+	}
+
+	return postEntry(ctx, tx, &JournalEntry{
+		Kind:          entryTransfer,
+		DebitUserID:   &user.ID,
+		CreditUserID:  &sink.ID,
+		Amount:        user.Coins,
+		TransactionID: &transactionID,
+		Description:   "offboarding",
+	})
+}
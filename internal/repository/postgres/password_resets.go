@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const passwordResetTokensTable = "password_reset_tokens"
+
+// CreatePasswordReset stores a new reset token for the user. Unused tokens
+// issued to the user before are discarded, so only the latest one works.
+func (r *repository) CreatePasswordReset(ctx context.Context, token *PasswordResetToken) error {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	discard := sq.Delete(passwordResetTokensTable).
+		Where(sq.Eq{userIDColumn: token.UserID, usedAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := discard.ToSql()
+	if err != nil {
+		return repo.ErrorBuildPasswordResetQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdatePasswordReset
+	}
+
+	insert := sq.Insert(passwordResetTokensTable).
+		Columns(tokenHashColumn, userIDColumn, createdAtColumn, expiresAtColumn).
+		Values(token.TokenHash, token.UserID, time.Now(), token.ExpiresAt).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = insert.ToSql()
+	if err != nil {
+		return repo.ErrorBuildPasswordResetQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorInsertPasswordReset
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return repo.ErrorTxCommit
+	}
+	return nil
+}
+
+// ResetPassword uses up the reset token identified by tokenHash, sets the
+// password of its user and ends all of the user's sessions. It returns the
+// user the token was issued to.
+func (r *repository) ResetPassword(ctx context.Context, tokenHash, password string) (*User, error) {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return nil, repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	selectToken := sq.Select("u."+idColumn, "u."+usernameColumn).
+		From(passwordResetTokensTable + " t").
+		Join(usersTable + " u ON u.id = t.user_id").
+		Where(sq.Eq{"t." + tokenHashColumn: tokenHash, "t." + usedAtColumn: nil}).
+		Where(sq.Expr("t." + expiresAtColumn + " > NOW()")).
+		Suffix("FOR UPDATE OF t").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectToken.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildPasswordResetQuery
+	}
+
+	var user User
+	if err := tx.QueryRow(ctx, query, args...).Scan(&user.ID, &user.Username); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorPasswordResetNotFound
+		}
+		return nil, repo.ErrorSelectPasswordReset
+	}
+
+	markUsed := sq.Update(passwordResetTokensTable).
+		Set(usedAtColumn, time.Now()).
+		Where(sq.Eq{tokenHashColumn: tokenHash}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = markUsed.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildPasswordResetQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return nil, repo.ErrorUpdatePasswordReset
+	}
+
+	if err := updatePassword(ctx, tx, user.ID, password); err != nil {
+		return nil, err
+	}
+
+	if _, err := revokeSessions(ctx, tx, sq.Eq{userIDColumn: user.ID}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, repo.ErrorTxCommit
+	}
+	return &user, nil
+}
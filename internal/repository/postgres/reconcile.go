@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	entryAdjustment = "adjustment"
+
+	// expectedBalanceExpr recomputes the balance of the user u from its
+	// activity instead of the journal: the welcome bonus and grants, plus
+	// received transfers, minus sent transfers, minus the coins held in its
+	// delayed transfers, minus the amounts paid for purchases, plus the
+	// amounts refunded. Purchases are taken at the price paid, so that a
+	// later price change does not show up as a discrepancy, and adjustments
+	// are added so that a corrected balance stays correct. Items bought
+	// before the journal have no purchase entry; the part of the inventory
+	// that no unrefunded purchase entry accounts for is taken at the current
+	// price, as it was before the journal. Accounts opened before the journal
+	// have no entry for their welcome bonus either; grants without an admin
+	// are welcome bonuses.
+	expectedBalanceExpr = `
+		CASE WHEN EXISTS (
+			SELECT 1 FROM journal_entries
+			WHERE credit_user_id = u.id AND kind = 'grant' AND created_by IS NULL
+		) THEN 0 ELSE ? END
+		+ COALESCE((SELECT SUM(amount) FROM journal_entries
+			WHERE credit_user_id = u.id AND kind = 'grant'), 0)
+		+ COALESCE((SELECT SUM(CASE WHEN credit_user_id = u.id THEN amount ELSE -amount END) FROM journal_entries
+			WHERE (credit_user_id = u.id OR debit_user_id = u.id) AND kind = 'adjustment'), 0)
+		+ COALESCE((SELECT SUM(amount) FROM coin_transactions WHERE to_user_id = u.id), 0)
+		- COALESCE((SELECT SUM(amount) FROM coin_transactions WHERE from_user_id = u.id), 0)
+		- COALESCE((SELECT SUM(amount) FROM pending_transfers
+			WHERE from_user_id = u.id AND status = 'pending'), 0)
+		- COALESCE((SELECT SUM(amount) FROM journal_entries
+			WHERE debit_user_id = u.id AND kind = 'purchase'), 0)
+		+ COALESCE((SELECT SUM(amount) FROM journal_entries
+			WHERE credit_user_id = u.id AND kind = 'refund'), 0)
+		- COALESCE((SELECT SUM(GREATEST(i.quantity - COALESCE((
+				SELECT SUM(p.quantity) FROM journal_entries p
+				WHERE p.debit_user_id = u.id AND p.kind = 'purchase' AND p.item_type = i.item_type
+					AND NOT EXISTS (SELECT 1 FROM journal_entries r
+						WHERE r.reverses_entry_id = p.id AND r.kind = 'refund')
+			), 0), 0) * s.price) FROM inventory i
+			JOIN shop_items s ON s.type = i.item_type WHERE i.user_id = u.id), 0)`
+
+	// journalBalanceExpr is the balance of the user u in the journal.
+	journalBalanceExpr = `
+		COALESCE((SELECT SUM(amount) FROM journal_entries WHERE credit_user_id = u.id), 0)
+		- COALESCE((SELECT SUM(amount) FROM journal_entries WHERE debit_user_id = u.id), 0)`
+)
+
+func selectBalanceChecks(where sq.Sqlizer) sq.SelectBuilder {
+	return sq.Select("u."+idColumn, "u."+usernameColumn, "u."+balanceColumn).
+		Column(journalBalanceExpr).
+		Column(sq.Expr(expectedBalanceExpr, welcomeBonus)).
+		From(usersTable + " u").
+		Where(where).
+		OrderBy("u." + usernameColumn).
+		PlaceholderFormat(sq.Dollar)
+}
+
+// ListBalanceChecks recomputes the balance of every user from the journal and
+// from its activity.
+func (r *repository) ListBalanceChecks(ctx context.Context) ([]BalanceCheck, error) {
+	query, args, err := selectBalanceChecks(sq.Expr("TRUE")).ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildReconcileQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectBalanceChecks
+	}
+	defer rows.Close()
+
+	var checks []BalanceCheck
+	for rows.Next() {
+		var c BalanceCheck
+		if err := rows.Scan(&c.UserID, &c.Username, &c.Balance, &c.JournalBalance, &c.ExpectedBalance); err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		checks = append(checks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectBalanceChecks
+	}
+	return checks, nil
+}
+
+// CorrectBalance makes the balance of the user and its journal match its
+// activity. The user is locked and checked again first; if the result differs
+// from found, the user has been active since and ErrorBalanceChanged is
+// returned. Otherwise users.coins, which only caches the journal balance, is
+// set to it again, and the difference between the expected and the journal
+// balance is written as an adjustment from or to the issuance account. The
+// adjustment is returned, or nil if the journal was right.
+func (r *repository) CorrectBalance(ctx context.Context, found *BalanceCheck, reason string) (*JournalEntry, error) {
+	var entry *JournalEntry
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		entry = nil
+		if _, err := selectUserForUpdate(ctx, tx, sq.Eq{idColumn: found.UserID}); err != nil {
+			return err
+		}
+
+		query, args, err := selectBalanceChecks(sq.Eq{"u." + idColumn: found.UserID}).ToSql()
+		if err != nil {
+			return repo.ErrorBuildReconcileQuery
+		}
+
+		var c BalanceCheck
+		err = tx.QueryRow(ctx, query, args...).
+			Scan(&c.UserID, &c.Username, &c.Balance, &c.JournalBalance, &c.ExpectedBalance)
+		if err != nil {
+			return txError(err, repo.ErrorSelectBalanceChecks)
+		}
+		if c != *found {
+			return repo.ErrorBalanceChanged
+		}
+
+		if c.Balance != c.JournalBalance {
+			if err := changeBalance(ctx, tx, c.UserID, c.JournalBalance-c.Balance); err != nil {
+				return err
+			}
+		}
+
+		diff := c.ExpectedBalance - c.JournalBalance
+		if diff == 0 {
+			return nil
+		}
+		entry = &JournalEntry{
+			Kind:        entryAdjustment,
+			Amount:      diff,
+			Description: reason,
+		}
+		if diff > 0 {
+			entry.DebitAccount = systemAccount(issuanceAccount)
+			entry.CreditUserID = &c.UserID
+		} else {
+			entry.Amount = -diff
+			entry.DebitUserID = &c.UserID
+			entry.CreditAccount = systemAccount(issuanceAccount)
+		}
+		return postEntry(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+// legacyPurchasesFixture is a user who bought a pink hoody and a cup before
+// the journal was introduced, so neither purchase has a journal entry.
+const legacyPurchasesFixture = `
+	INSERT INTO users (username, password, coins) VALUES ('legacy', '', 480);
+	INSERT INTO inventory (user_id, item_type, quantity)
+	SELECT id, item_type, 1 FROM users, (VALUES ('pink-hoody'), ('cup')) AS items (item_type)
+	WHERE username = 'legacy';`
+
+// TestBalanceChecksWithLegacyPurchases checks that purchases made before the
+// journal still count against the balance of the buyer, next to purchases and
+// refunds recorded in the journal.
+func TestBalanceChecksWithLegacyPurchases(t *testing.T) {
+	r := newMigratedRepository(t, "20250327120000_ledger", legacyPurchasesFixture)
+	ctx := context.Background()
+
+	checkBalance(t, r, "legacy", 480)
+
+	if err := r.BuyMerch(ctx, &InventoryItem{Username: "legacy", ItemType: "cup", Quantity: 2}, nil); err != nil {
+		t.Fatalf("buy cups: %v", err)
+	}
+	checkBalance(t, r, "legacy", 440)
+
+	admin := &User{Username: "admin"}
+	if err := r.CreateUser(ctx, admin); err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+
+	var purchaseID int64
+	err := r.db.pool.QueryRow(ctx, "SELECT id FROM journal_entries WHERE kind = 'purchase'").Scan(&purchaseID)
+	if err != nil {
+		t.Fatalf("select purchase: %v", err)
+	}
+	if _, err := r.RefundPurchase(ctx, purchaseID, admin.ID, "test"); err != nil {
+		t.Fatalf("refund cups: %v", err)
+	}
+	checkBalance(t, r, "legacy", 480)
+}
+
+// checkBalance fails the test unless every user adds up and the user has
+// the balance.
+func checkBalance(t *testing.T, r *repository, username string, balance int) {
+	t.Helper()
+
+	checks, err := r.ListBalanceChecks(context.Background())
+	if err != nil {
+		t.Fatalf("list balance checks: %v", err)
+	}
+
+	found := false
+	for _, c := range checks {
+		if c.Balance != c.JournalBalance || c.Balance != c.ExpectedBalance {
+			t.Errorf("%s: balance %d, journal %d, expected %d",
+				c.Username, c.Balance, c.JournalBalance, c.ExpectedBalance)
+		}
+		if c.Username == username {
+			found = true
+			if c.Balance != balance {
+				t.Errorf("balance of %s = %d, want %d", username, c.Balance, balance)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no balance check for %s", username)
+	}
+}
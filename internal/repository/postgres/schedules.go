@@ -0,0 +1,485 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	scheduledTransfersTable = "scheduled_transfers"
+	scheduledRunsTable      = "scheduled_transfer_runs"
+
+	toUserIDColumn            = "to_user_id"
+	runAtColumn               = "run_at"
+	ruleColumn                = "rule"
+	nextRunAtColumn           = "next_run_at"
+	cancelledAtColumn         = "cancelled_at"
+	scheduledTransferIDColumn = "scheduled_transfer_id"
+	scheduledForColumn        = "scheduled_for"
+	startedAtColumn           = "started_at"
+	finishedAtColumn          = "finished_at"
+	errorColumn               = "error"
+
+	runPending = "pending"
+)
+
+// selectScheduledTransfers selects scheduled transfers with the current
+// usernames of both sides and their latest run.
+func selectScheduledTransfers() sq.SelectBuilder {
+	return sq.Select(
+		"s."+idColumn, "s."+userIDColumn, "f."+usernameColumn, "s."+toUserIDColumn, "t."+usernameColumn,
+		"s."+amountColumn, "s."+messageColumn, "s."+categoryColumn, "s."+runAtColumn, "s."+ruleColumn,
+		"s."+nextRunAtColumn, "s."+createdAtColumn, "s."+cancelledAtColumn,
+		"lr."+idColumn, "lr."+scheduledForColumn, "lr."+startedAtColumn, "lr."+finishedAtColumn,
+		"lr."+statusColumn, "lr."+errorColumn,
+	).
+		From(scheduledTransfersTable + " s").
+		Join(usersTable + " f ON f.id = s." + userIDColumn).
+		Join(usersTable + " t ON t.id = s." + toUserIDColumn).
+		JoinClause("LEFT JOIN LATERAL (SELECT * FROM " + scheduledRunsTable + " r" +
+			" WHERE r." + scheduledTransferIDColumn + " = s." + idColumn +
+			" ORDER BY r." + scheduledForColumn + " DESC LIMIT 1) lr ON TRUE").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanScheduledTransfer(row pgx.Row) (*ScheduledTransfer, error) {
+	var s ScheduledTransfer
+	var runID *int64
+	var run ScheduledRun
+	var scheduledFor, startedAt *time.Time
+	var runStatus *string
+	err := row.Scan(&s.ID, &s.UserID, &s.FromUsername, &s.ToUserID, &s.ToUsername,
+		&s.Amount, &s.Message, &s.Category, &s.RunAt, &s.Rule,
+		&s.NextRunAt, &s.CreatedAt, &s.CancelledAt,
+		&runID, &scheduledFor, &startedAt, &run.FinishedAt, &runStatus, &run.Error)
+	if err != nil {
+		return nil, err
+	}
+	if runID != nil {
+		run.ID = *runID
+		run.ScheduledTransferID = s.ID
+		run.ScheduledFor = *scheduledFor
+		run.StartedAt = *startedAt
+		run.Status = *runStatus
+		s.LastRun = &run
+	}
+	return &s, nil
+}
+
+// CreateScheduledTransfer stores a new scheduled transfer of the user with
+// the id s.UserID to the user named s.ToUsername. The sender must be allowed
+// to spend coins, the receiver must not be closed and the sender may have at
+// most maxActive transfers that are still to run. The generated id and
+// creation time are written back to s.
+func (r *repository) CreateScheduledTransfer(ctx context.Context, s *ScheduledTransfer, maxActive int) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		// Locking the sender makes concurrent creations wait for each other,
+		// so the limit below holds.
+		sender, err := selectUserForUpdate(ctx, tx, sq.Eq{idColumn: s.UserID})
+		if err != nil {
+			return err
+		}
+		if err := checkCanSpend(sender.Status); err != nil {
+			return err
+		}
+
+		selectReceiver := sq.Select(idColumn, usernameColumn, statusColumn).
+			From(usersTable).
+			Where(usernameIs(usernameColumn, s.ToUsername)).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := selectReceiver.ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+
+		var receiverStatus string
+		err = tx.QueryRow(ctx, query, args...).Scan(&s.ToUserID, &s.ToUsername, &receiverStatus)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return repo.ErrorReceiverNotFound
+			}
+			return txError(err, repo.ErrorSelectUser)
+		}
+		if receiverStatus == statusClosed {
+			return repo.ErrorReceiverClosed
+		}
+
+		countActive := sq.Select("COUNT(*)").
+			From(scheduledTransfersTable).
+			Where(sq.Eq{userIDColumn: s.UserID}).
+			Where(sq.NotEq{nextRunAtColumn: nil}).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = countActive.ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+
+		var active int
+		if err := tx.QueryRow(ctx, query, args...).Scan(&active); err != nil {
+			return txError(err, repo.ErrorSelectSchedules)
+		}
+		if active >= maxActive {
+			return repo.ErrorTooManySchedules
+		}
+
+		insert := sq.Insert(scheduledTransfersTable).
+			Columns(
+				userIDColumn, toUserIDColumn, amountColumn, messageColumn, categoryColumn,
+				runAtColumn, ruleColumn, nextRunAtColumn, createdAtColumn,
+			).
+			Values(
+				s.UserID, s.ToUserID, s.Amount, s.Message, s.Category,
+				s.RunAt, s.Rule, s.NextRunAt, time.Now(),
+			).
+			Suffix("RETURNING " + idColumn + ", " + createdAtColumn).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+
+		if err := tx.QueryRow(ctx, query, args...).Scan(&s.ID, &s.CreatedAt); err != nil {
+			return txError(err, repo.ErrorInsertSchedule)
+		}
+		s.FromUsername = sender.Username
+		return nil
+	})
+}
+
+// ListScheduledTransfers returns the scheduled transfers of the user, newest
+// first, including those that have finished or were cancelled.
+func (r *repository) ListScheduledTransfers(ctx context.Context, userID string) ([]ScheduledTransfer, error) {
+	query, args, err := selectScheduledTransfers().
+		Where(sq.Eq{"s." + userIDColumn: userID}).
+		OrderBy("s."+createdAtColumn+" DESC", "s."+idColumn).
+		ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildScheduleQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectSchedules
+	}
+	defer rows.Close()
+
+	var schedules []ScheduledTransfer
+	for rows.Next() {
+		s, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		schedules = append(schedules, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectSchedules
+	}
+	return schedules, nil
+}
+
+// CancelScheduledTransfer stops a scheduled transfer of the user from
+// running again. A run that has already started is not affected.
+func (r *repository) CancelScheduledTransfer(ctx context.Context, userID, id string) error {
+	cancel := sq.Update(scheduledTransfersTable).
+		Set(cancelledAtColumn, time.Now()).
+		Set(nextRunAtColumn, nil).
+		Where(sq.Eq{idColumn: id, userIDColumn: userID}).
+		Where(sq.NotEq{nextRunAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := cancel.ToSql()
+	if err != nil {
+		return repo.ErrorBuildScheduleQuery
+	}
+
+	tag, err := r.db.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return repo.ErrorUpdateSchedule
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	exists, err := r.scheduledTransferExists(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repo.ErrorScheduleNotFound
+	}
+	return repo.ErrorScheduleNotPending
+}
+
+func (r *repository) scheduledTransferExists(ctx context.Context, userID, id string) (bool, error) {
+	query, args, err := sq.Select("1").
+		Prefix("SELECT EXISTS (").
+		From(scheduledTransfersTable).
+		Where(sq.Eq{idColumn: id, userIDColumn: userID}).
+		Suffix(")").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, repo.ErrorBuildScheduleQuery
+	}
+
+	var exists bool
+	if err := r.db.pool.QueryRow(ctx, query, args...).Scan(&exists); err != nil {
+		return false, repo.ErrorSelectSchedules
+	}
+	return exists, nil
+}
+
+// ListScheduledRuns returns the latest runs of a scheduled transfer of the
+// user, newest first.
+func (r *repository) ListScheduledRuns(ctx context.Context, userID, id string, limit int) ([]ScheduledRun, error) {
+	exists, err := r.scheduledTransferExists(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, repo.ErrorScheduleNotFound
+	}
+
+	builder := sq.Select(idColumn, scheduledTransferIDColumn, scheduledForColumn, startedAtColumn,
+		finishedAtColumn, statusColumn, errorColumn).
+		From(scheduledRunsTable).
+		Where(sq.Eq{scheduledTransferIDColumn: id}).
+		OrderBy(scheduledForColumn + " DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildScheduleQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectScheduledRuns
+	}
+	defer rows.Close()
+
+	var runs []ScheduledRun
+	for rows.Next() {
+		var run ScheduledRun
+		err := rows.Scan(&run.ID, &run.ScheduledTransferID, &run.ScheduledFor, &run.StartedAt,
+			&run.FinishedAt, &run.Status, &run.Error)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectScheduledRuns
+	}
+	return runs, nil
+}
+
+// DueScheduledTransfers returns up to limit scheduled transfers due at now,
+// the longest overdue first. They are not claimed; see StartScheduledRun.
+func (r *repository) DueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]ScheduledTransfer, error) {
+	query, args, err := selectScheduledTransfers().
+		Where(sq.LtOrEq{"s." + nextRunAtColumn: now}).
+		OrderBy("s." + nextRunAtColumn).
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildScheduleQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectSchedules
+	}
+	defer rows.Close()
+
+	var schedules []ScheduledTransfer
+	for rows.Next() {
+		s, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		schedules = append(schedules, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectSchedules
+	}
+	return schedules, nil
+}
+
+// StartScheduledRun claims the run of s due at s.NextRunAt: the transfer is
+// moved on to next, nil if it is not to run again, and the run is recorded
+// as pending. If another worker has claimed the run or the transfer has been
+// cancelled in the meantime, ErrorScheduleClaimed is returned.
+func (r *repository) StartScheduledRun(ctx context.Context, s *ScheduledTransfer, next *time.Time) (*ScheduledRun, error) {
+	run := &ScheduledRun{
+		ScheduledTransferID: s.ID,
+		ScheduledFor:        *s.NextRunAt,
+		StartedAt:           time.Now(),
+		Status:              runPending,
+	}
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		advance := sq.Update(scheduledTransfersTable).
+			Set(nextRunAtColumn, next).
+			Where(sq.Eq{idColumn: s.ID, nextRunAtColumn: run.ScheduledFor}).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := advance.ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorUpdateSchedule)
+		}
+		if tag.RowsAffected() == 0 {
+			return repo.ErrorScheduleClaimed
+		}
+
+		insert := sq.Insert(scheduledRunsTable).
+			Columns(scheduledTransferIDColumn, scheduledForColumn, startedAtColumn, statusColumn).
+			Values(run.ScheduledTransferID, run.ScheduledFor, run.StartedAt, run.Status).
+			Suffix("RETURNING " + idColumn).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+
+		if err := tx.QueryRow(ctx, query, args...).Scan(&run.ID); err != nil {
+			return txError(err, repo.ErrorInsertScheduledRun)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// RestartStaleScheduledRuns claims up to limit runs that have been pending
+// since before, e.g. because the server stopped in the middle of them, and
+// starts them again. Each run is returned with its scheduled transfer.
+func (r *repository) RestartStaleScheduledRuns(ctx context.Context, before time.Time, limit int) ([]ScheduledRun, error) {
+	var runs []ScheduledRun
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		runs = nil
+		selectStale := sq.Select(
+			"r."+idColumn, "r."+scheduledForColumn,
+			"s."+idColumn, "f."+usernameColumn, "t."+usernameColumn,
+			"s."+amountColumn, "s."+messageColumn, "s."+categoryColumn,
+		).
+			From(scheduledRunsTable + " r").
+			Join(scheduledTransfersTable + " s ON s.id = r." + scheduledTransferIDColumn).
+			Join(usersTable + " f ON f.id = s." + userIDColumn).
+			Join(usersTable + " t ON t.id = s." + toUserIDColumn).
+			Where(sq.Eq{"r." + statusColumn: runPending}).
+			Where(sq.Lt{"r." + startedAtColumn: before}).
+			OrderBy("r." + startedAtColumn).
+			Limit(uint64(limit)).
+			Suffix("FOR UPDATE OF r SKIP LOCKED").
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := selectStale.ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorSelectScheduledRuns)
+		}
+		ids := make([]int64, 0, limit)
+		for rows.Next() {
+			run := ScheduledRun{Status: runPending, Transfer: &ScheduledTransfer{}}
+			err := rows.Scan(&run.ID, &run.ScheduledFor,
+				&run.Transfer.ID, &run.Transfer.FromUsername, &run.Transfer.ToUsername,
+				&run.Transfer.Amount, &run.Transfer.Message, &run.Transfer.Category)
+			if err != nil {
+				rows.Close()
+				return repo.ErrorScanQuery
+			}
+			run.ScheduledTransferID = run.Transfer.ID
+			runs = append(runs, run)
+			ids = append(ids, run.ID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return txError(err, repo.ErrorSelectScheduledRuns)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		query, args, err = sq.Update(scheduledRunsTable).
+			Set(startedAtColumn, now).
+			Where(sq.Eq{idColumn: ids}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return repo.ErrorBuildScheduleQuery
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return txError(err, repo.ErrorUpdateScheduledRun)
+		}
+		for i := range runs {
+			runs[i].StartedAt = now
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// FinishScheduledRun records the outcome of a pending run.
+func (r *repository) FinishScheduledRun(ctx context.Context, runID int64, status string, runError *string) error {
+	finish := sq.Update(scheduledRunsTable).
+		Set(statusColumn, status).
+		Set(errorColumn, runError).
+		Set(finishedAtColumn, time.Now()).
+		Where(sq.Eq{idColumn: runID, statusColumn: runPending}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := finish.ToSql()
+	if err != nil {
+		return repo.ErrorBuildScheduleQuery
+	}
+
+	if _, err := r.db.pool.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdateScheduledRun
+	}
+	return nil
+}
+
+// cancelScheduledTransfers cancels the transfers of the user that are still
+// to run.
+func cancelScheduledTransfers(ctx context.Context, db execer, userID string, at time.Time) error {
+	query, args, err := sq.Update(scheduledTransfersTable).
+		Set(cancelledAtColumn, at).
+		Set(nextRunAtColumn, nil).
+		Where(sq.Eq{userIDColumn: userID}).
+		Where(sq.NotEq{nextRunAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return repo.ErrorBuildScheduleQuery
+	}
+	if _, err := db.Exec(ctx, query, args...); err != nil {
+		return txError(err, repo.ErrorUpdateSchedule)
+	}
+	return nil
+}
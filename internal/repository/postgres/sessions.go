@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	sessionsTable      = "sessions"
+	refreshTokensTable = "refresh_tokens"
+
+	sessionIDColumn   = "session_id"
+	tokenHashColumn   = "token_hash"
+	expiresAtColumn   = "expires_at"
+	revokedAtColumn   = "revoked_at"
+	usedAtColumn      = "used_at"
+	userAgentColumn   = "user_agent"
+	ipColumn          = "ip"
+	lastSeenAtColumn  = "last_seen_at"
+	lastTokenIDColumn = "last_token_id"
+
+	// sessionTouchInterval limits how often the last-seen time of a session
+	// is written while the client and token stay the same.
+	sessionTouchInterval = time.Minute
+)
+
+// CreateSession stores a new session together with its first refresh token.
+// The generated session id is written back to session.ID and token.SessionID.
+func (r *repository) CreateSession(ctx context.Context, session *Session, token *RefreshToken) error {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	now := time.Now()
+	insertSession := sq.Insert(sessionsTable).
+		Columns(userIDColumn, userAgentColumn, ipColumn, createdAtColumn, lastSeenAtColumn, expiresAtColumn).
+		Values(session.UserID, session.UserAgent, session.IP, now, now, session.ExpiresAt).
+		Suffix("RETURNING " + idColumn + ", " + createdAtColumn + ", " + lastSeenAtColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := insertSession.ToSql()
+	if err != nil {
+		return repo.ErrorBuildSessionQuery
+	}
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+	if err != nil {
+		return repo.ErrorInsertSession
+	}
+
+	token.SessionID = session.ID
+	if err := insertRefreshToken(ctx, tx, token); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return repo.ErrorTxCommit
+	}
+	return nil
+}
+
+// RotateRefreshToken exchanges the refresh token identified by tokenHash for
+// next and extends the session to next.ExpiresAt. Presenting a token that has
+// already been exchanged revokes the whole session, because it means the
+// token was copied.
+func (r *repository) RotateRefreshToken(ctx context.Context, tokenHash string, next *RefreshToken) (*Session, error) {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return nil, repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	selectToken := sq.Select(
+		"rt."+sessionIDColumn, "rt."+expiresAtColumn, "rt."+usedAtColumn,
+		"s."+userIDColumn, "u."+usernameColumn, "u."+roleColumn, "s."+createdAtColumn, "s."+expiresAtColumn, "s."+revokedAtColumn,
+	).
+		From(refreshTokensTable + " rt").
+		Join(sessionsTable + " s ON s.id = rt.session_id").
+		Join(usersTable + " u ON u.id = s.user_id").
+		Where(sq.Eq{"rt." + tokenHashColumn: tokenHash}).
+		Suffix("FOR UPDATE OF rt, s").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectToken.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildRefreshTokenQuery
+	}
+
+	var token RefreshToken
+	var session Session
+	err = tx.QueryRow(ctx, query, args...).Scan(
+		&token.SessionID, &token.ExpiresAt, &token.UsedAt,
+		&session.UserID, &session.Username, &session.Role, &session.CreatedAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorRefreshTokenNotFound
+		}
+		return nil, repo.ErrorSelectRefreshToken
+	}
+	session.ID = token.SessionID
+
+	now := time.Now()
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) || !token.ExpiresAt.After(now) {
+		return nil, repo.ErrorRefreshTokenNotFound
+	}
+
+	if token.UsedAt != nil {
+		if _, err := revokeSessions(ctx, tx, sq.Eq{idColumn: session.ID}); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, repo.ErrorTxCommit
+		}
+		return nil, repo.ErrorRefreshTokenReused
+	}
+
+	markUsed := sq.Update(refreshTokensTable).
+		Set(usedAtColumn, now).
+		Where(sq.Eq{tokenHashColumn: tokenHash}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = markUsed.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildRefreshTokenQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return nil, repo.ErrorUpdateRefreshToken
+	}
+
+	next.SessionID = session.ID
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return nil, err
+	}
+
+	extendSession := sq.Update(sessionsTable).
+		Set(expiresAtColumn, next.ExpiresAt).
+		Where(sq.Eq{idColumn: session.ID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = extendSession.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildSessionQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return nil, repo.ErrorUpdateSession
+	}
+	session.ExpiresAt = next.ExpiresAt
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, repo.ErrorTxCommit
+	}
+	return &session, nil
+}
+
+func (r *repository) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	builder := sq.Select("s."+idColumn, "s."+userIDColumn, "u."+usernameColumn, "u."+roleColumn, "s."+createdAtColumn, "s."+expiresAtColumn, "s."+revokedAtColumn).
+		From(sessionsTable + " s").
+		Join(usersTable + " u ON u.id = s.user_id").
+		Where(sq.Eq{"s." + idColumn: sessionID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildSessionQuery
+	}
+
+	var session Session
+	err = r.db.pool.QueryRow(ctx, query, args...).Scan(
+		&session.ID, &session.UserID, &session.Username, &session.Role, &session.CreatedAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorSessionNotFound
+		}
+		return nil, repo.ErrorSelectSession
+	}
+
+	return &session, nil
+}
+
+func (r *repository) RevokeSession(ctx context.Context, sessionID string) error {
+	_, err := revokeSessions(ctx, r.db.pool, sq.Eq{idColumn: sessionID})
+	return err
+}
+
+func (r *repository) RevokeUserSessions(ctx context.Context, userID string) error {
+	_, err := revokeSessions(ctx, r.db.pool, sq.Eq{userIDColumn: userID})
+	return err
+}
+
+// RevokeOtherSessions ends all sessions of the user except keepSessionID.
+func (r *repository) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error {
+	_, err := revokeSessions(ctx, r.db.pool, sq.And{
+		sq.Eq{userIDColumn: userID},
+		sq.NotEq{idColumn: keepSessionID},
+	})
+	return err
+}
+
+// RevokeUserSession ends a session only if it belongs to the user and is
+// still active.
+func (r *repository) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	revoked, err := revokeSessions(ctx, r.db.pool, sq.And{
+		sq.Eq{idColumn: sessionID, userIDColumn: userID},
+		sq.Expr(expiresAtColumn + " > NOW()"),
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return repo.ErrorSessionNotFound
+	}
+	return nil
+}
+
+// ListUserSessions returns the active sessions of the user, most recently
+// used first.
+func (r *repository) ListUserSessions(ctx context.Context, userID string) ([]Session, error) {
+	builder := sq.Select(idColumn, userIDColumn, userAgentColumn, ipColumn, lastTokenIDColumn, createdAtColumn, lastSeenAtColumn, expiresAtColumn).
+		From(sessionsTable).
+		Where(sq.Eq{userIDColumn: userID, revokedAtColumn: nil}).
+		Where(sq.Expr(expiresAtColumn + " > NOW()")).
+		OrderBy(lastSeenAtColumn + " DESC").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildSessionQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectSession
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.LastTokenID, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+		if err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// TouchSession records that the session has just been used with the given
+// access token from the given client. To keep authenticated requests cheap
+// the row is only written when the client or token changed or the last
+// write is older than sessionTouchInterval.
+func (r *repository) TouchSession(ctx context.Context, sessionID, tokenID, userAgent, ip string) error {
+	builder := sq.Update(sessionsTable).
+		Set(lastSeenAtColumn, time.Now()).
+		Set(lastTokenIDColumn, tokenID).
+		Set(userAgentColumn, userAgent).
+		Set(ipColumn, ip).
+		Where(sq.Eq{idColumn: sessionID}).
+		Where(sq.Or{
+			sq.Lt{lastSeenAtColumn: time.Now().Add(-sessionTouchInterval)},
+			sq.NotEq{lastTokenIDColumn: tokenID},
+			sq.NotEq{userAgentColumn: userAgent},
+			sq.NotEq{ipColumn: ip},
+		}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildSessionQuery
+	}
+
+	if _, err = r.db.pool.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdateSession
+	}
+	return nil
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// revokeSessions ends the active sessions matching where and returns how many
+// it ended.
+func revokeSessions(ctx context.Context, db execer, where sq.Sqlizer) (int64, error) {
+	builder := sq.Update(sessionsTable).
+		Set(revokedAtColumn, time.Now()).
+		Where(where).
+		Where(sq.Eq{revokedAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, repo.ErrorBuildSessionQuery
+	}
+
+	tag, err := db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, txError(err, repo.ErrorUpdateSession)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func insertRefreshToken(ctx context.Context, tx pgx.Tx, token *RefreshToken) error {
+	builder := sq.Insert(refreshTokensTable).
+		Columns(tokenHashColumn, sessionIDColumn, createdAtColumn, expiresAtColumn).
+		Values(token.TokenHash, token.SessionID, time.Now(), token.ExpiresAt).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildRefreshTokenQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorInsertRefreshToken
+	}
+	return nil
+}
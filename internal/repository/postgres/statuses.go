@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	userStatusEventsTable = "user_status_events"
+
+	statusColumn = "status"
+
+	statusActive    = "active"
+	statusFrozen    = "frozen"
+	statusSuspended = "suspended"
+	statusClosed    = "closed"
+)
+
+// checkCanSpend returns the error matching the status if it keeps the user
+// from spending coins. Only active users can spend.
+func checkCanSpend(status string) error {
+	switch status {
+	case statusActive:
+		return nil
+	case statusFrozen:
+		return repo.ErrorAccountFrozen
+	case statusSuspended:
+		return repo.ErrorAccountSuspended
+	default:
+		return repo.ErrorAccountClosed
+	}
+}
+
+// SetUserStatus changes the status of the user and records who changed it
+// and why. Suspending or closing the account ends all of its sessions in the
+// same transaction. It returns the updated user.
+func (r *repository) SetUserStatus(ctx context.Context, username, adminID, status, reason string) (*User, error) {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return nil, repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	update := sq.Update(usersTable).
+		Set(statusColumn, status).
+		Where(usernameIs(usernameColumn, username)).
+		Suffix("RETURNING " + idColumn + ", " + usernameColumn + ", " + roleColumn + ", " + statusColumn).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := update.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildStatusQuery
+	}
+
+	var user User
+	err = tx.QueryRow(ctx, query, args...).Scan(&user.ID, &user.Username, &user.Role, &user.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorUserNotFound
+		}
+		return nil, repo.ErrorUpdateStatus
+	}
+
+	if err = insertStatusEvent(ctx, tx, user.ID, adminID, status, reason); err != nil {
+		return nil, err
+	}
+
+	if status == statusSuspended || status == statusClosed {
+		if _, err = revokeSessions(ctx, tx, sq.Eq{userIDColumn: user.ID}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, repo.ErrorTxCommit
+	}
+	return &user, nil
+}
+
+func insertStatusEvent(ctx context.Context, db execer, userID, adminID, status, reason string) error {
+	builder := sq.Insert(userStatusEventsTable).
+		Columns(userIDColumn, adminIDColumn, statusColumn, reasonColumn, createdAtColumn).
+		Values(userID, adminID, status, reason, time.Now()).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildStatusQuery
+	}
+
+	if _, err = db.Exec(ctx, query, args...); err != nil {
+		return txError(err, repo.ErrorInsertStatusEvent)
+	}
+	return nil
+}
+
+// GetUserStatusEvents returns the latest status changes of the user, newest
+// first.
+func (r *repository) GetUserStatusEvents(ctx context.Context, userID string, limit int) ([]UserStatusEvent, error) {
+	builder := sq.Select(
+		"e."+idColumn, "e."+userIDColumn, "e."+adminIDColumn, "a."+usernameColumn,
+		"e."+statusColumn, "e."+reasonColumn, "e."+createdAtColumn,
+	).
+		From(userStatusEventsTable + " e").
+		LeftJoin(usersTable + " a ON a.id = e.admin_id").
+		Where(sq.Eq{"e." + userIDColumn: userID}).
+		OrderBy("e." + createdAtColumn + " DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildStatusQuery
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, repo.ErrorSelectStatusEvents
+	}
+	defer rows.Close()
+
+	var events []UserStatusEvent
+	for rows.Next() {
+		var e UserStatusEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AdminID, &e.AdminUsername, &e.Status, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, repo.ErrorScanQuery
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
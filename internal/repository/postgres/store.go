@@ -2,10 +2,11 @@ package postgres
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	repo "github.com/kingxl111/merch-store/internal/repository"
 
@@ -22,10 +23,13 @@ const (
 	usernameColumn = "username"
 	passwordColumn = "password"
 	balanceColumn  = "coins"
+	roleColumn     = "role"
 
 	senderIDColumn   = "from_user_id"
 	receiverIDColumn = "to_user_id"
 	amountColumn     = "amount"
+	messageColumn    = "message"
+	categoryColumn   = "category"
 	itemColumn       = "item"
 	createdAtColumn  = "created_at"
 
@@ -35,6 +39,8 @@ const (
 	userIDColumn   = "user_id"
 	itemTypeColumn = "item_type"
 	quantityColumn = "quantity"
+
+	uniqueViolationCode = "23505"
 )
 
 type repository struct {
@@ -45,169 +51,191 @@ func NewRepository(db *DB) *repository {
 	return &repository{db: db}
 }
 
-func (r *repository) AuthUser(ctx context.Context, user *User) error {
-	selectBuilder := sq.Select(usernameColumn).
-		PlaceholderFormat(sq.Dollar).
+// usernameIs matches the username in column regardless of case, as
+// usernames are unique regardless of case.
+func usernameIs(column, username string) sq.Sqlizer {
+	return sq.Expr("LOWER("+column+") = LOWER(?)", username)
+}
+
+// GetUser looks the user up by username. Usernames are unique regardless of
+// case, so the lookup is case-insensitive.
+func (r *repository) GetUser(ctx context.Context, username string) (*User, error) {
+	builder := sq.Select(idColumn, usernameColumn, passwordColumn, balanceColumn, roleColumn, statusColumn, createdAtColumn).
 		From(usersTable).
-		Where(sq.Eq{usernameColumn: user.Username})
+		Where(usernameIs(usernameColumn, username)).
+		PlaceholderFormat(sq.Dollar)
 
-	query, args, err := selectBuilder.ToSql()
+	query, args, err := builder.ToSql()
 	if err != nil {
-		return repo.ErrorBuildingSelectQuery
+		return nil, repo.ErrorBuildingSelectQuery
 	}
 
-	rows, err := r.db.pool.Query(ctx, query, args...)
+	var user User
+	err = r.db.pool.QueryRow(ctx, query, args...).
+		Scan(&user.ID, &user.Username, &user.Password, &user.Coins, &user.Role, &user.Status, &user.CreatedAt)
 	if err != nil {
-		return repo.ErrorSelectUser
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorUserNotFound
+		}
+		return nil, repo.ErrorSelectUser
 	}
-	defer rows.Close()
 
-	if !rows.Next() {
+	return &user, nil
+}
+
+// CreateUser creates the user and grants it the welcome bonus.
+func (r *repository) CreateUser(ctx context.Context, user *User) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
 		builder := sq.Insert(usersTable).
 			PlaceholderFormat(sq.Dollar).
 			Columns(usernameColumn, passwordColumn, balanceColumn, createdAtColumn).
-			Values(user.Username, user.Password, 1000, time.Now())
+			Values(user.Username, user.Password, 0, time.Now()).
+			Suffix("RETURNING " + idColumn + ", " + roleColumn + ", " + statusColumn)
 
 		query, args, err := builder.ToSql()
 		if err != nil {
 			return repo.ErrorBuildingInsertQuery
 		}
 
-		_, err = r.db.pool.Exec(ctx, query, args...)
+		err = tx.QueryRow(ctx, query, args...).Scan(&user.ID, &user.Role, &user.Status)
 		if err != nil {
-			fmt.Println(query)
-			fmt.Println(args...)
-			return repo.ErrorInsertUser
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+				return repo.ErrorUserAlreadyExists
+			}
+			return txError(err, repo.ErrorInsertUser)
 		}
-		return nil
-	}
-
-	selectBuilder = sq.Select(usernameColumn, passwordColumn).
-		PlaceholderFormat(sq.Dollar).
-		From(usersTable).
-		Where(sq.Eq{usernameColumn: user.Username}).
-		Where(sq.Eq{passwordColumn: user.Password})
 
-	query, args, err = selectBuilder.ToSql()
-	if err != nil {
-		return repo.ErrorBuildingSelectQuery
-	}
-
-	rows, err = r.db.pool.Query(ctx, query, args...)
-	if err != nil {
-		return repo.ErrorSelectUser
-	}
-	defer rows.Close()
-
-	if !rows.Next() {
-		return repo.ErrorUserPasswordCombine
-	}
-
-	return nil
+		err = postEntry(ctx, tx, &JournalEntry{
+			Kind:         entryGrant,
+			DebitAccount: systemAccount(issuanceAccount),
+			CreditUserID: &user.ID,
+			Amount:       welcomeBonus,
+			Description:  "welcome bonus",
+		})
+		if err != nil {
+			return err
+		}
+		user.Coins = welcomeBonus
+		return nil
+	})
 }
 
-func (r *repository) TransferCoins(ctx context.Context, fromUser, toUser string, amount int) error {
-	tx, err := r.db.pool.Begin(ctx)
-	if err != nil {
-		return repo.ErrorTxBegin
-	}
-	defer tx.Rollback(context.Background())
-
-	var fromUserID, toUserID uuid.UUID
-	var fromBalance int
-
-	selectSender := sq.Select(idColumn, balanceColumn).
-		From(usersTable).
-		Where(sq.Eq{usernameColumn: fromUser}).
-		Suffix("FOR UPDATE SKIP LOCKED").
-		PlaceholderFormat(sq.Dollar)
-	query, args, err := selectSender.ToSql()
-	if err != nil {
-		return repo.ErrorBuildSenderSelectQuery
-	}
-
-	err = tx.QueryRow(ctx, query, args...).Scan(&fromUserID, &fromBalance)
-	if err != nil {
-		return repo.ErrorSenderNotFound
-	}
+func (r *repository) UpdatePassword(ctx context.Context, userID, password string) error {
+	return updatePassword(ctx, r.db.pool, userID, password)
+}
 
-	selectReceiver := sq.Select(idColumn).
-		From(usersTable).
-		Where(sq.Eq{usernameColumn: toUser}).
-		Suffix("FOR UPDATE SKIP LOCKED").
+func updatePassword(ctx context.Context, db execer, userID, password string) error {
+	builder := sq.Update(usersTable).
+		Set(passwordColumn, password).
+		Where(sq.Eq{idColumn: userID}).
 		PlaceholderFormat(sq.Dollar)
 
-	query, args, err = selectReceiver.ToSql()
+	query, args, err := builder.ToSql()
 	if err != nil {
-		return repo.ErrorBuildReceiverSelectQuery
+		return repo.ErrorBuildPasswordUpdateQuery
 	}
 
-	err = tx.QueryRow(ctx, query, args...).Scan(&toUserID)
+	tag, err := db.Exec(ctx, query, args...)
 	if err != nil {
-		return repo.ErrorReceiverNotFound
+		return repo.ErrorUpdatePassword
 	}
-
-	if fromBalance < amount {
-		return repo.ErrorInsFunds
+	if tag.RowsAffected() == 0 {
+		return repo.ErrorUserNotFound
 	}
+	return nil
+}
 
-	updateSender := sq.Update(usersTable).
-		Set(balanceColumn, sq.Expr(balanceColumn+" - ?", amount)).
-		Where(sq.Eq{idColumn: fromUserID}).
+// SetUserRole changes the role of the user and returns the updated user.
+func (r *repository) SetUserRole(ctx context.Context, username, role string) (*User, error) {
+	builder := sq.Update(usersTable).
+		Set(roleColumn, role).
+		Where(usernameIs(usernameColumn, username)).
+		Suffix("RETURNING " + idColumn + ", " + usernameColumn + ", " + roleColumn).
 		PlaceholderFormat(sq.Dollar)
 
-	query, args, err = updateSender.ToSql()
+	query, args, err := builder.ToSql()
 	if err != nil {
-		return repo.ErrorBuildSenderUpdateQuery
+		return nil, repo.ErrorBuildRoleUpdateQuery
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
+	var user User
+	err = r.db.pool.QueryRow(ctx, query, args...).Scan(&user.ID, &user.Username, &user.Role)
 	if err != nil {
-		return repo.ErrorUpdateSenderBalance
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorUserNotFound
+		}
+		return nil, repo.ErrorUpdateRole
 	}
+	return &user, nil
+}
 
-	updateReceiver := sq.Update(usersTable).
-		Set(balanceColumn, sq.Expr(balanceColumn+" + ?", amount)).
-		Where(sq.Eq{idColumn: toUserID}).
-		PlaceholderFormat(sq.Dollar)
+// transferResults maps the results of the transfer_coins function to the
+// errors of TransferCoins.
+var transferResults = map[string]error{
+	"ok":                 nil,
+	"sender_not_found":   repo.ErrorSenderNotFound,
+	statusFrozen:         repo.ErrorAccountFrozen,
+	statusSuspended:      repo.ErrorAccountSuspended,
+	statusClosed:         repo.ErrorAccountClosed,
+	"receiver_not_found": repo.ErrorReceiverNotFound,
+	"receiver_closed":    repo.ErrorReceiverClosed,
+	"insufficient_funds": repo.ErrorInsFunds,
+}
 
-	query, args, err = updateReceiver.ToSql()
-	if err != nil {
-		return repo.ErrorBuildReceiverUpdateQuery
+// TransferCoins moves coins between the users named in t, with its message
+// and category if any. The checks, both balance updates and the ledger
+// record are made by the transfer_coins function, so a transfer takes a
+// single statement. With an idempotency key, a transfer is made only the
+// first time the key is used; see claimIdempotencyKey.
+func (r *repository) TransferCoins(ctx context.Context, t *CoinTransaction, idem *IdempotencyKey) error {
+	if idem == nil {
+		return retry(ctx, func() error {
+			return transferCoins(ctx, r.db.pool, t)
+		})
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
-	if err != nil {
-		return repo.ErrorUpdateReceiverBalance
-	}
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		claimed, err := claimUserIdempotencyKey(ctx, tx, t.FromUsername, idem)
+		if err != nil {
+			if errors.Is(err, repo.ErrorUserNotFound) {
+				return repo.ErrorSenderNotFound
+			}
+			return err
+		}
+		if !claimed {
+			return nil
+		}
+		return transferCoins(ctx, tx, t)
+	})
+}
 
-	insertTransaction := sq.Insert(transactionsTable).
-		Columns(senderIDColumn, receiverIDColumn, amountColumn, createdAtColumn).
-		Values(fromUserID, toUserID, amount, time.Now()).
+func transferCoins(ctx context.Context, db queryRower, t *CoinTransaction) error {
+	transfer := sq.Select().
+		Column(sq.Expr("transfer_coins(?, ?, ?, ?, ?)", t.FromUsername, t.ToUsername, t.Amount, t.Message, t.Category)).
 		PlaceholderFormat(sq.Dollar)
 
-	query, args, err = insertTransaction.ToSql()
+	query, args, err := transfer.ToSql()
 	if err != nil {
-		return repo.ErrorBuildInsertTransactionQuery
+		return repo.ErrorBuildTransferQuery
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
-	if err != nil {
-		return repo.ErrorInsertTransactionRecord
+	var result string
+	if err := db.QueryRow(ctx, query, args...).Scan(&result); err != nil {
+		return txError(err, repo.ErrorTransferCoins)
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		return repo.ErrorTxCommit
+	err, ok := transferResults[result]
+	if !ok {
+		return repo.ErrorTransferCoins
 	}
-
-	return nil
+	return err
 }
 
 func (r *repository) GetBalance(ctx context.Context, username string) (*int, error) {
 	builder := sq.Select(balanceColumn).
 		From(usersTable).
-		Where(sq.Eq{usernameColumn: username}).
+		Where(usernameIs(usernameColumn, username)).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := builder.ToSql()
@@ -234,14 +262,22 @@ func (r *repository) GetBalance(ctx context.Context, username string) (*int, err
 	return &balance, nil
 }
 
-func (r *repository) GetTransactionHistory(ctx context.Context, userID string) ([]CoinTransaction, error) {
-	builder := sq.Select(idColumn, senderIDColumn, receiverIDColumn, amountColumn, createdAtColumn).
-		From(transactionsTable).
+// GetTransactionHistory returns the transfers sent and received by the
+// user, newest first, with the current usernames of both sides.
+func (r *repository) GetTransactionHistory(ctx context.Context, username string) ([]CoinTransaction, error) {
+	builder := sq.Select(
+		"t."+idColumn, "t."+senderIDColumn, "COALESCE(f."+usernameColumn+", '')",
+		"t."+receiverIDColumn, "COALESCE(r."+usernameColumn+", '')",
+		"t."+amountColumn, "t."+messageColumn, "t."+categoryColumn, "t."+createdAtColumn,
+	).
+		From(transactionsTable + " t").
+		LeftJoin(usersTable + " f ON f.id = t." + senderIDColumn).
+		LeftJoin(usersTable + " r ON r.id = t." + receiverIDColumn).
 		Where(sq.Or{
-			sq.Eq{senderIDColumn: userID},
-			sq.Eq{receiverIDColumn: userID},
+			usernameIs("f."+usernameColumn, username),
+			usernameIs("r."+usernameColumn, username),
 		}).
-		OrderBy(createdAtColumn + " DESC").
+		OrderBy("t." + createdAtColumn + " DESC").
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := builder.ToSql()
@@ -258,119 +294,113 @@ func (r *repository) GetTransactionHistory(ctx context.Context, userID string) (
 	var transactions []CoinTransaction
 	for rows.Next() {
 		var t CoinTransaction
-		if err := rows.Scan(&t.ID, &t.FromUserID, &t.ToUserID, &t.Amount, &t.CreatedAt); err != nil {
+		err := rows.Scan(&t.ID, &t.FromUserID, &t.FromUsername, &t.ToUserID, &t.ToUsername,
+			&t.Amount, &t.Message, &t.Category, &t.CreatedAt)
+		if err != nil {
 			return nil, repo.ErrorScanTransaction
 		}
 		transactions = append(transactions, t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, repo.ErrorSelectTransactions
+	}
 	return transactions, nil
 }
 
-func (r *repository) BuyMerch(ctx context.Context, item *InventoryItem) error {
-	tx, err := r.db.pool.Begin(ctx)
-	if err != nil {
-		return repo.ErrorTxBegin
-	}
-	defer tx.Rollback(context.Background())
-
-	var userID uuid.UUID
-	var balance int
-
-	selectBalance := sq.Select(idColumn, balanceColumn).
-		From(usersTable).
-		Where(sq.Eq{usernameColumn: item.Username}).
-		PlaceholderFormat(sq.Dollar)
-
-	query, args, err := selectBalance.ToSql()
-	if err != nil {
-		return repo.ErrorBuildSenderSelectQuery
-	}
-
-	err = r.db.pool.QueryRow(ctx, query, args...).Scan(&userID, &balance)
-	if err != nil {
-		return repo.ErrorUserNotFound
-	}
-
-	var price int
-	selectItem := sq.Select(priceColumn).
-		From(shopItemsTable).
-		Where(sq.Eq{typeColumn: item.ItemType}).
-		PlaceholderFormat(sq.Dollar)
-
-	query, args, err = selectItem.ToSql()
-	if err != nil {
-		return repo.ErrorBuildSenderSelectQuery
-	}
-
-	err = r.db.pool.QueryRow(ctx, query, args...).Scan(&price)
-	if err != nil {
-		return repo.ErrorItemNotFound
-	}
-
-	totalCost := price * item.Quantity
+// BuyMerch buys the item for the user and records the purchase in the
+// journal. With an idempotency key, the item is bought only the first time
+// the key is used; see claimIdempotencyKey.
+func (r *repository) BuyMerch(ctx context.Context, item *InventoryItem, idem *IdempotencyKey) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		if idem != nil {
+			claimed, err := claimUserIdempotencyKey(ctx, tx, item.Username, idem)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return nil
+			}
+		}
 
-	if balance < totalCost {
-		return repo.ErrorInsFunds
-	}
+		// The balance is read with the row locked, so that concurrent
+		// purchases cannot both spend it.
+		selectUser := sq.Select(idColumn, balanceColumn, statusColumn).
+			From(usersTable).
+			Where(usernameIs(usernameColumn, item.Username)).
+			Suffix("FOR UPDATE").
+			PlaceholderFormat(sq.Dollar)
 
-	selectUserForUpdate := sq.Select(idColumn).
-		From(usersTable).
-		Where(sq.Eq{usernameColumn: item.Username}).
-		Suffix("FOR UPDATE SKIP LOCKED").
-		PlaceholderFormat(sq.Dollar)
+		query, args, err := selectUser.ToSql()
+		if err != nil {
+			return repo.ErrorBuildSenderSelectQuery
+		}
 
-	query, args, err = selectUserForUpdate.ToSql()
-	if err != nil {
-		return repo.ErrorBuildSenderSelectQuery
-	}
+		var user User
+		err = tx.QueryRow(ctx, query, args...).Scan(&user.ID, &user.Coins, &user.Status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return repo.ErrorUserNotFound
+			}
+			return txError(err, repo.ErrorSelectUser)
+		}
+		if err := checkCanSpend(user.Status); err != nil {
+			return err
+		}
 
-	err = tx.QueryRow(ctx, query, args...).Scan(&userID)
-	if err != nil {
-		return repo.ErrorUserNotFound
-	}
+		selectItem := sq.Select(priceColumn).
+			From(shopItemsTable).
+			Where(sq.Eq{typeColumn: item.ItemType}).
+			PlaceholderFormat(sq.Dollar)
 
-	updateBalance := sq.Update(usersTable).
-		Set(balanceColumn, sq.Expr(balanceColumn+" - ?", totalCost)).
-		Where(sq.Eq{idColumn: userID}).
-		PlaceholderFormat(sq.Dollar)
+		query, args, err = selectItem.ToSql()
+		if err != nil {
+			return repo.ErrorBuildItemSelectQuery
+		}
 
-	query, args, err = updateBalance.ToSql()
-	if err != nil {
-		return repo.ErrorBuildBalanceUpdateQuery
-	}
+		var price int
+		if err = tx.QueryRow(ctx, query, args...).Scan(&price); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return repo.ErrorItemNotFound
+			}
+			return txError(err, repo.ErrorSelectItem)
+		}
 
-	_, err = tx.Exec(ctx, query, args...)
-	if err != nil {
-		return repo.ErrorUpdateUserBalance
-	}
+		totalCost := price * item.Quantity
+		if user.Coins < totalCost {
+			return repo.ErrorInsFunds
+		}
 
-	upsertInventory := sq.Insert(inventoryTable).
-		Columns(userIDColumn, itemTypeColumn, quantityColumn).
-		Values(userID, item.ItemType, item.Quantity).
-		Suffix("ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity").
-		PlaceholderFormat(sq.Dollar)
+		upsertInventory := sq.Insert(inventoryTable).
+			Columns(userIDColumn, itemTypeColumn, quantityColumn).
+			Values(user.ID, item.ItemType, item.Quantity).
+			Suffix("ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity").
+			PlaceholderFormat(sq.Dollar)
 
-	query, args, err = upsertInventory.ToSql()
-	if err != nil {
-		return repo.ErrorBuildInventoryUpdateQuery
-	}
+		query, args, err = upsertInventory.ToSql()
+		if err != nil {
+			return repo.ErrorBuildInventoryUpdateQuery
+		}
 
-	_, err = tx.Exec(ctx, query, args...)
-	if err != nil {
-		return repo.ErrorInsertInventoryRecord
-	}
+		if _, err = tx.Exec(ctx, query, args...); err != nil {
+			return txError(err, repo.ErrorInsertInventoryRecord)
+		}
 
-	if err = tx.Commit(ctx); err != nil {
-		return repo.ErrorTxCommit
-	}
-	return nil
+		return postEntry(ctx, tx, &JournalEntry{
+			Kind:          entryPurchase,
+			DebitUserID:   &user.ID,
+			CreditAccount: systemAccount(shopAccount),
+			Amount:        totalCost,
+			ItemType:      &item.ItemType,
+			Quantity:      &item.Quantity,
+		})
+	})
 }
 
 func (r *repository) GetInventory(ctx context.Context, username string) ([]InventoryItem, error) {
 	builder := sq.Select("i.id", "i.user_id", "i.item_type", "i.quantity").
 		From("inventory i").
 		Join("users u ON u.id = i.user_id").
-		Where(sq.Eq{"u.username": username}).
+		Where(usernameIs("u."+usernameColumn, username)).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := builder.ToSql()
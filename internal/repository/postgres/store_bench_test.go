@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+// transferRowByRow is how TransferCoins used to work before the
+// transfer_coins function: it locks both users and then writes the same rows
+// as the function, each with a statement of its own. It is kept as the
+// baseline for BenchmarkTransferCoins.
+func transferRowByRow(ctx context.Context, r *repository, fromUser, toUser string, amount int) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT id, username, coins FROM users WHERE username IN ($1, $2) ORDER BY id FOR UPDATE",
+			fromUser, toUser)
+		if err != nil {
+			return txError(err, repo.ErrorSelectUser)
+		}
+		var sender, receiver *User
+		for rows.Next() {
+			var u User
+			if err := rows.Scan(&u.ID, &u.Username, &u.Coins); err != nil {
+				rows.Close()
+				return repo.ErrorScanQuery
+			}
+			if u.Username == fromUser {
+				sender = &u
+			}
+			if u.Username == toUser {
+				receiver = &u
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return txError(err, repo.ErrorSelectUser)
+		}
+
+		switch {
+		case sender == nil:
+			return repo.ErrorSenderNotFound
+		case receiver == nil:
+			return repo.ErrorReceiverNotFound
+		case sender.Coins < amount:
+			return repo.ErrorInsFunds
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE users SET coins = coins - $1 WHERE id = $2", amount, sender.ID); err != nil {
+			return txError(err, repo.ErrorUpdateSenderBalance)
+		}
+		if _, err := tx.Exec(ctx, "UPDATE users SET coins = coins + $1 WHERE id = $2", amount, receiver.ID); err != nil {
+			return txError(err, repo.ErrorUpdateReceiverBalance)
+		}
+		var transactionID int
+		err = tx.QueryRow(ctx,
+			"INSERT INTO coin_transactions (from_user_id, to_user_id, amount, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+			sender.ID, receiver.ID, amount, time.Now()).Scan(&transactionID)
+		if err != nil {
+			return txError(err, repo.ErrorInsertTransactionRecord)
+		}
+		_, err = tx.Exec(ctx,
+			"INSERT INTO journal_entries (kind, debit_user_id, credit_user_id, amount, transaction_id) VALUES ($1, $2, $3, $4, $5)",
+			entryTransfer, sender.ID, receiver.ID, amount, transactionID)
+		if err != nil {
+			return txError(err, repo.ErrorInsertJournalEntry)
+		}
+		return nil
+	})
+}
+
+// BenchmarkTransferCoins compares TransferCoins with the row-by-row baseline
+// under parallel transfers between random pairs of a few users. Run it with
+// PG_DSN set, see TestConcurrentTransfersAndPurchases.
+func BenchmarkTransferCoins(b *testing.B) {
+	r := newStressRepository(b)
+	const accounts = 16
+	usernames := createStressUsers(b, r, accounts, 1_000_000_000)
+
+	transfers := map[string]func(ctx context.Context, fromUser, toUser string) error{
+		"function": func(ctx context.Context, fromUser, toUser string) error {
+			return r.TransferCoins(ctx, &CoinTransaction{FromUsername: fromUser, ToUsername: toUser, Amount: 1}, nil)
+		},
+		"row-by-row": func(ctx context.Context, fromUser, toUser string) error {
+			return transferRowByRow(ctx, r, fromUser, toUser, 1)
+		},
+	}
+
+	for _, name := range []string{"row-by-row", "function"} {
+		transfer := transfers[name]
+		b.Run(name, func(b *testing.B) {
+			var seed atomic.Uint64
+			b.RunParallel(func(pb *testing.PB) {
+				s := seed.Add(1)
+				rnd := rand.New(rand.NewPCG(s, s))
+				ctx := context.Background()
+				for pb.Next() {
+					from := rnd.IntN(accounts)
+					to := (from + 1 + rnd.IntN(accounts-1)) % accounts
+					if err := transfer(ctx, usernames[from], usernames[to]); err != nil {
+						b.Errorf("transfer: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
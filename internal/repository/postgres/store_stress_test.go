@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const stressStartBalance = 1000
+
+// newStressRepository migrates a fresh schema in the database at PG_DSN and
+// returns a repository working in it. The schema is dropped when the test
+// ends.
+func newStressRepository(t testing.TB) *repository {
+	t.Helper()
+	return newMigratedRepository(t, "", "")
+}
+
+// newMigratedRepository is newStressRepository, except that the fixture is
+// run right before the migration whose name starts with fixtureBefore, so
+// that it can insert rows the way an older schema had them.
+func newMigratedRepository(t testing.TB, fixtureBefore, fixture string) *repository {
+	t.Helper()
+
+	dsn := os.Getenv("PG_DSN")
+	if dsn == "" {
+		t.Skip("PG_DSN is not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("stress_%d", time.Now().UnixNano())
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(context.Background(), dsn)
+		if err != nil {
+			t.Errorf("connect: %v", err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse PG_DSN: %v", err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	cfg.MaxConns = 32
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("open pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrations, err := filepath.Glob(filepath.Join("..", "..", "..", "migrations", "*.up.sql"))
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	sort.Strings(migrations)
+	for _, name := range migrations {
+		if fixture != "" && strings.HasPrefix(filepath.Base(name), fixtureBefore) {
+			if _, err := pool.Exec(ctx, fixture); err != nil {
+				t.Fatalf("apply fixture: %v", err)
+			}
+		}
+		migration, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if _, err := pool.Exec(ctx, string(migration)); err != nil {
+			t.Fatalf("apply %s: %v", name, err)
+		}
+	}
+
+	return &repository{db: &DB{pool: pool}}
+}
+
+func createStressUsers(t testing.TB, r *repository, n, balance int) []string {
+	t.Helper()
+
+	ctx := context.Background()
+	usernames := make([]string, n)
+	for i := range usernames {
+		usernames[i] = fmt.Sprintf("stress-%d", i)
+		err := r.inTx(ctx, func(tx pgx.Tx) error {
+			var userID string
+			err := tx.QueryRow(ctx, "INSERT INTO users (username, password, coins) VALUES ($1, '', 0) RETURNING id",
+				usernames[i]).Scan(&userID)
+			if err != nil {
+				return err
+			}
+			return postEntry(ctx, tx, &JournalEntry{
+				Kind:         entryGrant,
+				DebitAccount: systemAccount(issuanceAccount),
+				CreditUserID: &userID,
+				Amount:       balance,
+			})
+		})
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	return usernames
+}
+
+// TestConcurrentTransfersAndPurchases runs random transfers in both
+// directions and purchases between a few users in parallel and checks that
+// no coins are lost or created: every balance must match both the transfers
+// and purchases recorded for the user and its journal entries.
+func TestConcurrentTransfersAndPurchases(t *testing.T) {
+	r := newStressRepository(t)
+	ctx := context.Background()
+
+	const (
+		accounts   = 8
+		workers    = 16
+		operations = 200
+	)
+	usernames := createStressUsers(t, r, accounts, stressStartBalance)
+	items := map[string]int64{"pen": 10, "cup": 20, "book": 50}
+	itemTypes := []string{"pen", "cup", "book"}
+
+	var spent, transfers, purchases atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewPCG(seed, seed))
+
+			for i := 0; i < operations; i++ {
+				from := rnd.IntN(accounts)
+				if rnd.IntN(4) == 0 {
+					itemType := itemTypes[rnd.IntN(len(itemTypes))]
+					err := r.BuyMerch(ctx, &InventoryItem{Username: usernames[from], ItemType: itemType, Quantity: 1}, nil)
+					switch {
+					case err == nil:
+						spent.Add(items[itemType])
+						purchases.Add(1)
+					case errors.Is(err, repo.ErrorInsFunds):
+					default:
+						t.Errorf("buy %s for %s: %v", itemType, usernames[from], err)
+						return
+					}
+					continue
+				}
+
+				to := (from + 1 + rnd.IntN(accounts-1)) % accounts
+				err := r.TransferCoins(ctx, &CoinTransaction{
+					FromUsername: usernames[from],
+					ToUsername:   usernames[to],
+					Amount:       1 + rnd.IntN(100),
+				}, nil)
+				switch {
+				case err == nil:
+					transfers.Add(1)
+				case errors.Is(err, repo.ErrorInsFunds):
+				default:
+					t.Errorf("transfer from %s to %s: %v", usernames[from], usernames[to], err)
+					return
+				}
+			}
+		}(uint64(w))
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	t.Logf("%d transfers and %d purchases succeeded", transfers.Load(), purchases.Load())
+
+	var total int64
+	err := r.db.pool.QueryRow(ctx, "SELECT SUM(coins) FROM users WHERE username = ANY($1)", usernames).Scan(&total)
+	if err != nil {
+		t.Fatalf("sum balances: %v", err)
+	}
+	if want := int64(accounts*stressStartBalance) - spent.Load(); total != want {
+		t.Errorf("total balance is %d, want %d", total, want)
+	}
+
+	rows, err := r.db.pool.Query(ctx, `
+		SELECT u.username, u.coins,
+			COALESCE((SELECT SUM(amount) FROM coin_transactions WHERE to_user_id = u.id), 0)
+			- COALESCE((SELECT SUM(amount) FROM coin_transactions WHERE from_user_id = u.id), 0)
+			- COALESCE((SELECT SUM(i.quantity * s.price) FROM inventory i
+				JOIN shop_items s ON s.type = i.item_type WHERE i.user_id = u.id), 0),
+			COALESCE((SELECT SUM(amount) FROM journal_entries WHERE credit_user_id = u.id), 0)
+			- COALESCE((SELECT SUM(amount) FROM journal_entries WHERE debit_user_id = u.id), 0)
+		FROM users u
+		WHERE u.username = ANY($1)`, usernames)
+	if err != nil {
+		t.Fatalf("select balances: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var username string
+		var balance, change, journal int64
+		if err := rows.Scan(&username, &balance, &change, &journal); err != nil {
+			t.Fatalf("scan balance: %v", err)
+		}
+		if want := stressStartBalance + change; balance != want {
+			t.Errorf("balance of %s is %d, but its history adds up to %d", username, balance, want)
+		}
+		if balance != journal {
+			t.Errorf("balance of %s is %d, but its journal entries add up to %d", username, balance, journal)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("select balances: %v", err)
+	}
+}
+
+// TestConcurrentIdempotentTransfers sends the same transfer with the same
+// idempotency key in parallel and checks that it is made exactly once.
+func TestConcurrentIdempotentTransfers(t *testing.T) {
+	r := newStressRepository(t)
+	ctx := context.Background()
+
+	const (
+		retries = 8
+		amount  = 10
+	)
+	usernames := createStressUsers(t, r, 2, stressStartBalance)
+
+	var replays atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < retries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := &IdempotencyKey{Key: "stress", RequestHash: "hash", Status: 200, Response: []byte("{}")}
+			transfer := &CoinTransaction{FromUsername: usernames[0], ToUsername: usernames[1], Amount: amount}
+			if err := r.TransferCoins(ctx, transfer, key); err != nil {
+				t.Errorf("transfer: %v", err)
+				return
+			}
+			if key.Replayed {
+				replays.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := replays.Load(); got != retries-1 {
+		t.Errorf("%d of %d requests were replayed, want %d", got, retries, retries-1)
+	}
+
+	var balance int
+	err := r.db.pool.QueryRow(ctx, "SELECT coins FROM users WHERE username = $1", usernames[0]).Scan(&balance)
+	if err != nil {
+		t.Fatalf("select balance: %v", err)
+	}
+	if want := stressStartBalance - amount; balance != want {
+		t.Errorf("sender balance is %d, want %d", balance, want)
+	}
+}
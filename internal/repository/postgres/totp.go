@@ -0,0 +1,286 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	totpTable          = "user_totp"
+	recoveryCodesTable = "recovery_codes"
+	challengesTable    = "two_factor_challenges"
+
+	secretColumn       = "secret"
+	enabledAtColumn    = "enabled_at"
+	lastUsedStepColumn = "last_used_step"
+	codeHashColumn     = "code_hash"
+)
+
+func (r *repository) GetTOTP(ctx context.Context, userID string) (*TOTP, error) {
+	builder := sq.Select(userIDColumn, secretColumn, enabledAtColumn, lastUsedStepColumn, createdAtColumn).
+		From(totpTable).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildTOTPQuery
+	}
+
+	var totp TOTP
+	err = r.db.pool.QueryRow(ctx, query, args...).Scan(
+		&totp.UserID, &totp.Secret, &totp.EnabledAt, &totp.LastUsedStep, &totp.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorTOTPNotFound
+		}
+		return nil, repo.ErrorSelectTOTP
+	}
+	return &totp, nil
+}
+
+// SaveTOTPSecret stores a pending secret for the user, replacing a previous
+// pending one. The secret of an enabled TOTP is never replaced.
+func (r *repository) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	builder := sq.Insert(totpTable).
+		Columns(userIDColumn, secretColumn, createdAtColumn).
+		Values(userID, secret, time.Now()).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = EXCLUDED.created_at
+			WHERE user_totp.enabled_at IS NULL`).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	tag, err := r.db.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return repo.ErrorUpdateTOTP
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrorTOTPNotFound
+	}
+	return nil
+}
+
+// EnableTOTP enables the pending TOTP of the user, marks step as used and
+// replaces the recovery codes with codeHashes.
+func (r *repository) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	enable := sq.Update(totpTable).
+		Set(enabledAtColumn, time.Now()).
+		Set(lastUsedStepColumn, step).
+		Where(sq.Eq{userIDColumn: userID, enabledAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := enable.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return repo.ErrorUpdateTOTP
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrorTOTPNotFound
+	}
+
+	if err := deleteRecoveryCodes(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	insertCodes := sq.Insert(recoveryCodesTable).
+		Columns(userIDColumn, codeHashColumn).
+		PlaceholderFormat(sq.Dollar)
+	for _, hash := range codeHashes {
+		insertCodes = insertCodes.Values(userID, hash)
+	}
+
+	query, args, err = insertCodes.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorInsertRecoveryCode
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return repo.ErrorTxCommit
+	}
+	return nil
+}
+
+// UseTOTPStep records that the code of the given time step has been used.
+// Steps can only move forward, so every code is accepted at most once. A
+// non-empty challengeID is claimed in the same transaction, so that the step
+// is used only if the challenge is, and the other way round.
+func (r *repository) UseTOTPStep(ctx context.Context, userID string, step int64, challengeID string) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		if err := useTwoFactorChallenge(ctx, tx, userID, challengeID); err != nil {
+			return err
+		}
+
+		builder := sq.Update(totpTable).
+			Set(lastUsedStepColumn, step).
+			Where(sq.Eq{userIDColumn: userID}).
+			Where(sq.Lt{lastUsedStepColumn: step}).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := builder.ToSql()
+		if err != nil {
+			return repo.ErrorBuildTOTPQuery
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorUpdateTOTP)
+		}
+		if tag.RowsAffected() == 0 {
+			return repo.ErrorTOTPStepUsed
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used. A
+// non-empty challengeID is claimed in the same transaction, like in
+// UseTOTPStep.
+func (r *repository) UseRecoveryCode(ctx context.Context, userID, codeHash, challengeID string) error {
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		if err := useTwoFactorChallenge(ctx, tx, userID, challengeID); err != nil {
+			return err
+		}
+
+		builder := sq.Update(recoveryCodesTable).
+			Set(usedAtColumn, time.Now()).
+			Where(sq.Eq{userIDColumn: userID, codeHashColumn: codeHash, usedAtColumn: nil}).
+			PlaceholderFormat(sq.Dollar)
+
+		query, args, err := builder.ToSql()
+		if err != nil {
+			return repo.ErrorBuildTOTPQuery
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return txError(err, repo.ErrorUpdateRecoveryCode)
+		}
+		if tag.RowsAffected() == 0 {
+			return repo.ErrorRecoveryCodeNotFound
+		}
+		return nil
+	})
+}
+
+// DisableTOTP removes the TOTP secret and the recovery codes of the user.
+func (r *repository) DisableTOTP(ctx context.Context, userID string) error {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := deleteRecoveryCodes(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	builder := sq.Delete(totpTable).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdateTOTP
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return repo.ErrorTxCommit
+	}
+	return nil
+}
+
+func deleteRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string) error {
+	builder := sq.Delete(recoveryCodesTable).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorUpdateRecoveryCode
+	}
+	return nil
+}
+
+// CreateTwoFactorChallenge records a challenge token handed out to the user.
+func (r *repository) CreateTwoFactorChallenge(ctx context.Context, c *TwoFactorChallenge) error {
+	builder := sq.Insert(challengesTable).
+		Columns(idColumn, userIDColumn, expiresAtColumn).
+		Values(c.ID, c.UserID, c.ExpiresAt).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	if _, err := r.db.pool.Exec(ctx, query, args...); err != nil {
+		return repo.ErrorInsertChallenge
+	}
+	return nil
+}
+
+// useTwoFactorChallenge marks the challenge of the user as used, unless id
+// is empty. A challenge that has been used before or has expired yields
+// ErrorChallengeNotFound.
+func useTwoFactorChallenge(ctx context.Context, tx pgx.Tx, userID, id string) error {
+	if id == "" {
+		return nil
+	}
+
+	now := time.Now()
+	builder := sq.Update(challengesTable).
+		Set(usedAtColumn, now).
+		Where(sq.Eq{idColumn: id, userIDColumn: userID, usedAtColumn: nil}).
+		Where(sq.Gt{expiresAtColumn: now}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.ErrorBuildTOTPQuery
+	}
+
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return txError(err, repo.ErrorUpdateChallenge)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrorChallengeNotFound
+	}
+	return nil
+}
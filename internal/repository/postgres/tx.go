@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+
+	// maxTxAttempts bounds how many times a transaction is run when it keeps
+	// conflicting with concurrent ones.
+	maxTxAttempts = 5
+	// txRetryBackoff is the base pause before running a transaction again.
+	// It grows with every attempt and is jittered, so that the transactions
+	// that conflicted do not collide again.
+	txRetryBackoff = 10 * time.Millisecond
+)
+
+// inTx runs fn in a transaction and commits it. A transaction that fails on a
+// serialization failure or a deadlock is rolled back and run again, see
+// retry. fn must pass such failures through with txError for them to be
+// recognised; any other error ends inTx at once.
+func (r *repository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return retry(ctx, func() error {
+		return r.runTx(ctx, fn)
+	})
+}
+
+// retry calls fn again while it fails on a serialization failure or a
+// deadlock, at most maxTxAttempts times in total.
+func retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !isRetryable(err) {
+			return err
+		}
+		if attempt == maxTxAttempts {
+			return repo.ErrorTxConflict
+		}
+
+		pause := time.Duration(attempt)*txRetryBackoff + rand.N(txRetryBackoff)
+		select {
+		case <-ctx.Done():
+			return repo.ErrorTxConflict
+		case <-time.After(pause):
+		}
+	}
+}
+
+func (r *repository) runTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return txError(err, repo.ErrorTxCommit)
+	}
+	return nil
+}
+
+// txError returns err itself if the transaction failed because of a
+// concurrent one and can be run again, and sentinel otherwise.
+func txError(err, sentinel error) error {
+	if isRetryable(err) {
+		return err
+	}
+	return sentinel
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode
+}
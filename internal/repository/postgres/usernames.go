@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	repo "github.com/kingxl111/merch-store/internal/repository"
+)
+
+const (
+	usernameHistoryTable = "username_history"
+
+	changedAtColumn = "changed_at"
+)
+
+// RenameUser changes the username of the user and records the previous one.
+// It fails with ErrorRenameCooldown if the user has been renamed after
+// notBefore, and with ErrorUserAlreadyExists if the name belongs to another
+// user or was given up by another user after reservedSince.
+func (r *repository) RenameUser(ctx context.Context, userID, username string, notBefore, reservedSince time.Time) (*UsernameChange, error) {
+	tx, err := r.db.pool.Begin(ctx)
+	if err != nil {
+		return nil, repo.ErrorTxBegin
+	}
+	defer tx.Rollback(context.Background())
+
+	selectUser := sq.Select(usernameColumn).
+		From(usersTable).
+		Where(sq.Eq{idColumn: userID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := selectUser.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildRenameQuery
+	}
+
+	change := UsernameChange{
+		UserID:          userID,
+		CurrentUsername: username,
+		ChangedAt:       time.Now(),
+	}
+	if err := tx.QueryRow(ctx, query, args...).Scan(&change.Username); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorUserNotFound
+		}
+		return nil, repo.ErrorSelectUser
+	}
+
+	// The row lock above serialises renames of the user, so the cooldown
+	// cannot be dodged by concurrent requests.
+	selectRecent := sq.Select("1").
+		From(usernameHistoryTable).
+		Where(sq.Eq{userIDColumn: userID}).
+		Where(sq.Gt{changedAtColumn: notBefore}).
+		Limit(1).
+		PlaceholderFormat(sq.Dollar)
+
+	if found, err := historyExists(ctx, tx, selectRecent); err != nil {
+		return nil, err
+	} else if found {
+		return nil, repo.ErrorRenameCooldown
+	}
+
+	selectReserved := sq.Select("1").
+		From(usernameHistoryTable).
+		Where(usernameIs(usernameColumn, username)).
+		Where(sq.NotEq{userIDColumn: userID}).
+		Where(sq.Gt{changedAtColumn: reservedSince}).
+		Limit(1).
+		PlaceholderFormat(sq.Dollar)
+
+	if found, err := historyExists(ctx, tx, selectReserved); err != nil {
+		return nil, err
+	} else if found {
+		return nil, repo.ErrorUserAlreadyExists
+	}
+
+	rename := sq.Update(usersTable).
+		Set(usernameColumn, username).
+		Where(sq.Eq{idColumn: userID}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = rename.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildRenameQuery
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return nil, repo.ErrorUserAlreadyExists
+		}
+		return nil, repo.ErrorRenameUser
+	}
+
+	insertHistory := sq.Insert(usernameHistoryTable).
+		Columns(userIDColumn, usernameColumn, changedAtColumn).
+		Values(userID, change.Username, change.ChangedAt).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err = insertHistory.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildRenameQuery
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return nil, repo.ErrorRenameUser
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repo.ErrorTxCommit
+	}
+	return &change, nil
+}
+
+// GetLastUsernameChange returns the latest rename of the user, or nil if the
+// user has never been renamed.
+func (r *repository) GetLastUsernameChange(ctx context.Context, userID string) (*UsernameChange, error) {
+	builder := sq.Select("h."+userIDColumn, "h."+usernameColumn, "u."+usernameColumn, "h."+changedAtColumn).
+		From(usernameHistoryTable + " h").
+		Join(usersTable + " u ON u.id = h.user_id").
+		Where(sq.Eq{"h." + userIDColumn: userID}).
+		OrderBy("h." + changedAtColumn + " DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar)
+
+	change, err := r.selectUsernameChange(ctx, builder)
+	if errors.Is(err, repo.ErrorUserNotFound) {
+		return nil, nil
+	}
+	return change, err
+}
+
+// GetRenamedUser finds the user who gave up the username after since. It
+// fails with ErrorUserNotFound if nobody did.
+func (r *repository) GetRenamedUser(ctx context.Context, username string, since time.Time) (*UsernameChange, error) {
+	builder := sq.Select("h."+userIDColumn, "h."+usernameColumn, "u."+usernameColumn, "h."+changedAtColumn).
+		From(usernameHistoryTable + " h").
+		Join(usersTable + " u ON u.id = h.user_id").
+		Where(usernameIs("h."+usernameColumn, username)).
+		Where(sq.Gt{"h." + changedAtColumn: since}).
+		OrderBy("h." + changedAtColumn + " DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar)
+
+	return r.selectUsernameChange(ctx, builder)
+}
+
+func (r *repository) selectUsernameChange(ctx context.Context, builder sq.SelectBuilder) (*UsernameChange, error) {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, repo.ErrorBuildRenameQuery
+	}
+
+	var change UsernameChange
+	err = r.db.pool.QueryRow(ctx, query, args...).
+		Scan(&change.UserID, &change.Username, &change.CurrentUsername, &change.ChangedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrorUserNotFound
+		}
+		return nil, repo.ErrorSelectUsernameLog
+	}
+	return &change, nil
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// historyExists reports whether the query returns a row.
+func historyExists(ctx context.Context, db queryRower, builder sq.SelectBuilder) (bool, error) {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return false, repo.ErrorBuildRenameQuery
+	}
+
+	var one int
+	err = db.QueryRow(ctx, query, args...).Scan(&one)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, repo.ErrorSelectUsernameLog
+	default:
+		return true, nil
+	}
+}
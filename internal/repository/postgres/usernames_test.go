@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMixedCaseUsernames checks that the money paths find users whatever the
+// case of the username they are given, as GetUser does.
+func TestMixedCaseUsernames(t *testing.T) {
+	r := newStressRepository(t)
+	ctx := context.Background()
+
+	alice := &User{Username: "Alice"}
+	if err := r.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	err := r.BuyMerch(ctx, &InventoryItem{Username: "ALICE", ItemType: "cup", Quantity: 1}, nil)
+	if err != nil {
+		t.Fatalf("buy as ALICE: %v", err)
+	}
+
+	balance, err := r.GetBalance(ctx, "alice")
+	if err != nil {
+		t.Fatalf("balance of alice: %v", err)
+	}
+	if *balance != welcomeBonus-20 {
+		t.Errorf("balance of alice = %d, want %d", *balance, welcomeBonus-20)
+	}
+
+	inventory, err := r.GetInventory(ctx, "aLiCe")
+	if err != nil {
+		t.Fatalf("inventory of aLiCe: %v", err)
+	}
+	if len(inventory) != 1 || inventory[0].ItemType != "cup" || inventory[0].Quantity != 1 {
+		t.Errorf("inventory of aLiCe = %+v, want one cup", inventory)
+	}
+
+	bob := &User{Username: "Bob"}
+	if err := r.CreateUser(ctx, bob); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	transfer := &CoinTransaction{FromUsername: "aLICE", ToUsername: "BOB", Amount: 10}
+	key := &IdempotencyKey{Key: "mixed-case", RequestHash: "hash", Status: 200, Response: []byte("{}")}
+	if err := r.TransferCoins(ctx, transfer, key); err != nil {
+		t.Fatalf("transfer from aLICE to BOB: %v", err)
+	}
+
+	balance, err = r.GetBalance(ctx, "bob")
+	if err != nil {
+		t.Fatalf("balance of bob: %v", err)
+	}
+	if *balance != welcomeBonus+10 {
+		t.Errorf("balance of bob = %d, want %d", *balance, welcomeBonus+10)
+	}
+
+	hold := &PendingTransfer{FromUsername: "bOB", ToUsername: "alice", Amount: 5, SettlesAt: time.Now().Add(time.Minute)}
+	if err := r.HoldCoins(ctx, hold, nil); err != nil {
+		t.Fatalf("hold from bOB to alice: %v", err)
+	}
+
+	held, err := r.GetHeldCoins(ctx, "BoB")
+	if err != nil {
+		t.Fatalf("held coins of BoB: %v", err)
+	}
+	if held != 5 {
+		t.Errorf("held coins of BoB = %d, want 5", held)
+	}
+}
@@ -0,0 +1,185 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRuleSearch bounds the search for the next time a rule fires, so that a
+// rule that can never fire, such as "0 0 30 2 *", is detected.
+const maxRuleSearch = 5 * 366 * 24 * time.Hour
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type ruleField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = ruleField{name: "minute", min: 0, max: 59}
+	hourField   = ruleField{name: "hour", min: 0, max: 23}
+	domField    = ruleField{name: "day of month", min: 1, max: 31}
+	monthField  = ruleField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Both 0 and 7 are Sunday.
+	dowField = ruleField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Rule is a cron expression with the usual five fields: minute, hour, day of
+// month, month and day of week, each a *, a value, a range or a list of them,
+// optionally with a /step. Months and days of week can be given by their
+// three-letter English names. @yearly, @monthly, @weekly, @daily and @hourly
+// are accepted as well. Rules are evaluated in UTC.
+//
+// As in cron, if both the day of month and the day of week are restricted, a
+// day matches if either of them does. A field listing all of its values is
+// not restricted.
+type Rule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// ParseRule parses a cron expression.
+func ParseRule(expr string) (*Rule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: want 5 fields, got %d", ErrorInvalidRule, len(fields))
+	}
+
+	var r Rule
+	var err error
+	if r.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if r.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if r.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if r.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if r.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if r.dow&(1<<7) != 0 {
+		r.dow |= 1
+	}
+	// A field that lists every value, such as "1-31" or "0-6", restricts
+	// nothing, just like "*".
+	r.domAny = r.dom == domField.all()
+	r.dowAny = r.dow|1<<7 == dowField.all()
+	return &r, nil
+}
+
+// Next returns the first time after t at which the rule fires, or the zero
+// time if it never fires.
+func (r *Rule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxRuleSearch)
+
+	for t.Before(limit) {
+		switch {
+		case r.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !r.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case r.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case r.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (r *Rule) dayMatches(t time.Time) bool {
+	dom := r.dom&(1<<uint(t.Day())) != 0
+	dow := r.dow&(1<<uint(t.Weekday())) != 0
+	if r.domAny || r.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parse returns the values of the field as a bit set.
+func (f ruleField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step %q in %s", ErrorInvalidRule, stepExpr, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rangeExpr != "*" {
+			from, to, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%w: empty range %q in %s", ErrorInvalidRule, rangeExpr, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// all returns the bit set of every value of the field.
+func (f ruleField) all() uint64 {
+	return 1<<uint(f.max+1) - 1<<uint(f.min)
+}
+
+func (f ruleField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%w: bad %s %q", ErrorInvalidRule, f.name, s)
+	}
+	return v, nil
+}
@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseRule(expr); !errors.Is(err, ErrorInvalidRule) {
+				t.Errorf("ParseRule(%q) error = %v, want %v", expr, err, ErrorInvalidRule)
+			}
+		})
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	// 2025-01-01 is a Wednesday.
+	from := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jun *", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		// Both days restricted: either one matches.
+		{"0 0 15 * fri", time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		// A field covering its whole range restricts nothing.
+		{"0 0 1-31 * fri", time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 0-6", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 1-7", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * sun,mon,tue,wed,thu,fri,sat", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, err := ParseRule(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.expr, err)
+			}
+			if got := rule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
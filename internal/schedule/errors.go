@@ -0,0 +1,16 @@
+package schedule
+
+import "errors"
+
+// Errors of the transfer itself, such as an unknown receiver, are those of
+// the users package.
+var (
+	ErrorService            = errors.New("schedule service error")
+	ErrorInvalidTiming      = errors.New("exactly one of runAt and rule must be set")
+	ErrorRunAtInPast        = errors.New("runAt must be in the future")
+	ErrorInvalidRule        = errors.New("invalid rule")
+	ErrorSelfTransfer       = errors.New("cannot schedule a transfer to yourself")
+	ErrorTooManySchedules   = errors.New("too many active scheduled transfers")
+	ErrorScheduleNotFound   = errors.New("scheduled transfer not found")
+	ErrorScheduleNotPending = errors.New("scheduled transfer is already finished or cancelled")
+)
@@ -0,0 +1,50 @@
+package schedule
+
+import "time"
+
+// Statuses of a run of a scheduled transfer.
+const (
+	RunPending = "pending"
+	RunOK      = "ok"
+	RunFailed  = "failed"
+)
+
+// ScheduledTransfer sends Amount coins from FromUser to ToUser once at RunAt
+// or whenever Rule fires. NextRunAt is nil once it will not run again,
+// because it has run or has been cancelled.
+type ScheduledTransfer struct {
+	ID          string
+	FromUser    string
+	ToUser      string
+	Amount      int
+	Message     *string
+	Category    *string
+	RunAt       *time.Time
+	Rule        *string
+	NextRunAt   *time.Time
+	CreatedAt   time.Time
+	CancelledAt *time.Time
+	LastRun     *Run
+}
+
+// Run is one execution of a scheduled transfer. Error says why a failed run
+// made no transfer, e.g. "insufficient funds".
+type Run struct {
+	ID           int64
+	ScheduledFor time.Time
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+	Status       string
+	Error        *string
+}
+
+// CreateRequest describes a new scheduled transfer of the caller. Exactly one
+// of RunAt and Rule is set.
+type CreateRequest struct {
+	ToUser   string
+	Amount   int
+	Message  *string
+	Category *string
+	RunAt    *time.Time
+	Rule     *string
+}
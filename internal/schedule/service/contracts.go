@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/kingxl111/merch-store/internal/idempotency"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+type Repository interface {
+	CreateScheduledTransfer(ctx context.Context, s *postgres.ScheduledTransfer, maxActive int) error
+	ListScheduledTransfers(ctx context.Context, userID string) ([]postgres.ScheduledTransfer, error)
+	CancelScheduledTransfer(ctx context.Context, userID, id string) error
+	ListScheduledRuns(ctx context.Context, userID, id string, limit int) ([]postgres.ScheduledRun, error)
+
+	DueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]postgres.ScheduledTransfer, error)
+	StartScheduledRun(ctx context.Context, s *postgres.ScheduledTransfer, next *time.Time) (*postgres.ScheduledRun, error)
+	RestartStaleScheduledRuns(ctx context.Context, before time.Time, limit int) ([]postgres.ScheduledRun, error)
+	FinishScheduledRun(ctx context.Context, runID int64, status string, runError *string) error
+}
+
+// Transferer makes the transfers when they are due, so that they go through
+// the same checks as transfers made by users.
+type Transferer interface {
+	TransferCoins(ctx context.Context, req *users.CoinTransfer, rec *idempotency.Record) error
+}
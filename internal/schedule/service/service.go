@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/schedule"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	// maxActiveSchedules is how many scheduled transfers a user can have
+	// that are still to run.
+	maxActiveSchedules = 20
+	// runsLimit is how many runs of a scheduled transfer are listed.
+	runsLimit = 50
+)
+
+type scheduleService struct {
+	repo      Repository
+	transfers Transferer
+}
+
+func NewScheduleService(repo Repository, transfers Transferer) *scheduleService {
+	return &scheduleService{
+		repo:      repo,
+		transfers: transfers,
+	}
+}
+
+// Create schedules a transfer from the caller, once at req.RunAt or whenever
+// req.Rule fires.
+func (s *scheduleService) Create(ctx context.Context, principal *users.Principal, req *schedule.CreateRequest) (*schedule.ScheduledTransfer, error) {
+	if req.Amount <= 0 {
+		return nil, users.ErrorInvalidAmount
+	}
+	message, ok := users.TransferNote(req.Message, users.MaxTransferMessageLen)
+	if !ok {
+		return nil, users.ErrorInvalidMessage
+	}
+	category, ok := users.TransferNote(req.Category, users.MaxTransferCategoryLen)
+	if !ok {
+		return nil, users.ErrorInvalidCategory
+	}
+	if strings.EqualFold(req.ToUser, principal.Username) {
+		return nil, schedule.ErrorSelfTransfer
+	}
+
+	st := &postgres.ScheduledTransfer{
+		UserID:     principal.UserID,
+		ToUsername: req.ToUser,
+		Amount:     req.Amount,
+		Message:    message,
+		Category:   category,
+	}
+	now := time.Now()
+	switch {
+	case (req.RunAt == nil) == (req.Rule == nil):
+		return nil, schedule.ErrorInvalidTiming
+	case req.RunAt != nil:
+		if !req.RunAt.After(now) {
+			return nil, schedule.ErrorRunAtInPast
+		}
+		st.RunAt = req.RunAt
+		st.NextRunAt = req.RunAt
+	default:
+		expr := strings.TrimSpace(*req.Rule)
+		rule, err := schedule.ParseRule(expr)
+		if err != nil {
+			return nil, err
+		}
+		next := rule.Next(now)
+		if next.IsZero() {
+			return nil, schedule.ErrorInvalidRule
+		}
+		st.Rule = &expr
+		st.NextRunAt = &next
+	}
+
+	if err := s.repo.CreateScheduledTransfer(ctx, st, maxActiveSchedules); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorReceiverNotFound):
+			return nil, users.ErrorReceiverNotFound
+		case errors.Is(err, repository.ErrorReceiverClosed):
+			return nil, users.ErrorReceiverClosed
+		case errors.Is(err, repository.ErrorAccountFrozen):
+			return nil, users.ErrorAccountFrozen
+		case errors.Is(err, repository.ErrorAccountSuspended):
+			return nil, users.ErrorAccountSuspended
+		case errors.Is(err, repository.ErrorAccountClosed):
+			return nil, users.ErrorAccountClosed
+		case errors.Is(err, repository.ErrorTooManySchedules):
+			return nil, schedule.ErrorTooManySchedules
+		default:
+			return nil, schedule.ErrorService
+		}
+	}
+	return scheduledTransfer(st), nil
+}
+
+// List returns the scheduled transfers of the caller, newest first.
+func (s *scheduleService) List(ctx context.Context, principal *users.Principal) ([]schedule.ScheduledTransfer, error) {
+	list, err := s.repo.ListScheduledTransfers(ctx, principal.UserID)
+	if err != nil {
+		return nil, schedule.ErrorService
+	}
+
+	transfers := make([]schedule.ScheduledTransfer, 0, len(list))
+	for i := range list {
+		transfers = append(transfers, *scheduledTransfer(&list[i]))
+	}
+	return transfers, nil
+}
+
+// Cancel stops a scheduled transfer of the caller from running again.
+func (s *scheduleService) Cancel(ctx context.Context, principal *users.Principal, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return schedule.ErrorScheduleNotFound
+	}
+
+	if err := s.repo.CancelScheduledTransfer(ctx, principal.UserID, id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorScheduleNotFound):
+			return schedule.ErrorScheduleNotFound
+		case errors.Is(err, repository.ErrorScheduleNotPending):
+			return schedule.ErrorScheduleNotPending
+		default:
+			return schedule.ErrorService
+		}
+	}
+	return nil
+}
+
+// Runs returns the latest runs of a scheduled transfer of the caller, newest
+// first.
+func (s *scheduleService) Runs(ctx context.Context, principal *users.Principal, id string) ([]schedule.Run, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, schedule.ErrorScheduleNotFound
+	}
+
+	list, err := s.repo.ListScheduledRuns(ctx, principal.UserID, id, runsLimit)
+	if err != nil {
+		if errors.Is(err, repository.ErrorScheduleNotFound) {
+			return nil, schedule.ErrorScheduleNotFound
+		}
+		return nil, schedule.ErrorService
+	}
+
+	runs := make([]schedule.Run, 0, len(list))
+	for i := range list {
+		runs = append(runs, *scheduledRun(&list[i]))
+	}
+	return runs, nil
+}
+
+func scheduledTransfer(st *postgres.ScheduledTransfer) *schedule.ScheduledTransfer {
+	t := &schedule.ScheduledTransfer{
+		ID:          st.ID,
+		FromUser:    st.FromUsername,
+		ToUser:      st.ToUsername,
+		Amount:      st.Amount,
+		Message:     st.Message,
+		Category:    st.Category,
+		RunAt:       st.RunAt,
+		Rule:        st.Rule,
+		NextRunAt:   st.NextRunAt,
+		CreatedAt:   st.CreatedAt,
+		CancelledAt: st.CancelledAt,
+	}
+	if st.LastRun != nil {
+		t.LastRun = scheduledRun(st.LastRun)
+	}
+	return t
+}
+
+func scheduledRun(r *postgres.ScheduledRun) *schedule.Run {
+	return &schedule.Run{
+		ID:           r.ID,
+		ScheduledFor: r.ScheduledFor,
+		StartedAt:    r.StartedAt,
+		FinishedAt:   r.FinishedAt,
+		Status:       r.Status,
+		Error:        r.Error,
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/idempotency"
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/schedule"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	// batchSize is how many due transfers are run per poll.
+	batchSize = 100
+	// runLease is how long a run may stay pending before it is assumed to
+	// have been interrupted and is started again.
+	runLease = 5 * time.Minute
+)
+
+// Work runs the due scheduled transfers every interval until ctx is done.
+// Several servers can work at once: each run is claimed by one of them.
+func (s *scheduleService) Work(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduleService) runDue(ctx context.Context) {
+	now := time.Now()
+
+	stale, err := s.repo.RestartStaleScheduledRuns(ctx, now.Add(-runLease), batchSize)
+	if err != nil {
+		slog.Error("failed to restart stale scheduled transfer runs", slog.Any("error", err))
+	}
+	for i := range stale {
+		s.execute(ctx, &stale[i], stale[i].Transfer)
+	}
+
+	due, err := s.repo.DueScheduledTransfers(ctx, now, batchSize)
+	if err != nil {
+		slog.Error("failed to select due scheduled transfers", slog.Any("error", err))
+		return
+	}
+	for i := range due {
+		st := &due[i]
+		run, err := s.repo.StartScheduledRun(ctx, st, nextRun(st, now))
+		if errors.Is(err, repository.ErrorScheduleClaimed) {
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to start scheduled transfer run",
+				slog.String("schedule", st.ID),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		s.execute(ctx, run, st)
+	}
+}
+
+// nextRun returns when st is due after the run due now, or nil if it is not
+// to run again. Occurrences of a rule missed while no server was running are
+// skipped rather than made up for.
+func nextRun(st *postgres.ScheduledTransfer, now time.Time) *time.Time {
+	if st.Rule == nil {
+		return nil
+	}
+	rule, err := schedule.ParseRule(*st.Rule)
+	if err != nil {
+		return nil
+	}
+
+	after := *st.NextRunAt
+	if now.After(after) {
+		after = now
+	}
+	next := rule.Next(after)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// execute makes the transfer of a started run and records its outcome. The
+// idempotency key of the run makes sure that a run started again after an
+// interruption does not transfer the coins twice.
+func (s *scheduleService) execute(ctx context.Context, run *postgres.ScheduledRun, st *postgres.ScheduledTransfer) {
+	rec := &idempotency.Record{
+		Key:         fmt.Sprintf("scheduled-transfer-run:%d", run.ID),
+		RequestHash: idempotency.Hash(http.MethodPost, "/api/scheduledTransfers/"+st.ID, nil),
+		Status:      http.StatusOK,
+		Response:    []byte(`"Coins sent"`),
+	}
+	err := s.transfers.TransferCoins(ctx, &users.CoinTransfer{
+		FromUser: st.FromUsername,
+		ToUser:   st.ToUsername,
+		Amount:   st.Amount,
+		Message:  st.Message,
+		Category: st.Category,
+	}, rec)
+	if ctx.Err() != nil {
+		// The run stays pending and is started again once its lease is up.
+		return
+	}
+
+	status, runError := schedule.RunOK, (*string)(nil)
+	if err != nil {
+		msg := err.Error()
+		status, runError = schedule.RunFailed, &msg
+		slog.Warn("scheduled transfer failed",
+			slog.String("schedule", st.ID),
+			slog.String("from", st.FromUsername),
+			slog.String("to", st.ToUsername),
+			slog.Int("amount", st.Amount),
+			slog.String("error", msg),
+		)
+	} else {
+		slog.Info("scheduled transfer made",
+			slog.String("schedule", st.ID),
+			slog.String("from", st.FromUsername),
+			slog.String("to", st.ToUsername),
+			slog.Int("amount", st.Amount),
+		)
+	}
+
+	if err := s.repo.FinishScheduledRun(ctx, run.ID, status, runError); err != nil {
+		slog.Error("failed to record scheduled transfer run",
+			slog.Int64("run", run.ID),
+			slog.Any("error", err),
+		)
+	}
+}
@@ -8,6 +8,9 @@ var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrItemNotFound      = errors.New("item not found")
 	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrAccountSuspended  = errors.New("account is suspended")
+	ErrAccountClosed     = errors.New("account is closed")
 	ErrBuildQuery        = errors.New("failed to build query")
 	ErrUpdateBalance     = errors.New("failed to update user balance")
 	ErrUpdateInventory   = errors.New("failed to update inventory")
@@ -7,6 +7,6 @@ import (
 )
 
 type ShopRepository interface {
-	BuyMerch(ctx context.Context, item *postgres.InventoryItem) error
+	BuyMerch(ctx context.Context, item *postgres.InventoryItem, idem *postgres.IdempotencyKey) error
 	GetInventory(ctx context.Context, userID string) ([]postgres.InventoryItem, error)
 }
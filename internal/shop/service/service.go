@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	env "github.com/kingxl111/merch-store/internal/environment"
+	"github.com/kingxl111/merch-store/internal/idempotency"
 	repo "github.com/kingxl111/merch-store/internal/repository"
 	"github.com/kingxl111/merch-store/internal/repository/postgres"
 
@@ -21,7 +22,10 @@ func NewShopService(shopRepo ShopRepository) *shopService {
 	}
 }
 
-func (s *shopService) BuyMerch(ctx context.Context, req shop.InventoryItem) error {
+// BuyMerch buys the item for the caller. With a non-nil rec the item is
+// bought only once per idempotency key; a retry gets the first response in
+// rec.
+func (s *shopService) BuyMerch(ctx context.Context, req shop.InventoryItem, rec *idempotency.Record) error {
 	username, ok := ctx.Value(env.UsernameContextKey).(string)
 	if !ok {
 		return shop.ErrUserNotFound
@@ -33,15 +37,24 @@ func (s *shopService) BuyMerch(ctx context.Context, req shop.InventoryItem) erro
 		Quantity: req.Quantity,
 	}
 
-	err := s.shopRepo.BuyMerch(ctx, &item)
+	idem := idempotency.StoredKey(rec)
+	err := s.shopRepo.BuyMerch(ctx, &item, idem)
 	if err != nil {
 		switch {
+		case errors.Is(err, repo.ErrorIdempotencyKeyReused):
+			return idempotency.ErrKeyReused
 		case errors.Is(err, repo.ErrorUserNotFound):
 			return shop.ErrUserNotFound
 		case errors.Is(err, repo.ErrorItemNotFound):
 			return shop.ErrItemNotFound
 		case errors.Is(err, repo.ErrorInsFunds):
 			return shop.ErrInsufficientFunds
+		case errors.Is(err, repo.ErrorAccountFrozen):
+			return shop.ErrAccountFrozen
+		case errors.Is(err, repo.ErrorAccountSuspended):
+			return shop.ErrAccountSuspended
+		case errors.Is(err, repo.ErrorAccountClosed):
+			return shop.ErrAccountClosed
 		case errors.Is(err, repo.ErrorBuildSenderSelectQuery),
 			errors.Is(err, repo.ErrorBuildBalanceUpdateQuery),
 			errors.Is(err, repo.ErrorBuildInventoryUpdateQuery):
@@ -58,5 +71,6 @@ func (s *shopService) BuyMerch(ctx context.Context, req shop.InventoryItem) erro
 		}
 	}
 
+	idempotency.Replay(rec, idem)
 	return nil
 }
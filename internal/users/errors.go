@@ -1,6 +1,10 @@
 package users
 
-import "github.com/go-faster/errors"
+import (
+	"time"
+
+	"github.com/go-faster/errors"
+)
 
 var (
 	ErrorService       = errors.New("users service error")
@@ -9,4 +13,115 @@ var (
 	ErrorWrongPassword = errors.New("wrong password")
 	ErrorInsufFunds    = errors.New("insufficient funds")
 	ErrorInvalidAmount = errors.New("invalid amount")
+	ErrorSelfTransfer  = errors.New("cannot send coins to yourself")
+
+	ErrorInvalidMessage  = errors.New("transfer message is too long")
+	ErrorInvalidCategory = errors.New("transfer category is too long")
+
+	ErrorInvalidUsername  = errors.New("invalid username")
+	ErrorReservedUsername = errors.New("username is reserved")
+	ErrorUsernameTaken    = errors.New("username is already taken")
+	ErrorInvalidPassword  = errors.New("invalid password")
+
+	ErrorInvalidResetToken = errors.New("invalid password reset token")
+
+	ErrorInvalidToken        = errors.New("invalid token")
+	ErrorInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrorSessionEnded        = errors.New("session ended")
+	ErrorSessionNotFound     = errors.New("session not found")
+
+	ErrorUserNotFound     = errors.New("user not found")
+	ErrorReceiverNotFound = errors.New("receiver not found")
+	ErrorUserRenamed      = errors.New("user has been renamed")
+	ErrorRenameCooldown   = errors.New("username was changed too recently")
+	ErrorInvalidRole      = errors.New("invalid role")
+
+	ErrorInvalidStatus    = errors.New("invalid account status")
+	ErrorAccountFrozen    = errors.New("account is frozen")
+	ErrorAccountSuspended = errors.New("account is suspended")
+	ErrorAccountClosed    = errors.New("account is closed")
+	ErrorReceiverClosed   = errors.New("receiver account is closed")
+
+	ErrorCannotOffboard    = errors.New("user cannot be offboarded")
+	ErrorSinkNotConfigured = errors.New("offboarding sink account is not configured")
+	ErrorSinkUnavailable   = errors.New("offboarding sink account not found or closed")
+
+	ErrorPurchaseNotFound   = errors.New("purchase not found")
+	ErrorAlreadyRefunded    = errors.New("purchase already refunded")
+	ErrorItemNotInInventory = errors.New("item is no longer in the inventory")
+
+	ErrorReasonRequired    = errors.New("reason is required")
+	ErrorCannotImpersonate = errors.New("user cannot be impersonated")
+
+	ErrorTooManyAttempts = errors.New("too many failed login attempts")
+
+	ErrorInvalidAPIKey     = errors.New("invalid api key")
+	ErrorAPIKeyNotFound    = errors.New("api key not found")
+	ErrorInvalidAPIKeyName = errors.New("invalid api key name")
+	ErrorInvalidScope      = errors.New("invalid scope")
+	ErrorInvalidQuota      = errors.New("invalid quota")
+	ErrorQuotaExceeded     = errors.New("api key quota exceeded")
+
+	ErrorInvalidChallenge     = errors.New("invalid two-factor challenge")
+	ErrorInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrorTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrorTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+	ErrorTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
 )
+
+// LockoutError is returned while logins are locked after repeated failures.
+// It matches ErrorTooManyAttempts.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return ErrorTooManyAttempts.Error()
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrorTooManyAttempts
+}
+
+// QuotaError is returned when an API key has used up its daily quota. It
+// matches ErrorQuotaExceeded.
+type QuotaError struct {
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return ErrorQuotaExceeded.Error()
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrorQuotaExceeded
+}
+
+// RenameCooldownError is returned when a user tries to change the username
+// again before the cooldown has passed. It matches ErrorRenameCooldown.
+type RenameCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RenameCooldownError) Error() string {
+	return ErrorRenameCooldown.Error()
+}
+
+func (e *RenameCooldownError) Is(target error) bool {
+	return target == ErrorRenameCooldown
+}
+
+// UserRenamedError is returned for coins sent to a username its owner has
+// recently given up, when such transfers are not routed to the new one. It
+// matches ErrorUserRenamed.
+type UserRenamedError struct {
+	Username string
+}
+
+func (e *UserRenamedError) Error() string {
+	return ErrorUserRenamed.Error() + " to " + e.Username
+}
+
+func (e *UserRenamedError) Is(target error) bool {
+	return target == ErrorUserRenamed
+}
@@ -1,25 +1,324 @@
 package users
 
-import "github.com/kingxl111/merch-store/internal/shop"
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kingxl111/merch-store/internal/shop"
+)
 
 type AuthRequest struct {
 	Username string
 	Password string
+	// ClientIP is the address the login attempt comes from. It is used to
+	// throttle password guessing and may be empty.
+	ClientIP string
+	// UserAgent is stored with the new session, so that users can tell
+	// their sessions apart.
+	UserAgent string
 }
 
+// AuthResponse holds the tokens of a new session. For users with two-factor
+// authentication a correct password only yields a ChallengeToken, valid for
+// ExpiresIn seconds, which is exchanged for the session tokens together with
+// a code.
 type AuthResponse struct {
-	Token string
+	Token          string
+	RefreshToken   string
+	ExpiresIn      int
+	ChallengeToken string
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string
+	// Code is a TOTP code or one of the recovery codes.
+	Code      string
+	ClientIP  string
+	UserAgent string
+}
+
+type TwoFactorEnrollment struct {
+	Secret string
+	URL    string
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string
+	NewPassword string
+}
+
+type ResetPasswordRequest struct {
+	Token       string
+	NewPassword string
+}
+
+type RefreshRequest struct {
+	RefreshToken string
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Account statuses. Frozen users can log in and receive coins but not spend
+// them, suspended users cannot log in either, and closed accounts cannot
+// receive coins anymore.
+const (
+	StatusActive    = "active"
+	StatusFrozen    = "frozen"
+	StatusSuspended = "suspended"
+	StatusClosed    = "closed"
+)
+
+// Scopes an API key can be limited to.
+const (
+	ScopeInfoRead  = "info:read"
+	ScopeCoinsSend = "coins:send"
+	ScopeShopBuy   = "shop:buy"
+)
+
+// Principal identifies the caller of an authenticated request. Callers
+// authenticated with an access token have a SessionID, callers authenticated
+// with an API key an APIKeyID and the Scopes of the key.
+type Principal struct {
+	UserID    string
+	Username  string
+	SessionID string
+	TokenID   string
+	Roles     []string
+	APIKeyID  string
+	Scopes    []string
+	// Impersonation is set when an admin acts as the user.
+	Impersonation *Impersonation
+}
+
+// Impersonation identifies an admin acting as another user with an
+// impersonation token. Read-only impersonations can only call operations
+// that do not change anything.
+type Impersonation struct {
+	ID            string
+	AdminID       string
+	AdminUsername string
+	ReadOnly      bool
+}
+
+type ImpersonateRequest struct {
+	Username    string
+	Reason      string
+	AllowWrites bool
+}
+
+type ImpersonationToken struct {
+	Token     string
+	ExpiresIn int
+	ReadOnly  bool
+}
+
+// ImpersonationRecord tells a user that an admin has acted as them.
+type ImpersonationRecord struct {
+	Admin     string
+	Reason    string
+	ReadOnly  bool
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string
+	Scopes []string
+	// Quota limits the requests per day (UTC) made with the key. Nil means
+	// unlimited.
+	Quota *int
+}
+
+// APIKey describes an API key. Key, the secret itself, is only set when the
+// key has just been created.
+type APIKey struct {
+	ID         string
+	Name       string
+	Prefix     string
+	Key        string
+	Scopes     []string
+	Quota      *int
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
 }
 
+// SessionInfo describes an active session of a user.
+type SessionInfo struct {
+	ID         string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	// Current is set for the session of the caller.
+	Current bool
+}
+
+type SetRoleRequest struct {
+	Username string
+	Role     string
+}
+
+type SetStatusRequest struct {
+	Username string
+	Status   string
+	Reason   string
+}
+
+// AccountStatus is the current status of a user with its latest changes,
+// newest first.
+type AccountStatus struct {
+	Status  string
+	History []StatusChange
+}
+
+type StatusChange struct {
+	Status string
+	Reason string
+	// Admin is empty if the admin's account has since been deleted.
+	Admin     string
+	CreatedAt time.Time
+}
+
+type GrantRequest struct {
+	Username string
+	Amount   int
+	Reason   string
+}
+
+type RefundRequest struct {
+	EntryID int64
+	Reason  string
+}
+
+// LedgerEntry is an entry of the coin journal as seen from the account of one
+// user. Amount is positive if the entry credited the account and negative if
+// it debited it. Counterparty is the username or the system account on the
+// other side: "issuance" for grants or "shop" for purchases and refunds.
+type LedgerEntry struct {
+	ID           int64
+	Kind         string
+	Amount       int
+	Counterparty string
+	Item         *string
+	Quantity     *int
+	// RefundOf is the purchase a refund reverses.
+	RefundOf    *int64
+	Description string
+	// CreatedBy is the admin who made a grant or a refund.
+	CreatedBy *string
+	CreatedAt time.Time
+}
+
+type OffboardRequest struct {
+	Username string
+	Reason   string
+}
+
+// OffboardingSummary describes a closed account for the records of HR. It is
+// the only place the original username is reported once the account is
+// anonymised.
+type OffboardingSummary struct {
+	UserID               string
+	Username             string
+	AnonymizedUsername   string
+	SinkAccount          string
+	SweptCoins           int
+	Inventory            []shop.InventoryItem
+	SentTransactions     int
+	ReceivedTransactions int
+	SessionsRevoked      int
+	APIKeysRevoked       int
+	Reason               string
+	ClosedBy             string
+	ClosedAt             time.Time
+}
+
+// UserInfoResponse describes the account of a user. Coins is the available
+// balance; HeldCoins are held in the user's delayed transfers.
 type UserInfoResponse struct {
 	Coins           int
+	HeldCoins       int
 	Inventory       []shop.InventoryItem
 	ReceivedHistory []CoinTransfer
 	SentHistory     []CoinTransfer
+	Impersonations  []ImpersonationRecord
+}
+
+// Limits of the optional message and category of a transfer, in characters.
+const (
+	MaxTransferMessageLen  = 200
+	MaxTransferCategoryLen = 32
+)
+
+// TransferNote trims the message or category of a transfer. A blank one is
+// dropped; ok is false if it is longer than maxLen characters.
+func TransferNote(note *string, maxLen int) (*string, bool) {
+	if note == nil {
+		return nil, true
+	}
+	s := strings.TrimSpace(*note)
+	if s == "" {
+		return nil, true
+	}
+	if utf8.RuneCountInString(s) > maxLen {
+		return nil, false
+	}
+	return &s, true
 }
 
+// CoinTransfer is a transfer of coins. Message and Category are optional.
+// A Delayed transfer holds the coins for a while, during which the sender
+// can cancel it, instead of sending them right away.
 type CoinTransfer struct {
 	FromUser string
 	ToUser   string
 	Amount   int
+	Message  *string
+	Category *string
+	Delayed  bool
+}
+
+// LockoutEvent records that logins for a username or a client address were
+// locked after too many failed attempts.
+type LockoutEvent struct {
+	Scope       string
+	Subject     string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
+
+// JSONWebKey is a public verification key as published in a JWKS document.
+type JSONWebKey struct {
+	KeyID     string
+	KeyType   string
+	Algorithm string
+	Use       string
+	Curve     string
+	X         string
+	N         string
+	E         string
 }
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	// apiKeyPrefix makes keys recognizable, e.g. by secret scanners.
+	apiKeyPrefix = "msk_"
+	// apiKeyShownLen is how much of a key is stored in clear text so that
+	// users can tell their keys apart.
+	apiKeyShownLen = len(apiKeyPrefix) + 8
+
+	maxAPIKeyNameLen = 64
+)
+
+var apiKeyScopes = map[string]struct{}{
+	users.ScopeInfoRead:  {},
+	users.ScopeCoinsSend: {},
+	users.ScopeShopBuy:   {},
+}
+
+// CreateAPIKey issues a new API key for the caller. The key is returned only
+// once; afterwards only its prefix is known.
+func (u *userService) CreateAPIKey(ctx context.Context, principal *users.Principal, req *users.CreateAPIKeyRequest) (*users.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if len(name) == 0 || utf8.RuneCountInString(name) > maxAPIKeyNameLen {
+		return nil, users.ErrorInvalidAPIKeyName
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, users.ErrorInvalidScope
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]struct{}, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if _, ok := apiKeyScopes[scope]; !ok {
+			return nil, users.ErrorInvalidScope
+		}
+		if _, ok := seen[scope]; !ok {
+			seen[scope] = struct{}{}
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.Quota != nil && *req.Quota <= 0 {
+		return nil, users.ErrorInvalidQuota
+	}
+
+	secret, _, err := generateOpaqueToken()
+	if err != nil {
+		return nil, users.ErrorGenerateToken
+	}
+	key := apiKeyPrefix + secret
+
+	record := &postgres.APIKey{
+		UserID:  principal.UserID,
+		Name:    name,
+		Prefix:  key[:apiKeyShownLen],
+		KeyHash: hashOpaqueToken(key),
+		Scopes:  scopes,
+		Quota:   req.Quota,
+	}
+	if err := u.authRepo.CreateAPIKey(ctx, record); err != nil {
+		return nil, users.ErrorService
+	}
+
+	resp := apiKeyInfo(record)
+	resp.Key = key
+	return &resp, nil
+}
+
+func (u *userService) ListAPIKeys(ctx context.Context, principal *users.Principal) ([]users.APIKey, error) {
+	keys, err := u.authRepo.ListAPIKeys(ctx, principal.UserID)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	resp := make([]users.APIKey, 0, len(keys))
+	for i := range keys {
+		resp = append(resp, apiKeyInfo(&keys[i]))
+	}
+	return resp, nil
+}
+
+func (u *userService) RevokeAPIKey(ctx context.Context, principal *users.Principal, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return users.ErrorAPIKeyNotFound
+	}
+
+	if err := u.authRepo.RevokeAPIKey(ctx, principal.UserID, keyID); err != nil {
+		if errors.Is(err, repository.ErrorAPIKeyNotFound) {
+			return users.ErrorAPIKeyNotFound
+		}
+		return users.ErrorService
+	}
+	return nil
+}
+
+// VerifyAPIKey authenticates a request made with an API key. The request is
+// not counted against the key's quota until CountAPIKeyRequest is called.
+func (u *userService) VerifyAPIKey(ctx context.Context, key string) (*users.Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, users.ErrorInvalidAPIKey
+	}
+
+	record, err := u.authRepo.GetAPIKey(ctx, hashOpaqueToken(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrorAPIKeyNotFound) {
+			return nil, users.ErrorInvalidAPIKey
+		}
+		return nil, users.ErrorService
+	}
+
+	if err := checkCanLogIn(record.Status); err != nil {
+		return nil, err
+	}
+
+	return &users.Principal{
+		UserID:   record.UserID,
+		Username: record.Username,
+		Roles:    []string{record.Role},
+		APIKeyID: record.ID,
+		Scopes:   record.Scopes,
+	}, nil
+}
+
+// CountAPIKeyRequest counts a request made with the API key of the principal
+// against the key's quota. Once the quota is used up it returns a
+// *users.QuotaError until the end of the day (UTC).
+func (u *userService) CountAPIKeyRequest(ctx context.Context, principal *users.Principal) error {
+	requests, quota, err := u.authRepo.CountAPIKeyRequest(ctx, principal.APIKeyID)
+	if err != nil {
+		return users.ErrorService
+	}
+
+	if quota != nil && requests > *quota {
+		now := time.Now().UTC()
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return &users.QuotaError{RetryAfter: tomorrow.Sub(now)}
+	}
+	return nil
+}
+
+func apiKeyInfo(key *postgres.APIKey) users.APIKey {
+	return users.APIKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		Quota:      key.Quota,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
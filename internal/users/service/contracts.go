@@ -2,17 +2,88 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
 )
 
 type AuthRepository interface {
-	AuthUser(ctx context.Context, user *postgres.User) error
+	GetUser(ctx context.Context, username string) (*postgres.User, error)
+	CreateUser(ctx context.Context, user *postgres.User) error
+	UpdatePassword(ctx context.Context, userID, password string) error
+
+	CreateSession(ctx context.Context, session *postgres.Session, token *postgres.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, tokenHash string, next *postgres.RefreshToken) (*postgres.Session, error)
+	GetSession(ctx context.Context, sessionID string) (*postgres.Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	RevokeUserSessions(ctx context.Context, userID string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error
+	RevokeUserSession(ctx context.Context, userID, sessionID string) error
+	ListUserSessions(ctx context.Context, userID string) ([]postgres.Session, error)
+	TouchSession(ctx context.Context, sessionID, tokenID, userAgent, ip string) error
+
+	CreatePasswordReset(ctx context.Context, token *postgres.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash, password string) (*postgres.User, error)
+
+	SetUserRole(ctx context.Context, username, role string) (*postgres.User, error)
+	SetUserStatus(ctx context.Context, username, adminID, status, reason string) (*postgres.User, error)
+	GetUserStatusEvents(ctx context.Context, userID string, limit int) ([]postgres.UserStatusEvent, error)
+	OffboardUser(ctx context.Context, off *postgres.Offboarding) error
+
+	RenameUser(ctx context.Context, userID, username string, notBefore, reservedSince time.Time) (*postgres.UsernameChange, error)
+	GetLastUsernameChange(ctx context.Context, userID string) (*postgres.UsernameChange, error)
+	GetRenamedUser(ctx context.Context, username string, since time.Time) (*postgres.UsernameChange, error)
+
+	GetLockedUntil(ctx context.Context, keys []postgres.AuthFailureKey) (*time.Time, error)
+	RecordAuthFailure(ctx context.Context, key postgres.AuthFailureKey, window time.Duration) (int, error)
+	LockAuth(ctx context.Context, key postgres.AuthFailureKey, failures int, until time.Time) error
+	ResetAuthFailures(ctx context.Context, key postgres.AuthFailureKey) error
+	GetLockoutEvents(ctx context.Context, limit int) ([]postgres.LockoutEvent, error)
+
+	CreateAPIKey(ctx context.Context, key *postgres.APIKey) error
+	ListAPIKeys(ctx context.Context, userID string) ([]postgres.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string) error
+	GetAPIKey(ctx context.Context, keyHash string) (*postgres.APIKey, error)
+	CountAPIKeyRequest(ctx context.Context, keyID string) (int, *int, error)
+
+	CreateImpersonation(ctx context.Context, imp *postgres.Impersonation) error
+	RecordImpersonatedRequest(ctx context.Context, impersonationID, method, path string, status int) error
+	ListUserImpersonations(ctx context.Context, username string, limit int) ([]postgres.Impersonation, error)
+
+	GetTOTP(ctx context.Context, userID string) (*postgres.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID, secret string) error
+	EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64, challengeID string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash, challengeID string) error
+	DisableTOTP(ctx context.Context, userID string) error
+	CreateTwoFactorChallenge(ctx context.Context, c *postgres.TwoFactorChallenge) error
 }
 
 type UserRepository interface {
 	GetInventory(ctx context.Context, username string) ([]postgres.InventoryItem, error)
-	TransferCoins(ctx context.Context, fromUser, toUser string, amount int) error
+	TransferCoins(ctx context.Context, t *postgres.CoinTransaction, idem *postgres.IdempotencyKey) error
+	HoldCoins(ctx context.Context, p *postgres.PendingTransfer, idem *postgres.IdempotencyKey) error
 	GetBalance(ctx context.Context, username string) (*int, error)
+	GetHeldCoins(ctx context.Context, username string) (int, error)
 	GetTransactionHistory(ctx context.Context, username string) ([]postgres.CoinTransaction, error)
+
+	GrantCoins(ctx context.Context, username, adminID string, amount int, reason string) (*postgres.JournalEntry, error)
+	RefundPurchase(ctx context.Context, entryID int64, adminID, reason string) (*postgres.JournalEntry, error)
+	GetLedger(ctx context.Context, userID string, limit int) ([]postgres.LedgerLine, error)
+}
+
+type TokenManager interface {
+	GenerateToken(principal *users.Principal) (string, error)
+	ParseToken(accessToken string) (*users.Principal, error)
+	TokenTTL() time.Duration
+	GenerateImpersonationToken(principal *users.Principal, ttl time.Duration) (string, error)
+
+	GenerateChallengeToken(challengeID, userID, username string, ttl time.Duration) (string, error)
+	ParseChallengeToken(challengeToken string) (challengeID, userID, username string, err error)
+}
+
+// Notifier delivers messages to users outside of the API.
+type Notifier interface {
+	NotifyPasswordReset(ctx context.Context, username, token string, expiresAt time.Time) error
 }
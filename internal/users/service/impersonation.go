@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	impersonationTTL = 15 * time.Minute
+
+	// impersonationRecordsLimit is how many past impersonations /api/info
+	// shows.
+	impersonationRecordsLimit = 20
+)
+
+// Impersonate issues a short-lived access token that lets the admin act as
+// another user, for support. The token is read-only unless writes are
+// explicitly allowed, is bound to the admin's session and every request made
+// with it is audited. Admins cannot be impersonated.
+func (u *userService) Impersonate(ctx context.Context, admin *users.Principal, req *users.ImpersonateRequest) (*users.ImpersonationToken, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) == 0 {
+		return nil, users.ErrorReasonRequired
+	}
+
+	if admin.SessionID == "" || admin.Impersonation != nil {
+		return nil, users.ErrorCannotImpersonate
+	}
+
+	target, err := u.authRepo.GetUser(ctx, req.Username)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return nil, users.ErrorUserNotFound
+		}
+		return nil, users.ErrorService
+	}
+	if target.ID == admin.UserID || target.Role == users.RoleAdmin {
+		return nil, users.ErrorCannotImpersonate
+	}
+
+	imp := &postgres.Impersonation{
+		AdminID:   admin.UserID,
+		UserID:    target.ID,
+		SessionID: admin.SessionID,
+		Reason:    reason,
+		ReadOnly:  !req.AllowWrites,
+		ExpiresAt: time.Now().Add(impersonationTTL),
+	}
+	if err := u.authRepo.CreateImpersonation(ctx, imp); err != nil {
+		return nil, users.ErrorService
+	}
+
+	token, err := u.tokens.GenerateImpersonationToken(&users.Principal{
+		UserID:    target.ID,
+		Username:  target.Username,
+		SessionID: admin.SessionID,
+		Roles:     []string{target.Role},
+		Impersonation: &users.Impersonation{
+			ID:            imp.ID,
+			AdminID:       admin.UserID,
+			AdminUsername: admin.Username,
+			ReadOnly:      imp.ReadOnly,
+		},
+	}, impersonationTTL)
+	if err != nil {
+		return nil, users.ErrorGenerateToken
+	}
+
+	slog.Warn("impersonation started",
+		slog.String("admin", admin.Username),
+		slog.String("user", target.Username),
+		slog.String("impersonation", imp.ID),
+		slog.Bool("read_only", imp.ReadOnly),
+	)
+
+	return &users.ImpersonationToken{
+		Token:     token,
+		ExpiresIn: int(impersonationTTL.Seconds()),
+		ReadOnly:  imp.ReadOnly,
+	}, nil
+}
+
+// AuditImpersonatedRequest adds a request made with an impersonation token
+// to the audit trail.
+func (u *userService) AuditImpersonatedRequest(ctx context.Context, principal *users.Principal, method, path string, status int) error {
+	if principal.Impersonation == nil {
+		return nil
+	}
+
+	err := u.authRepo.RecordImpersonatedRequest(ctx, principal.Impersonation.ID, method, path, status)
+	if err != nil {
+		return users.ErrorService
+	}
+	return nil
+}
+
+func (u *userService) impersonationRecords(ctx context.Context, username string) ([]users.ImpersonationRecord, error) {
+	imps, err := u.authRepo.ListUserImpersonations(ctx, username, impersonationRecordsLimit)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	records := make([]users.ImpersonationRecord, 0, len(imps))
+	for _, imp := range imps {
+		records = append(records, users.ImpersonationRecord{
+			Admin:     imp.AdminUsername,
+			Reason:    imp.Reason,
+			ReadOnly:  imp.ReadOnly,
+			CreatedAt: imp.CreatedAt,
+			ExpiresAt: imp.ExpiresAt,
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const ledgerLimit = 100
+
+// GrantCoins credits a user with new coins on behalf of the admin.
+func (u *userService) GrantCoins(ctx context.Context, admin *users.Principal, req *users.GrantRequest) (*users.LedgerEntry, error) {
+	if req.Amount <= 0 {
+		return nil, users.ErrorInvalidAmount
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, users.ErrorReasonRequired
+	}
+
+	entry, err := u.userRepo.GrantCoins(ctx, req.Username, admin.UserID, req.Amount, reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorUserNotFound):
+			return nil, users.ErrorUserNotFound
+		case errors.Is(err, repository.ErrorAccountClosed):
+			return nil, users.ErrorAccountClosed
+		default:
+			return nil, users.ErrorService
+		}
+	}
+
+	slog.Warn("coins granted",
+		slog.String("admin", admin.Username),
+		slog.String("user", req.Username),
+		slog.Int("amount", req.Amount),
+		slog.String("reason", reason),
+	)
+	return creditEntry(entry, admin.Username), nil
+}
+
+// RefundPurchase reverses a purchase on behalf of the admin, giving the coins
+// back and taking the items out of the buyer's inventory.
+func (u *userService) RefundPurchase(ctx context.Context, admin *users.Principal, req *users.RefundRequest) (*users.LedgerEntry, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, users.ErrorReasonRequired
+	}
+
+	entry, err := u.userRepo.RefundPurchase(ctx, req.EntryID, admin.UserID, reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorPurchaseNotFound):
+			return nil, users.ErrorPurchaseNotFound
+		case errors.Is(err, repository.ErrorAlreadyRefunded):
+			return nil, users.ErrorAlreadyRefunded
+		case errors.Is(err, repository.ErrorItemNotInInventory):
+			return nil, users.ErrorItemNotInInventory
+		case errors.Is(err, repository.ErrorAccountClosed):
+			return nil, users.ErrorAccountClosed
+		default:
+			return nil, users.ErrorService
+		}
+	}
+
+	slog.Warn("purchase refunded",
+		slog.String("admin", admin.Username),
+		slog.Int64("purchase", req.EntryID),
+		slog.Int("amount", entry.Amount),
+		slog.String("reason", reason),
+	)
+	return creditEntry(entry, admin.Username), nil
+}
+
+// GetLedger returns the latest journal entries on the account of a user,
+// newest first.
+func (u *userService) GetLedger(ctx context.Context, username string) ([]users.LedgerEntry, error) {
+	user, err := u.authRepo.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return nil, users.ErrorUserNotFound
+		}
+		return nil, users.ErrorService
+	}
+
+	lines, err := u.userRepo.GetLedger(ctx, user.ID, ledgerLimit)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	entries := make([]users.LedgerEntry, 0, len(lines))
+	for _, l := range lines {
+		entries = append(entries, users.LedgerEntry{
+			ID:           l.EntryID,
+			Kind:         l.Kind,
+			Amount:       l.Change,
+			Counterparty: l.Counterparty,
+			Item:         l.ItemType,
+			Quantity:     l.Quantity,
+			RefundOf:     l.ReversesEntryID,
+			Description:  l.Description,
+			CreatedBy:    l.CreatedBy,
+			CreatedAt:    l.CreatedAt,
+		})
+	}
+	return entries, nil
+}
+
+// creditEntry describes an entry the admin made that credits a user from a
+// system account.
+func creditEntry(e *postgres.JournalEntry, admin string) *users.LedgerEntry {
+	return &users.LedgerEntry{
+		ID:           e.ID,
+		Kind:         e.Kind,
+		Amount:       e.Amount,
+		Counterparty: *e.DebitAccount,
+		Item:         e.ItemType,
+		Quantity:     e.Quantity,
+		RefundOf:     e.ReversesEntryID,
+		Description:  e.Description,
+		CreatedBy:    &admin,
+		CreatedAt:    e.CreatedAt,
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	usernameScope = "username"
+	ipScope       = "ip"
+
+	// A username is locked after usernameFailureLimit failed logins and a
+	// client address after ipFailureLimit, counted over failureWindow.
+	usernameFailureLimit = 5
+	ipFailureLimit       = 20
+	failureWindow        = 15 * time.Minute
+
+	// The first lock lasts baseLockDuration and doubles with every further
+	// failure, up to maxLockDuration.
+	baseLockDuration = time.Minute
+	maxLockDuration  = time.Hour
+
+	lockoutEventsLimit = 100
+)
+
+// authFailureKeys returns the counters a login attempt is throttled by.
+func authFailureKeys(req *users.AuthRequest) []postgres.AuthFailureKey {
+	keys := []postgres.AuthFailureKey{usernameKey(req.Username)}
+	if req.ClientIP != "" {
+		keys = append(keys, postgres.AuthFailureKey{Scope: ipScope, Subject: req.ClientIP})
+	}
+	return keys
+}
+
+// usernameKey is case-insensitive, like usernames themselves.
+func usernameKey(username string) postgres.AuthFailureKey {
+	return postgres.AuthFailureKey{Scope: usernameScope, Subject: strings.ToLower(username)}
+}
+
+func failureLimit(scope string) int {
+	if scope == ipScope {
+		return ipFailureLimit
+	}
+	return usernameFailureLimit
+}
+
+// lockDuration grows exponentially with the number of failures beyond the
+// limit.
+func lockDuration(failures, limit int) time.Duration {
+	d := baseLockDuration
+	for i := limit; i < failures && d < maxLockDuration; i++ {
+		d *= 2
+	}
+	return min(d, maxLockDuration)
+}
+
+// checkLockout returns a *users.LockoutError while any of the counters of the
+// login attempt is locked.
+func (u *userService) checkLockout(ctx context.Context, req *users.AuthRequest) error {
+	lockedUntil, err := u.authRepo.GetLockedUntil(ctx, authFailureKeys(req))
+	if err != nil {
+		return users.ErrorService
+	}
+	if lockedUntil == nil {
+		return nil
+	}
+	return &users.LockoutError{RetryAfter: time.Until(*lockedUntil)}
+}
+
+// recordFailure counts a failed login and locks the counters that reached
+// their limit. Every lock is stored as a lockout event.
+func (u *userService) recordFailure(ctx context.Context, req *users.AuthRequest) error {
+	for _, key := range authFailureKeys(req) {
+		failures, err := u.authRepo.RecordAuthFailure(ctx, key, failureWindow)
+		if err != nil {
+			return users.ErrorService
+		}
+
+		limit := failureLimit(key.Scope)
+		if failures < limit {
+			continue
+		}
+
+		until := time.Now().Add(lockDuration(failures, limit))
+		if err := u.authRepo.LockAuth(ctx, key, failures, until); err != nil {
+			return users.ErrorService
+		}
+		slog.Warn("login locked after failed attempts",
+			slog.String("scope", key.Scope),
+			slog.String("subject", key.Subject),
+			slog.Int("failures", failures),
+			slog.Time("until", until),
+		)
+	}
+	return nil
+}
+
+// resetFailures clears the username counter of a successful login. The
+// address counter is left to expire, since anyone can log into an account of
+// their own between guesses at other users' passwords.
+func (u *userService) resetFailures(ctx context.Context, req *users.AuthRequest) error {
+	return u.authRepo.ResetAuthFailures(ctx, usernameKey(req.Username))
+}
+
+// GetLockoutEvents returns the most recent login lockouts, newest first.
+func (u *userService) GetLockoutEvents(ctx context.Context) ([]users.LockoutEvent, error) {
+	events, err := u.authRepo.GetLockoutEvents(ctx, lockoutEventsLimit)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	resp := make([]users.LockoutEvent, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, users.LockoutEvent{
+			Scope:       e.Scope,
+			Subject:     e.Subject,
+			Failures:    e.Failures,
+			LockedUntil: e.LockedUntil,
+			CreatedAt:   e.CreatedAt,
+		})
+	}
+	return resp, nil
+}
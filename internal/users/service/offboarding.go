@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/shop"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+// Offboard closes the account of a departing user on behalf of the admin.
+// Its balance goes to the configured sink account, its username is
+// anonymised, and its password, sessions, API keys and second factor stop
+// working, all at once. The returned summary is the only record that ties the
+// anonymised account to the original username.
+func (u *userService) Offboard(ctx context.Context, admin *users.Principal, req *users.OffboardRequest) (*users.OffboardingSummary, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, users.ErrorReasonRequired
+	}
+	if u.cfg.OffboardingSink == "" {
+		return nil, users.ErrorSinkNotConfigured
+	}
+	if strings.EqualFold(req.Username, u.cfg.OffboardingSink) || strings.EqualFold(req.Username, admin.Username) {
+		return nil, users.ErrorCannotOffboard
+	}
+
+	// Nobody knows this password, so the account cannot be logged into even
+	// if it were reopened.
+	password, _, err := generateOpaqueToken()
+	if err != nil {
+		return nil, users.ErrorService
+	}
+	hash, err := generatePasswordHash(password)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	off := &postgres.Offboarding{
+		Username:     req.Username,
+		SinkUsername: u.cfg.OffboardingSink,
+		AdminID:      admin.UserID,
+		Reason:       reason,
+		Password:     hash,
+	}
+	if err := u.authRepo.OffboardUser(ctx, off); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorUserNotFound):
+			return nil, users.ErrorUserNotFound
+		case errors.Is(err, repository.ErrorAccountClosed):
+			return nil, users.ErrorAccountClosed
+		case errors.Is(err, repository.ErrorSinkNotFound),
+			errors.Is(err, repository.ErrorReceiverClosed):
+			return nil, users.ErrorSinkUnavailable
+		default:
+			return nil, users.ErrorService
+		}
+	}
+
+	slog.Warn("user offboarded",
+		slog.String("admin", admin.Username),
+		slog.String("user", off.AnonymizedUsername),
+		slog.Int("swept_coins", off.SweptCoins),
+		slog.String("sink", off.SinkUsername),
+	)
+
+	inventory := make([]shop.InventoryItem, 0, len(off.Inventory))
+	for _, item := range off.Inventory {
+		inventory = append(inventory, shop.InventoryItem{
+			Type:     item.ItemType,
+			Quantity: item.Quantity,
+		})
+	}
+
+	return &users.OffboardingSummary{
+		UserID:               off.UserID,
+		Username:             off.Username,
+		AnonymizedUsername:   off.AnonymizedUsername,
+		SinkAccount:          off.SinkUsername,
+		SweptCoins:           off.SweptCoins,
+		Inventory:            inventory,
+		SentTransactions:     off.SentTransactions,
+		ReceivedTransactions: off.ReceivedTransactions,
+		SessionsRevoked:      int(off.SessionsRevoked),
+		APIKeysRevoked:       int(off.APIKeysRevoked),
+		Reason:               reason,
+		ClosedBy:             admin.Username,
+		ClosedAt:             off.ClosedAt,
+	}, nil
+}
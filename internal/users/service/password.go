@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	// passwordResetSendTimeout bounds issuing and sending a reset token,
+	// which happens after the request has been answered.
+	passwordResetSendTimeout = 30 * time.Second
+
+	resetUsernameScope = "reset_username"
+	resetIPScope       = "reset_ip"
+
+	// A username can be sent resetUsernameLimit tokens and a client address
+	// can ask for resetIPLimit resets per resetWindow.
+	resetUsernameLimit = 3
+	resetIPLimit       = 10
+	resetWindow        = time.Hour
+)
+
+// ChangePassword replaces the caller's password after checking the old one,
+// which counts as a login attempt. All other sessions of the user are ended.
+func (u *userService) ChangePassword(ctx context.Context, principal *users.Principal, req *users.ChangePasswordRequest) error {
+	if len(req.NewPassword) == 0 {
+		return users.ErrorInvalidPassword
+	}
+
+	attempt := &users.AuthRequest{Username: principal.Username}
+	if err := u.checkLockout(ctx, attempt); err != nil {
+		return err
+	}
+
+	user, err := u.authRepo.GetUser(ctx, principal.Username)
+	if err != nil || user.ID != principal.UserID {
+		return users.ErrorService
+	}
+
+	ok, _, err := verifyPassword(req.OldPassword, user.Password)
+	if err != nil {
+		return users.ErrorService
+	}
+	if !ok {
+		return u.loginFailed(ctx, attempt)
+	}
+
+	hash, err := generatePasswordHash(req.NewPassword)
+	if err != nil {
+		return users.ErrorService
+	}
+
+	if err := u.authRepo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return users.ErrorService
+	}
+
+	if err := u.authRepo.RevokeOtherSessions(ctx, user.ID, principal.SessionID); err != nil {
+		return users.ErrorService
+	}
+	return nil
+}
+
+// RequestPasswordReset sends a single-use reset token to the user through the
+// notifier. Requests are throttled per username and per client address, so
+// that nobody can flood a user with tokens or keep invalidating the one the
+// user is about to use; over the limit a *users.LockoutError is returned.
+// Past the throttle the token is issued in the background, so that neither
+// the response nor its timing reveals which accounts exist.
+func (u *userService) RequestPasswordReset(ctx context.Context, username, clientIP string) error {
+	if err := u.throttlePasswordReset(ctx, username, clientIP); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetSendTimeout)
+	go func() {
+		defer cancel()
+		if err := u.sendPasswordReset(ctx, username); err != nil {
+			slog.Error("failed to send password reset", slog.String("user", username), slog.Any("err", err))
+		}
+	}()
+	return nil
+}
+
+// sendPasswordReset issues a reset token to the user and sends it through the
+// notifier. Unknown usernames are silently ignored.
+func (u *userService) sendPasswordReset(ctx context.Context, username string) error {
+	user, err := u.authRepo.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(passwordResetTTL)
+	err = u.authRepo.CreatePasswordReset(ctx, &postgres.PasswordResetToken{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.notifier.NotifyPasswordReset(ctx, user.Username, token, expiresAt)
+}
+
+// throttlePasswordReset counts a reset request against the username, whether
+// it exists or not, and the client address, and locks those that reached
+// their limit until the window is over.
+func (u *userService) throttlePasswordReset(ctx context.Context, username, clientIP string) error {
+	keys := []postgres.AuthFailureKey{{Scope: resetUsernameScope, Subject: strings.ToLower(username)}}
+	if clientIP != "" {
+		keys = append(keys, postgres.AuthFailureKey{Scope: resetIPScope, Subject: clientIP})
+	}
+
+	lockedUntil, err := u.authRepo.GetLockedUntil(ctx, keys)
+	if err != nil {
+		return users.ErrorService
+	}
+	if lockedUntil != nil {
+		return &users.LockoutError{RetryAfter: time.Until(*lockedUntil)}
+	}
+
+	for _, key := range keys {
+		requests, err := u.authRepo.RecordAuthFailure(ctx, key, resetWindow)
+		if err != nil {
+			return users.ErrorService
+		}
+
+		limit := resetUsernameLimit
+		if key.Scope == resetIPScope {
+			limit = resetIPLimit
+		}
+		if requests < limit {
+			continue
+		}
+
+		until := time.Now().Add(resetWindow)
+		if err := u.authRepo.LockAuth(ctx, key, requests, until); err != nil {
+			return users.ErrorService
+		}
+		slog.Warn("password resets locked after repeated requests",
+			slog.String("scope", key.Scope),
+			slog.String("subject", key.Subject),
+			slog.Int("requests", requests),
+			slog.Time("until", until),
+		)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token. The token is used
+// up, all sessions of the user are ended and the failed login counter of the
+// username is cleared.
+func (u *userService) ResetPassword(ctx context.Context, req *users.ResetPasswordRequest) error {
+	if len(req.NewPassword) == 0 {
+		return users.ErrorInvalidPassword
+	}
+
+	hash, err := generatePasswordHash(req.NewPassword)
+	if err != nil {
+		return users.ErrorService
+	}
+
+	user, err := u.authRepo.ResetPassword(ctx, hashOpaqueToken(req.Token), hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrorPasswordResetNotFound) {
+			return users.ErrorInvalidResetToken
+		}
+		return users.ErrorService
+	}
+
+	if err := u.authRepo.ResetAuthFailures(ctx, usernameKey(user.Username)); err != nil {
+		slog.Warn("failed to reset login failures", slog.String("user", user.Username), slog.Any("err", err))
+	}
+	return nil
+}
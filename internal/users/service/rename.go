@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+// ChangeUsername renames the caller. A user can be renamed once per
+// UsernameCooldown, and the old name stays reserved for the owner for
+// UsernameGracePeriod, during which coins sent to it reach the new one.
+// Access tokens carry the username, so those issued before the rename are
+// rejected and the client has to refresh them. Usernames are matched
+// regardless of case, so changing only the case of the name does nothing.
+func (u *userService) ChangeUsername(ctx context.Context, principal *users.Principal, username string) error {
+	if err := validateUsername(username); err != nil {
+		return err
+	}
+	if strings.EqualFold(username, principal.Username) {
+		return nil
+	}
+
+	last, err := u.authRepo.GetLastUsernameChange(ctx, principal.UserID)
+	if err != nil {
+		return users.ErrorService
+	}
+	now := time.Now()
+	if last != nil {
+		if next := last.ChangedAt.Add(u.cfg.UsernameCooldown); next.After(now) {
+			return &users.RenameCooldownError{RetryAfter: next.Sub(now)}
+		}
+	}
+
+	change, err := u.authRepo.RenameUser(ctx, principal.UserID, username,
+		now.Add(-u.cfg.UsernameCooldown), now.Add(-u.cfg.UsernameGracePeriod))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorRenameCooldown):
+			return &users.RenameCooldownError{RetryAfter: u.cfg.UsernameCooldown}
+		case errors.Is(err, repository.ErrorUserAlreadyExists):
+			return users.ErrorUsernameTaken
+		case errors.Is(err, repository.ErrorUserNotFound):
+			return users.ErrorUserNotFound
+		default:
+			return users.ErrorService
+		}
+	}
+
+	slog.Info("username changed",
+		slog.String("user_id", change.UserID),
+		slog.String("from", change.Username),
+		slog.String("to", change.CurrentUsername),
+	)
+	return nil
+}
+
+// renamedUser returns the current username of the user who gave up username
+// within the grace period, or ErrorUserNotFound.
+func (u *userService) renamedUser(ctx context.Context, username string) (string, error) {
+	change, err := u.authRepo.GetRenamedUser(ctx, username, time.Now().Add(-u.cfg.UsernameGracePeriod))
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return "", users.ErrorUserNotFound
+		}
+		return "", users.ErrorService
+	}
+	return change.CurrentUsername, nil
+}
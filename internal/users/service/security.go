@@ -1,65 +1,154 @@
 package service
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/go-faster/errors"
-
-	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/argon2"
 )
 
+const salt = "kqwemjksdnfhaksrmksvj283njwksdf"
+
+// argon2id parameters for newly hashed passwords. They are stored in the
+// encoded hash, so changing them only affects hashes created afterwards and
+// older hashes are upgraded on the next successful login.
 const (
-	salt       = "kqwemjksdnfhaksrmksvj283njwksdf"
-	signingKey = "821nci1nc1234ubcz,mszd2jcv1wd23"
-	tokenTTL   = time.Minute * 1000
+	argonMemory  uint32 = 19 * 1024
+	argonTime    uint32 = 2
+	argonThreads uint8  = 1
+	argonSaltLen        = 16
+	argonKeyLen  uint32 = 32
+
+	argonPrefix = "$argon2id$"
 )
 
-type tokenClaims struct {
-	jwt.RegisteredClaims
-	Username string `json:"username"`
+var errMalformedHash = errors.New("malformed password hash")
+
+// dummyPasswordHash is checked against when a login names an unknown user,
+// so that the answer takes as long as for a wrong password and does not
+// tell whether the account exists.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := generatePasswordHash("")
+	return hash
+})
+
+type argonParams struct {
+	memory  uint32
+	time    uint32
+	threads uint8
 }
 
-func generatePasswordHash(password string) string {
-	hash := sha256.New()
-	hash.Write([]byte(password))
-	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+// generatePasswordHash returns an encoded argon2id hash of the password in
+// the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$hash.
+func generatePasswordHash(password string) (string, error) {
+	saltBytes := make([]byte, argonSaltLen)
+	if _, err := rand.Read(saltBytes); err != nil {
+		return "", errors.Wrap(err, "generate salt")
+	}
+
+	key := argon2.IDKey([]byte(password), saltBytes, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argonPrefix,
+		argon2.Version,
+		argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(saltBytes),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-func GenerateToken(username string) (string, error) {
-	claims := &tokenClaims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// verifyPassword checks the password against a stored hash. needsRehash is
+// true when the password matched but the hash uses a legacy algorithm or
+// outdated argon2id parameters.
+func verifyPassword(password, encoded string) (ok bool, needsRehash bool, err error) {
+	if !strings.HasPrefix(encoded, argonPrefix) {
+		expected := legacyPasswordHash(password)
+		ok = subtle.ConstantTimeCompare([]byte(expected), []byte(encoded)) == 1
+		return ok, ok, nil
+	}
+
+	params, saltBytes, key, err := decodePasswordHash(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), saltBytes, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return false, false, nil
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	needsRehash = params.memory != argonMemory ||
+		params.time != argonTime ||
+		params.threads != argonThreads ||
+		uint32(len(key)) != argonKeyLen
 
-	return token.SignedString([]byte(signingKey))
+	return true, needsRehash, nil
 }
 
-func ParseToken(accessToken string) (string, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(signingKey), nil
-	})
+func decodePasswordHash(encoded string) (argonParams, []byte, []byte, error) {
+	var params argonParams
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, errors.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+
+	saltBytes, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return "", err
+		return params, nil, nil, errMalformedHash
 	}
 
-	claims, ok := token.Claims.(*tokenClaims)
-	if !ok || claims == nil {
-		return "", errors.New("token claims are not of type *tokenClaims")
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errMalformedHash
 	}
 
-	if claims.ExpiresAt.Before(time.Now()) {
-		return "", errors.New("token expired")
+	return params, saltBytes, key, nil
+}
+
+// legacyPasswordHash reproduces the salted SHA-256 hash used before argon2id.
+// It is only used to verify passwords that have not been upgraded yet.
+func legacyPasswordHash(password string) string {
+	hash := sha256.New()
+	hash.Write([]byte(password))
+	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+}
+
+const opaqueTokenLen = 32
+
+// generateOpaqueToken returns a random opaque token, used for refresh and
+// password reset tokens, and the hash under which it is stored. The token
+// itself is never persisted.
+func generateOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, opaqueTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errors.Wrap(err, "generate token")
 	}
 
-	return claims.Username, nil
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashOpaqueToken(token), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%x", sum)
 }
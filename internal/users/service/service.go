@@ -2,67 +2,451 @@ package service
 
 import (
 	"context"
-	"fmt"
-	"github.com/kingxl111/merch-store/internal/shop"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/go-faster/errors"
+	"github.com/kingxl111/merch-store/internal/idempotency"
 	"github.com/kingxl111/merch-store/internal/repository"
 	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/shop"
 	"github.com/kingxl111/merch-store/internal/users"
 )
 
+// Config holds the tunables of the users service.
+type Config struct {
+	RefreshTokenTTL time.Duration
+	// AutoRegister creates an account on the first login with an unknown
+	// username, as /api/auth did before /api/register existed.
+	AutoRegister bool
+	// OffboardingSink is the username of the account that receives the
+	// remaining coins of offboarded users, e.g. a company pool.
+	OffboardingSink string
+	// UsernameCooldown is the time a user has to wait between renames.
+	UsernameCooldown time.Duration
+	// UsernameGracePeriod is how long a username given up in a rename stays
+	// reserved for its former owner.
+	UsernameGracePeriod time.Duration
+	// RouteRenamedTransfers sends coins addressed to a username given up
+	// within the grace period to its former owner. Otherwise such transfers
+	// fail with a *users.UserRenamedError naming the new username.
+	RouteRenamedTransfers bool
+	// TransferHoldWindow is how long the coins of a delayed transfer are
+	// held before it is settled; until then the sender can cancel it.
+	TransferHoldWindow time.Duration
+}
+
 type userService struct {
 	userRepo UserRepository
 	authRepo AuthRepository
+	tokens   TokenManager
+	notifier Notifier
+	cfg      Config
 }
 
-func NewUserService(usrRepo UserRepository, authRepo AuthRepository) *userService {
+func NewUserService(usrRepo UserRepository, authRepo AuthRepository, tokens TokenManager, notifier Notifier, cfg Config) *userService {
 	return &userService{
 		userRepo: usrRepo,
 		authRepo: authRepo,
+		tokens:   tokens,
+		notifier: notifier,
+		cfg:      cfg,
 	}
 }
 
+// Authenticate logs the user in, or returns a challenge token when the user
+// has two-factor authentication enabled. Failed attempts are counted per username
+// and per client address, and once either reaches its limit further attempts
+// fail with a *users.LockoutError until the lock expires.
 func (u *userService) Authenticate(ctx context.Context, req *users.AuthRequest) (*users.AuthResponse, error) {
-	var resp users.AuthResponse
-	usrRepo := postgres.User{
-		Username: req.Username,
-		Password: req.Password,
+	if err := u.checkLockout(ctx, req); err != nil {
+		return nil, err
+	}
+
+	user, err := u.authRepo.GetUser(ctx, req.Username)
+	switch {
+	case errors.Is(err, repository.ErrorUserNotFound):
+		if !u.cfg.AutoRegister {
+			_, _, _ = verifyPassword(req.Password, dummyPasswordHash())
+			return nil, u.loginFailed(ctx, req)
+		}
+		user, err = u.createUser(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, users.ErrorService
+	default:
+		if err := u.checkPassword(ctx, user, req.Password); err != nil {
+			if errors.Is(err, users.ErrorWrongPassword) {
+				return nil, u.loginFailed(ctx, req)
+			}
+			return nil, err
+		}
+
+		enabled, err := u.twoFactorEnabled(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			// The failure counters are reset once the second factor is
+			// passed as well, so that codes are throttled like passwords.
+			return u.challenge(ctx, user)
+		}
 	}
-	usrRepo.Password = generatePasswordHash(usrRepo.Password)
 
-	err := u.authRepo.AuthUser(ctx, &usrRepo)
+	if err := u.resetFailures(ctx, req); err != nil {
+		slog.Warn("failed to reset login failures", slog.String("user", req.Username), slog.Any("err", err))
+	}
+
+	return u.startSession(ctx, user, req)
+}
+
+func (u *userService) loginFailed(ctx context.Context, req *users.AuthRequest) error {
+	if err := u.recordFailure(ctx, req); err != nil {
+		return err
+	}
+	return users.ErrorWrongPassword
+}
+
+// Register creates a new account and logs it in.
+func (u *userService) Register(ctx context.Context, req *users.AuthRequest) (*users.AuthResponse, error) {
+	if err := validateUsername(req.Username); err != nil {
+		return nil, err
+	}
+	if len(req.Password) == 0 {
+		return nil, users.ErrorInvalidPassword
+	}
+
+	user, err := u.createUser(ctx, req)
 	if err != nil {
-		if errors.Is(err, repository.ErrorInsertUser) {
+		return nil, err
+	}
+
+	return u.startSession(ctx, user, req)
+}
+
+// createUser only checks that the username is free, whatever its case.
+// Logins that register an unknown username accept the same usernames and
+// passwords as before /api/register existed, so the rules for new usernames
+// are left to Register.
+func (u *userService) createUser(ctx context.Context, req *users.AuthRequest) (*postgres.User, error) {
+	if _, err := u.renamedUser(ctx, req.Username); err == nil {
+		return nil, users.ErrorUsernameTaken
+	} else if !errors.Is(err, users.ErrorUserNotFound) {
+		return nil, err
+	}
+
+	hash, err := generatePasswordHash(req.Password)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	user := &postgres.User{
+		Username: req.Username,
+		Password: hash,
+	}
+	err = u.authRepo.CreateUser(ctx, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorUserAlreadyExists):
+			return nil, users.ErrorUsernameTaken
+		case errors.Is(err, repository.ErrorInsertUser):
 			return nil, users.ErrorCreateUser
+		default:
+			return nil, users.ErrorService
 		}
-		if errors.Is(err, repository.ErrorUserPasswordCombine) {
-			return nil, users.ErrorWrongPassword
-		}
+	}
+	return user, nil
+}
+
+// startSession opens a new session for the user, logged in from the client
+// of req, and issues its first pair of access and refresh tokens. Suspended
+// and closed accounts cannot log in.
+func (u *userService) startSession(ctx context.Context, user *postgres.User, req *users.AuthRequest) (*users.AuthResponse, error) {
+	if err := checkCanLogIn(user.Status); err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := generateOpaqueToken()
+	if err != nil {
+		return nil, users.ErrorGenerateToken
+	}
+
+	expiresAt := time.Now().Add(u.cfg.RefreshTokenTTL)
+	session := &postgres.Session{
+		UserID:    user.ID,
+		UserAgent: req.UserAgent,
+		IP:        req.ClientIP,
+		ExpiresAt: expiresAt,
+	}
+	err = u.authRepo.CreateSession(ctx, session, &postgres.RefreshToken{
+		TokenHash: refreshHash,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
 		return nil, users.ErrorService
 	}
 
-	token, err := GenerateToken(req.Username)
+	return u.issueTokens(&users.Principal{
+		UserID:    user.ID,
+		Username:  user.Username,
+		SessionID: session.ID,
+		Roles:     []string{user.Role},
+	}, refreshToken)
+}
+
+func (u *userService) issueTokens(principal *users.Principal, refreshToken string) (*users.AuthResponse, error) {
+	token, err := u.tokens.GenerateToken(principal)
+	if err != nil {
+		return nil, users.ErrorGenerateToken
+	}
+
+	return &users.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(u.tokens.TokenTTL().Seconds()),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Every refresh token can be used only once.
+func (u *userService) Refresh(ctx context.Context, req *users.RefreshRequest) (*users.AuthResponse, error) {
+	refreshToken, refreshHash, err := generateOpaqueToken()
 	if err != nil {
 		return nil, users.ErrorGenerateToken
 	}
-	resp.Token = token
-	return &resp, nil
+
+	session, err := u.authRepo.RotateRefreshToken(ctx, hashOpaqueToken(req.RefreshToken), &postgres.RefreshToken{
+		TokenHash: refreshHash,
+		ExpiresAt: time.Now().Add(u.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorRefreshTokenNotFound):
+			return nil, users.ErrorInvalidRefreshToken
+		case errors.Is(err, repository.ErrorRefreshTokenReused):
+			slog.Warn("refresh token reused, session revoked")
+			return nil, users.ErrorInvalidRefreshToken
+		default:
+			return nil, users.ErrorService
+		}
+	}
+
+	return u.issueTokens(&users.Principal{
+		UserID:    session.UserID,
+		Username:  session.Username,
+		SessionID: session.ID,
+		Roles:     []string{session.Role},
+	}, refreshToken)
+}
+
+// Logout ends the session the principal's token belongs to. Access tokens of
+// an ended session are rejected by VerifyAccessToken even before they expire.
+func (u *userService) Logout(ctx context.Context, principal *users.Principal) error {
+	if err := u.authRepo.RevokeSession(ctx, principal.SessionID); err != nil {
+		return users.ErrorService
+	}
+	return nil
 }
 
-func (u *userService) TransferCoins(ctx context.Context, req *users.CoinTransfer) error {
-	if req.Amount < 0 {
+// VerifyAccessToken checks the token signature and expiry and that its
+// session has not been ended, and returns the principal with the current
+// role of the user.
+func (u *userService) VerifyAccessToken(ctx context.Context, accessToken string) (*users.Principal, error) {
+	principal, err := u.tokens.ParseToken(accessToken)
+	if err != nil {
+		return nil, users.ErrorInvalidToken
+	}
+
+	session, err := u.authRepo.GetSession(ctx, principal.SessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrorSessionNotFound) {
+			return nil, users.ErrorSessionEnded
+		}
+		return nil, users.ErrorService
+	}
+
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return nil, users.ErrorSessionEnded
+	}
+
+	// Impersonation tokens belong to the admin's session and stop working
+	// as soon as the admin logs out or loses the admin role.
+	owner := principal.UserID
+	if imp := principal.Impersonation; imp != nil {
+		owner = imp.AdminID
+		if session.Role != users.RoleAdmin {
+			return nil, users.ErrorSessionEnded
+		}
+	}
+	if session.UserID != owner {
+		return nil, users.ErrorSessionEnded
+	}
+
+	// Tokens issued before a rename name the previous username.
+	username := principal.Username
+	if imp := principal.Impersonation; imp != nil {
+		username = imp.AdminUsername
+	}
+	if session.Username != username {
+		return nil, users.ErrorInvalidToken
+	}
+
+	// The roles in the token are not trusted: a token carries the role the
+	// user has now. Impersonated users are never admins.
+	if principal.Impersonation != nil {
+		principal.Roles = []string{users.RoleUser}
+	} else {
+		principal.Roles = []string{session.Role}
+	}
+
+	return principal, nil
+}
+
+// checkPassword verifies the password and, when the stored hash is in a
+// legacy format or uses outdated parameters, replaces it with a fresh
+// argon2id hash. A failed upgrade does not fail the login.
+func (u *userService) checkPassword(ctx context.Context, user *postgres.User, password string) error {
+	ok, needsRehash, err := verifyPassword(password, user.Password)
+	if err != nil {
+		return users.ErrorService
+	}
+	if !ok {
+		return users.ErrorWrongPassword
+	}
+	if !needsRehash {
+		return nil
+	}
+
+	hash, err := generatePasswordHash(password)
+	if err == nil {
+		err = u.authRepo.UpdatePassword(ctx, user.ID, hash)
+	}
+	if err != nil {
+		slog.Warn("failed to upgrade password hash", slog.String("user", user.Username), slog.Any("err", err))
+	}
+	return nil
+}
+
+// SetRole changes the role of a user. Access tokens are checked against the
+// current role, so the change takes effect on the next request; a demotion
+// also ends all of the user's sessions.
+func (u *userService) SetRole(ctx context.Context, req *users.SetRoleRequest) error {
+	if req.Role != users.RoleUser && req.Role != users.RoleAdmin {
+		return users.ErrorInvalidRole
+	}
+
+	user, err := u.authRepo.SetUserRole(ctx, req.Username, req.Role)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return users.ErrorUserNotFound
+		}
+		return users.ErrorService
+	}
+
+	if req.Role != users.RoleAdmin {
+		if err := u.authRepo.RevokeUserSessions(ctx, user.ID); err != nil {
+			return users.ErrorService
+		}
+	}
+	return nil
+}
+
+// EnsureAdmins grants the admin role to the listed users so that the first
+// administrators can be set up without database access. Unknown usernames
+// are skipped.
+func (u *userService) EnsureAdmins(ctx context.Context, usernames []string) error {
+	for _, username := range usernames {
+		_, err := u.authRepo.SetUserRole(ctx, username, users.RoleAdmin)
+		if err != nil {
+			if errors.Is(err, repository.ErrorUserNotFound) {
+				slog.Warn("admin user not found", slog.String("user", username))
+				continue
+			}
+			return errors.Wrapf(err, "grant admin role to %s", username)
+		}
+	}
+	return nil
+}
+
+// TransferCoins sends coins to another user. Coins sent to a username given
+// up within the grace period go to its former owner, or fail with a
+// *users.UserRenamedError, depending on RouteRenamedTransfers. A delayed
+// transfer only holds the coins for TransferHoldWindow, see the escrow
+// package. With a non-nil rec the transfer is made only once per idempotency
+// key; a retry gets the first response in rec.
+func (u *userService) TransferCoins(ctx context.Context, req *users.CoinTransfer, rec *idempotency.Record) error {
+	if req.Amount <= 0 {
 		return users.ErrorInvalidAmount
 	}
-	err := u.userRepo.TransferCoins(ctx, req.FromUser, req.ToUser, req.Amount)
+	if strings.EqualFold(req.FromUser, req.ToUser) {
+		return users.ErrorSelfTransfer
+	}
+	message, ok := users.TransferNote(req.Message, users.MaxTransferMessageLen)
+	if !ok {
+		return users.ErrorInvalidMessage
+	}
+	category, ok := users.TransferNote(req.Category, users.MaxTransferCategoryLen)
+	if !ok {
+		return users.ErrorInvalidCategory
+	}
+
+	transfer := &postgres.CoinTransaction{
+		FromUsername: req.FromUser,
+		ToUsername:   req.ToUser,
+		Amount:       req.Amount,
+		Message:      message,
+		Category:     category,
+	}
+	idem := idempotency.StoredKey(rec)
+	send := func() error {
+		if !req.Delayed {
+			return u.userRepo.TransferCoins(ctx, transfer, idem)
+		}
+		return u.userRepo.HoldCoins(ctx, &postgres.PendingTransfer{
+			FromUsername: transfer.FromUsername,
+			ToUsername:   transfer.ToUsername,
+			Amount:       transfer.Amount,
+			Message:      transfer.Message,
+			Category:     transfer.Category,
+			SettlesAt:    time.Now().Add(u.cfg.TransferHoldWindow),
+		}, idem)
+	}
+	err := send()
+	if errors.Is(err, repository.ErrorReceiverNotFound) {
+		renamed, lookupErr := u.renamedUser(ctx, req.ToUser)
+		switch {
+		case errors.Is(lookupErr, users.ErrorUserNotFound):
+			return users.ErrorReceiverNotFound
+		case lookupErr != nil:
+			return lookupErr
+		case !u.cfg.RouteRenamedTransfers:
+			return &users.UserRenamedError{Username: renamed}
+		case strings.EqualFold(renamed, req.FromUser):
+			return users.ErrorSelfTransfer
+		}
+		transfer.ToUsername = renamed
+		err = send()
+	}
 	if err != nil {
-		fmt.Println(err)
 		if errors.Is(err, repository.ErrorInsFunds) {
 			return users.ErrorInsufFunds
 		}
+		if errors.Is(err, repository.ErrorIdempotencyKeyReused) {
+			return idempotency.ErrKeyReused
+		}
+		if errors.Is(err, repository.ErrorReceiverNotFound) {
+			return users.ErrorReceiverNotFound
+		}
+		if statusErr := accountError(err); statusErr != nil {
+			return statusErr
+		}
 		return users.ErrorService
 	}
 
+	idempotency.Replay(rec, idem)
 	return nil
 }
 
@@ -72,34 +456,36 @@ func (u *userService) GetUserInfo(ctx context.Context, username string) (*users.
 		return nil, users.ErrorService
 	}
 
+	held, err := u.userRepo.GetHeldCoins(ctx, username)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
 	inventory, err := u.userRepo.GetInventory(ctx, username)
 	if err != nil {
 		return nil, users.ErrorService
 	}
-	fmt.Println(inventory)
 
 	transactions, err := u.userRepo.GetTransactionHistory(ctx, username)
 	if err != nil {
 		return nil, users.ErrorService
 	}
-	fmt.Println(transactions)
 
 	var receivedHistory []users.CoinTransfer
 	var sentHistory []users.CoinTransfer
 
 	for _, tx := range transactions {
-		if tx.ToUserID == username {
-			receivedHistory = append(receivedHistory, users.CoinTransfer{
-				FromUser: tx.FromUserID,
-				ToUser:   tx.ToUserID,
-				Amount:   tx.Amount,
-			})
-		} else if tx.FromUserID == username {
-			sentHistory = append(sentHistory, users.CoinTransfer{
-				FromUser: tx.FromUserID,
-				ToUser:   tx.ToUserID,
-				Amount:   tx.Amount,
-			})
+		transfer := users.CoinTransfer{
+			FromUser: tx.FromUsername,
+			ToUser:   tx.ToUsername,
+			Amount:   tx.Amount,
+			Message:  tx.Message,
+			Category: tx.Category,
+		}
+		if strings.EqualFold(tx.ToUsername, username) {
+			receivedHistory = append(receivedHistory, transfer)
+		} else if strings.EqualFold(tx.FromUsername, username) {
+			sentHistory = append(sentHistory, transfer)
 		}
 	}
 	userInventory := make([]shop.InventoryItem, 0)
@@ -111,11 +497,18 @@ func (u *userService) GetUserInfo(ctx context.Context, username string) (*users.
 		userInventory = append(userInventory, item)
 	}
 
+	impersonations, err := u.impersonationRecords(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &users.UserInfoResponse{
 		Coins:           *balance,
+		HeldCoins:       held,
 		Inventory:       userInventory,
 		ReceivedHistory: receivedHistory,
 		SentHistory:     sentHistory,
+		Impersonations:  impersonations,
 	}
 
 	return resp, nil
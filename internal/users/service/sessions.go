@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+// ListSessions returns the active sessions of the caller.
+func (u *userService) ListSessions(ctx context.Context, principal *users.Principal) ([]users.SessionInfo, error) {
+	sessions, err := u.authRepo.ListUserSessions(ctx, principal.UserID)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	resp := make([]users.SessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, users.SessionInfo{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == principal.SessionID,
+		})
+	}
+	return resp, nil
+}
+
+// EndSession ends one of the caller's sessions, which may be the current one.
+func (u *userService) EndSession(ctx context.Context, principal *users.Principal, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return users.ErrorSessionNotFound
+	}
+
+	if err := u.authRepo.RevokeUserSession(ctx, principal.UserID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrorSessionNotFound) {
+			return users.ErrorSessionNotFound
+		}
+		return users.ErrorService
+	}
+	return nil
+}
+
+// EndOtherSessions ends all sessions of the caller except the current one.
+func (u *userService) EndOtherSessions(ctx context.Context, principal *users.Principal) error {
+	if err := u.authRepo.RevokeOtherSessions(ctx, principal.UserID, principal.SessionID); err != nil {
+		return users.ErrorService
+	}
+	return nil
+}
+
+// TouchSession records that the principal's session has been used by the
+// given client with the principal's access token.
+func (u *userService) TouchSession(ctx context.Context, principal *users.Principal, userAgent, clientIP string) error {
+	if err := u.authRepo.TouchSession(ctx, principal.SessionID, principal.TokenID, userAgent, clientIP); err != nil {
+		return users.ErrorService
+	}
+	return nil
+}
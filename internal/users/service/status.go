@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go-faster/errors"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const statusHistoryLimit = 50
+
+// checkCanLogIn returns the error matching the status if it keeps the user
+// from logging in.
+func checkCanLogIn(status string) error {
+	switch status {
+	case users.StatusSuspended:
+		return users.ErrorAccountSuspended
+	case users.StatusClosed:
+		return users.ErrorAccountClosed
+	default:
+		return nil
+	}
+}
+
+// accountError translates the errors the repository returns when the status
+// of an account does not allow an operation. Other errors yield nil.
+func accountError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrorAccountFrozen):
+		return users.ErrorAccountFrozen
+	case errors.Is(err, repository.ErrorAccountSuspended):
+		return users.ErrorAccountSuspended
+	case errors.Is(err, repository.ErrorAccountClosed):
+		return users.ErrorAccountClosed
+	case errors.Is(err, repository.ErrorReceiverClosed):
+		return users.ErrorReceiverClosed
+	default:
+		return nil
+	}
+}
+
+// SetStatus changes the status of a user on behalf of the admin. Suspending
+// or closing an account ends all of its sessions along with the change, so
+// that it is locked out immediately rather than once its access tokens
+// expire.
+func (u *userService) SetStatus(ctx context.Context, admin *users.Principal, req *users.SetStatusRequest) error {
+	switch req.Status {
+	case users.StatusActive, users.StatusFrozen, users.StatusSuspended, users.StatusClosed:
+	default:
+		return users.ErrorInvalidStatus
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return users.ErrorReasonRequired
+	}
+
+	user, err := u.authRepo.SetUserStatus(ctx, req.Username, admin.UserID, req.Status, reason)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return users.ErrorUserNotFound
+		}
+		return users.ErrorService
+	}
+
+	slog.Warn("account status changed",
+		slog.String("admin", admin.Username),
+		slog.String("user", user.Username),
+		slog.String("status", req.Status),
+		slog.String("reason", reason),
+	)
+	return nil
+}
+
+// GetStatus returns the status of a user and its latest changes.
+func (u *userService) GetStatus(ctx context.Context, username string) (*users.AccountStatus, error) {
+	user, err := u.authRepo.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return nil, users.ErrorUserNotFound
+		}
+		return nil, users.ErrorService
+	}
+
+	events, err := u.authRepo.GetUserStatusEvents(ctx, user.ID, statusHistoryLimit)
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	status := &users.AccountStatus{
+		Status:  user.Status,
+		History: make([]users.StatusChange, 0, len(events)),
+	}
+	for _, e := range events {
+		change := users.StatusChange{
+			Status:    e.Status,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt,
+		}
+		if e.AdminUsername != nil {
+			change.Admin = *e.AdminUsername
+		}
+		status.History = append(status.History, change)
+	}
+	return status, nil
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/config"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+var errKeyMismatch = errors.New("token algorithm does not match signing key")
+
+// challengePurpose marks the tokens handed out after a correct password to
+// users with two-factor authentication. They are exchanged for access tokens
+// and are never accepted as access tokens themselves.
+const challengePurpose = "2fa"
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Username  string   `json:"username"`
+	SessionID string   `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Purpose   string   `json:"purpose,omitempty"`
+	// Actor is set on impersonation tokens and identifies the admin acting
+	// as the subject (RFC 8693). The session is the admin's.
+	Actor *actorClaims `json:"act,omitempty"`
+}
+
+type actorClaims struct {
+	Subject         string `json:"sub"`
+	Username        string `json:"username"`
+	ImpersonationID string `json:"imp"`
+	ReadOnly        bool   `json:"ro,omitempty"`
+}
+
+type ringKey struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+type tokenManager struct {
+	keys     map[string]ringKey
+	activeID string
+	ttl      time.Duration
+}
+
+// NewTokenManager builds a key ring from keys. Each key is identified by the
+// kid header of the tokens it signs; HMAC secrets sign with HS256, Ed25519
+// keys with EdDSA and RSA keys with RS256. New tokens are signed with the
+// key named activeKeyID, while tokens signed with any other key of the ring
+// are accepted until they expire.
+func NewTokenManager(keys []config.SigningKey, activeKeyID string, ttl time.Duration) (*tokenManager, error) {
+	ring := make(map[string]ringKey, len(keys))
+	for _, key := range keys {
+		rk, err := newRingKey(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "signing key %q", key.ID)
+		}
+		ring[key.ID] = rk
+	}
+
+	if _, ok := ring[activeKeyID]; !ok {
+		return nil, errors.Errorf("active signing key %q not found", activeKeyID)
+	}
+
+	return &tokenManager{
+		keys:     ring,
+		activeID: activeKeyID,
+		ttl:      ttl,
+	}, nil
+}
+
+func newRingKey(key config.SigningKey) (ringKey, error) {
+	switch {
+	case key.PrivateKey != nil && len(key.Secret) != 0:
+		return ringKey{}, errors.New("both secret and private key are set")
+	case len(key.Secret) != 0:
+		return ringKey{method: jwt.SigningMethodHS256, signKey: key.Secret, verifyKey: key.Secret}, nil
+	}
+
+	switch pk := key.PrivateKey.(type) {
+	case ed25519.PrivateKey:
+		return ringKey{method: jwt.SigningMethodEdDSA, signKey: pk, verifyKey: pk.Public()}, nil
+	case *rsa.PrivateKey:
+		if pk.N.BitLen() < 2048 {
+			return ringKey{}, errors.New("rsa key must be at least 2048 bits")
+		}
+		return ringKey{method: jwt.SigningMethodRS256, signKey: pk, verifyKey: &pk.PublicKey}, nil
+	case nil:
+		return ringKey{}, errors.New("key is empty")
+	default:
+		return ringKey{}, errors.Errorf("unsupported private key type %T", pk)
+	}
+}
+
+// GenerateToken issues an access token for the principal's session. A fresh
+// token id is generated for every token and stored in principal.TokenID.
+func (m *tokenManager) GenerateToken(principal *users.Principal) (string, error) {
+	return m.generate(principal, m.ttl)
+}
+
+// GenerateImpersonationToken issues an access token valid for ttl that lets
+// principal.Impersonation.AdminID act as the principal's user.
+func (m *tokenManager) GenerateImpersonationToken(principal *users.Principal, ttl time.Duration) (string, error) {
+	if principal.Impersonation == nil {
+		return "", errors.New("principal is not impersonated")
+	}
+	return m.generate(principal, ttl)
+}
+
+func (m *tokenManager) generate(principal *users.Principal, ttl time.Duration) (string, error) {
+	principal.TokenID = uuid.NewString()
+
+	claims := &tokenClaims{
+		Username:  principal.Username,
+		SessionID: principal.SessionID,
+		Roles:     principal.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        principal.TokenID,
+			Subject:   principal.UserID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if imp := principal.Impersonation; imp != nil {
+		claims.Actor = &actorClaims{
+			Subject:         imp.AdminID,
+			Username:        imp.AdminUsername,
+			ImpersonationID: imp.ID,
+			ReadOnly:        imp.ReadOnly,
+		}
+	}
+
+	return m.sign(claims)
+}
+
+// GenerateChallengeToken issues a token that proves the user has entered the
+// right password and is valid for ttl. The challenge id becomes the token id,
+// so that the caller can make the token single-use.
+func (m *tokenManager) GenerateChallengeToken(challengeID, userID, username string, ttl time.Duration) (string, error) {
+	claims := &tokenClaims{
+		Username: username,
+		Purpose:  challengePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID,
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return m.sign(claims)
+}
+
+func (m *tokenManager) sign(claims *tokenClaims) (string, error) {
+	key := m.keys[m.activeID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = m.activeID
+
+	return token.SignedString(key.signKey)
+}
+
+func (m *tokenManager) TokenTTL() time.Duration {
+	return m.ttl
+}
+
+func (m *tokenManager) ParseToken(accessToken string) (*users.Principal, error) {
+	claims, err := m.parse(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, errors.New("token is not an access token")
+	}
+
+	// Tokens issued before sessions were introduced cannot be revoked and are
+	// no longer accepted.
+	if claims.SessionID == "" || claims.Subject == "" {
+		return nil, errors.New("token has no session")
+	}
+
+	principal := &users.Principal{
+		UserID:    claims.Subject,
+		Username:  claims.Username,
+		SessionID: claims.SessionID,
+		TokenID:   claims.ID,
+		Roles:     claims.Roles,
+	}
+	if act := claims.Actor; act != nil {
+		if act.Subject == "" || act.ImpersonationID == "" {
+			return nil, errors.New("token has an incomplete actor")
+		}
+		principal.Impersonation = &users.Impersonation{
+			ID:            act.ImpersonationID,
+			AdminID:       act.Subject,
+			AdminUsername: act.Username,
+			ReadOnly:      act.ReadOnly,
+		}
+	}
+	return principal, nil
+}
+
+// ParseChallengeToken validates a token issued by GenerateChallengeToken and
+// returns its challenge id and the user it was issued to.
+func (m *tokenManager) ParseChallengeToken(challengeToken string) (challengeID, userID, username string, err error) {
+	claims, err := m.parse(challengeToken)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if claims.Purpose != challengePurpose || claims.Subject == "" || claims.ID == "" {
+		return "", "", "", errors.New("token is not a challenge token")
+	}
+	return claims.ID, claims.Subject, claims.Username, nil
+}
+
+// parse checks the signature and expiry of a token signed by the ring.
+func (m *tokenManager) parse(accessToken string) (*tokenClaims, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{
+		jwt.SigningMethodHS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+	}))
+
+	var claims *tokenClaims
+	for _, keyID := range m.candidateKeys(accessToken) {
+		key := m.keys[keyID]
+		token, err := parser.ParseWithClaims(accessToken, &tokenClaims{}, func(t *jwt.Token) (interface{}, error) {
+			// The algorithm is bound to the key, so an HMAC token can never be
+			// checked against a public key or the other way round.
+			if t.Method.Alg() != key.method.Alg() {
+				return nil, errKeyMismatch
+			}
+			return key.verifyKey, nil
+		})
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenSignatureInvalid) || errors.Is(err, errKeyMismatch) {
+				continue
+			}
+			return nil, err
+		}
+
+		var ok bool
+		claims, ok = token.Claims.(*tokenClaims)
+		if !ok || claims == nil {
+			return nil, errors.New("token claims are not of type *tokenClaims")
+		}
+		break
+	}
+	if claims == nil {
+		return nil, errors.New("token signature is invalid")
+	}
+
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("token expired")
+	}
+
+	return claims, nil
+}
+
+// candidateKeys returns the ids of the keys the token may be signed with.
+// Tokens issued before key ids were introduced carry no kid header and are
+// checked against the whole ring.
+func (m *tokenManager) candidateKeys(accessToken string) []string {
+	token, _, err := jwt.NewParser().ParseUnverified(accessToken, &tokenClaims{})
+	if err == nil {
+		if keyID, ok := token.Header["kid"].(string); ok {
+			if _, known := m.keys[keyID]; !known {
+				return nil
+			}
+			return []string{keyID}
+		}
+	}
+
+	ids := make([]string, 0, len(m.keys))
+	for id := range m.keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// PublicKeys returns the verification keys of all asymmetric keys in the
+// ring, sorted by key id. HMAC secrets are never published.
+func (m *tokenManager) PublicKeys() []users.JSONWebKey {
+	keys := make([]users.JSONWebKey, 0, len(m.keys))
+	for id, key := range m.keys {
+		switch pub := key.verifyKey.(type) {
+		case ed25519.PublicKey:
+			keys = append(keys, users.JSONWebKey{
+				KeyID:     id,
+				KeyType:   "OKP",
+				Algorithm: key.method.Alg(),
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		case *rsa.PublicKey:
+			keys = append(keys, users.JSONWebKey{
+				KeyID:     id,
+				KeyType:   "RSA",
+				Algorithm: key.method.Alg(),
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].KeyID < keys[j].KeyID
+	})
+	return keys
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/kingxl111/merch-store/internal/repository"
+	"github.com/kingxl111/merch-store/internal/repository/postgres"
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+func (u *userService) twoFactorEnabled(ctx context.Context, userID string) (bool, error) {
+	totp, err := u.authRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrorTOTPNotFound) {
+			return false, nil
+		}
+		return false, users.ErrorService
+	}
+	return totp.EnabledAt != nil, nil
+}
+
+// challenge hands out a challenge token for the second step of the login.
+// The token is recorded, so that it completes a single login only.
+func (u *userService) challenge(ctx context.Context, user *postgres.User) (*users.AuthResponse, error) {
+	c := &postgres.TwoFactorChallenge{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(challengeTokenTTL),
+	}
+	token, err := u.tokens.GenerateChallengeToken(c.ID, user.ID, user.Username, challengeTokenTTL)
+	if err != nil {
+		return nil, users.ErrorGenerateToken
+	}
+	if err := u.authRepo.CreateTwoFactorChallenge(ctx, c); err != nil {
+		return nil, users.ErrorService
+	}
+
+	return &users.AuthResponse{
+		ChallengeToken: token,
+		ExpiresIn:      int(challengeTokenTTL.Seconds()),
+	}, nil
+}
+
+// CompleteTwoFactor exchanges a challenge token and a TOTP or recovery code
+// for the tokens of a new session. Wrong codes count as failed logins, and a
+// challenge token is used up by the login it completes.
+func (u *userService) CompleteTwoFactor(ctx context.Context, req *users.TwoFactorLoginRequest) (*users.AuthResponse, error) {
+	challengeID, userID, username, err := u.tokens.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, users.ErrorInvalidChallenge
+	}
+
+	attempt := &users.AuthRequest{Username: username, ClientIP: req.ClientIP, UserAgent: req.UserAgent}
+	if err := u.checkLockout(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	user, err := u.authRepo.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrorUserNotFound) {
+			return nil, users.ErrorInvalidChallenge
+		}
+		return nil, users.ErrorService
+	}
+	if user.ID != userID {
+		return nil, users.ErrorInvalidChallenge
+	}
+
+	totp, err := u.enabledTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, users.ErrorTwoFactorNotEnabled) {
+			return nil, users.ErrorInvalidChallenge
+		}
+		return nil, err
+	}
+
+	if err := u.verifySecondFactor(ctx, totp, req.Code, challengeID); err != nil {
+		return nil, u.secondFactorFailed(ctx, attempt, err)
+	}
+
+	if err := u.resetFailures(ctx, attempt); err != nil {
+		return nil, users.ErrorService
+	}
+
+	return u.startSession(ctx, user, attempt)
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the caller. It only takes
+// effect once confirmed with a code by ConfirmTwoFactor.
+func (u *userService) EnrollTwoFactor(ctx context.Context, principal *users.Principal) (*users.TwoFactorEnrollment, error) {
+	enabled, err := u.twoFactorEnabled(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, users.ErrorTwoFactorEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	if err := u.authRepo.SaveTOTPSecret(ctx, principal.UserID, secret); err != nil {
+		if errors.Is(err, repository.ErrorTOTPNotFound) {
+			return nil, users.ErrorTwoFactorEnabled
+		}
+		return nil, users.ErrorService
+	}
+
+	return &users.TwoFactorEnrollment{
+		Secret: secret,
+		URL:    totpURL(principal.Username, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables the enrolled secret once the caller proves it is
+// set up with a valid code, and returns a fresh set of recovery codes. The
+// codes are shown only once. Wrong codes count as failed logins.
+func (u *userService) ConfirmTwoFactor(ctx context.Context, principal *users.Principal, code string) ([]string, error) {
+	attempt := &users.AuthRequest{Username: principal.Username}
+	if err := u.checkLockout(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	totp, err := u.authRepo.GetTOTP(ctx, principal.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrorTOTPNotFound) {
+			return nil, users.ErrorTwoFactorNotEnrolled
+		}
+		return nil, users.ErrorService
+	}
+	if totp.EnabledAt != nil {
+		return nil, users.ErrorTwoFactorEnabled
+	}
+
+	step, ok := matchTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, u.secondFactorFailed(ctx, attempt, users.ErrorInvalidTwoFactorCode)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, users.ErrorService
+	}
+
+	if err := u.authRepo.EnableTOTP(ctx, principal.UserID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrorTOTPNotFound) {
+			return nil, users.ErrorTwoFactorEnabled
+		}
+		return nil, users.ErrorService
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off. A valid TOTP or
+// recovery code is required so that a stolen access token alone is not
+// enough to remove the second factor.
+func (u *userService) DisableTwoFactor(ctx context.Context, principal *users.Principal, code string) error {
+	attempt := &users.AuthRequest{Username: principal.Username}
+	if err := u.checkLockout(ctx, attempt); err != nil {
+		return err
+	}
+
+	totp, err := u.enabledTOTP(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.verifySecondFactor(ctx, totp, code, ""); err != nil {
+		return u.secondFactorFailed(ctx, attempt, err)
+	}
+
+	if err := u.authRepo.DisableTOTP(ctx, principal.UserID); err != nil {
+		return users.ErrorService
+	}
+	return nil
+}
+
+func (u *userService) enabledTOTP(ctx context.Context, userID string) (*postgres.TOTP, error) {
+	totp, err := u.authRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrorTOTPNotFound) {
+			return nil, users.ErrorTwoFactorNotEnabled
+		}
+		return nil, users.ErrorService
+	}
+	if totp.EnabledAt == nil {
+		return nil, users.ErrorTwoFactorNotEnabled
+	}
+	return totp, nil
+}
+
+// verifySecondFactor accepts a TOTP code that has not been used yet or an
+// unused recovery code, which is used up. A non-empty challengeID is used up
+// together with the code; if the challenge has been used already, the code is
+// left as it was and ErrorInvalidChallenge is returned.
+func (u *userService) verifySecondFactor(ctx context.Context, totp *postgres.TOTP, code, challengeID string) error {
+	if step, ok := matchTOTP(totp.Secret, code, time.Now()); ok {
+		err := u.authRepo.UseTOTPStep(ctx, totp.UserID, step, challengeID)
+		switch {
+		case errors.Is(err, repository.ErrorTOTPStepUsed):
+			return users.ErrorInvalidTwoFactorCode
+		case errors.Is(err, repository.ErrorChallengeNotFound):
+			return users.ErrorInvalidChallenge
+		case err != nil:
+			return users.ErrorService
+		}
+		return nil
+	}
+
+	err := u.authRepo.UseRecoveryCode(ctx, totp.UserID, hashRecoveryCode(code), challengeID)
+	switch {
+	case errors.Is(err, repository.ErrorRecoveryCodeNotFound):
+		return users.ErrorInvalidTwoFactorCode
+	case errors.Is(err, repository.ErrorChallengeNotFound):
+		return users.ErrorInvalidChallenge
+	case err != nil:
+		return users.ErrorService
+	}
+	return nil
+}
+
+// secondFactorFailed counts a wrong code like a wrong password.
+func (u *userService) secondFactorFailed(ctx context.Context, attempt *users.AuthRequest, err error) error {
+	if !errors.Is(err, users.ErrorInvalidTwoFactorCode) {
+		return err
+	}
+	if err := u.recordFailure(ctx, attempt); err != nil {
+		return err
+	}
+	return users.ErrorInvalidTwoFactorCode
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kingxl111/merch-store/internal/users"
+)
+
+const (
+	minUsernameLen = 3
+	maxUsernameLen = 32
+)
+
+// usernamePattern allows latin letters, digits, dots, dashes and underscores
+// and requires the name to start with a letter or a digit.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// reservedUsernames cannot be registered, compared case-insensitively.
+var reservedUsernames = map[string]struct{}{
+	"admin":         {},
+	"administrator": {},
+	"root":          {},
+	"system":        {},
+	"support":       {},
+	"security":      {},
+	"api":           {},
+	"merch-store":   {},
+	"shop":          {},
+	"null":          {},
+	"undefined":     {},
+}
+
+func validateUsername(username string) error {
+	if len(username) < minUsernameLen || len(username) > maxUsernameLen {
+		return users.ErrorInvalidUsername
+	}
+	if !usernamePattern.MatchString(username) {
+		return users.ErrorInvalidUsername
+	}
+	if _, ok := reservedUsernames[strings.ToLower(username)]; ok {
+		return users.ErrorReservedUsername
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+-- Fails if the table already holds usernames that differ only in case; such
+-- accounts have to be renamed by hand before applying this migration.
+CREATE UNIQUE INDEX users_username_lower_key ON users (LOWER(username));
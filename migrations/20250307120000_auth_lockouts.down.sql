@@ -0,0 +1,2 @@
+DROP TABLE auth_lockout_events;
+DROP TABLE auth_failures;
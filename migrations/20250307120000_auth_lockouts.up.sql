@@ -0,0 +1,19 @@
+CREATE TABLE auth_failures (
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('username', 'ip')),
+    subject TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE TABLE auth_lockout_events (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(16) NOT NULL,
+    subject TEXT NOT NULL,
+    failures INT NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_auth_lockout_events_created ON auth_lockout_events(created_at);
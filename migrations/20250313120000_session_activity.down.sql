@@ -0,0 +1,5 @@
+ALTER TABLE sessions
+    DROP COLUMN user_agent,
+    DROP COLUMN ip,
+    DROP COLUMN last_seen_at,
+    DROP COLUMN last_token_id;
@@ -0,0 +1,23 @@
+CREATE TABLE impersonations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id UUID NOT NULL REFERENCES users(id),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    read_only BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_impersonations_user ON impersonations(user_id, created_at);
+
+CREATE TABLE impersonation_requests (
+    id BIGSERIAL PRIMARY KEY,
+    impersonation_id UUID NOT NULL REFERENCES impersonations(id) ON DELETE CASCADE,
+    method VARCHAR(16) NOT NULL,
+    path TEXT NOT NULL,
+    status INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_impersonation_requests_impersonation ON impersonation_requests(impersonation_id);
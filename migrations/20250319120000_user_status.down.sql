@@ -0,0 +1,2 @@
+DROP TABLE user_status_events;
+ALTER TABLE users DROP COLUMN status;
@@ -0,0 +1,9 @@
+CREATE TABLE username_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_username_history_user ON username_history(user_id, changed_at);
+CREATE INDEX idx_username_history_username ON username_history(LOWER(username), changed_at);
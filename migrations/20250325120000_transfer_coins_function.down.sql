@@ -0,0 +1 @@
+DROP FUNCTION transfer_coins(VARCHAR, VARCHAR, INT);
@@ -0,0 +1,43 @@
+CREATE OR REPLACE FUNCTION transfer_coins(sender_name VARCHAR, receiver_name VARCHAR, transfer_amount INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    sender users%ROWTYPE;
+    receiver users%ROWTYPE;
+BEGIN
+    PERFORM 1 FROM users
+    WHERE username IN (sender_name, receiver_name)
+    ORDER BY id
+    FOR UPDATE;
+
+    SELECT * INTO sender FROM users WHERE username = sender_name;
+    IF NOT FOUND THEN
+        RETURN 'sender_not_found';
+    END IF;
+    IF sender.status <> 'active' THEN
+        RETURN sender.status;
+    END IF;
+
+    SELECT * INTO receiver FROM users WHERE username = receiver_name;
+    IF NOT FOUND THEN
+        RETURN 'receiver_not_found';
+    END IF;
+    IF receiver.status = 'closed' THEN
+        RETURN 'receiver_closed';
+    END IF;
+
+    IF sender.coins < transfer_amount THEN
+        RETURN 'insufficient_funds';
+    END IF;
+
+    UPDATE users SET coins = coins - transfer_amount WHERE id = sender.id;
+    UPDATE users SET coins = coins + transfer_amount WHERE id = receiver.id;
+    INSERT INTO coin_transactions (from_user_id, to_user_id, amount, created_at)
+    VALUES (sender.id, receiver.id, transfer_amount, NOW());
+
+    RETURN 'ok';
+END;
+$$;
+
+DROP TABLE journal_entries;
@@ -0,0 +1,82 @@
+-- Every movement of coins is a journal entry that debits one account and
+-- credits another by the same amount. An account is either a user or one of
+-- the system accounts: "issuance", which granted coins come from, and "shop",
+-- which coins spent on merch go to. users.coins is the balance of the user's
+-- account, its credits minus its debits, updated with every entry.
+CREATE TABLE journal_entries (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL
+        CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund')),
+    debit_user_id UUID REFERENCES users(id),
+    debit_account VARCHAR(16) CHECK (debit_account IN ('issuance', 'shop')),
+    credit_user_id UUID REFERENCES users(id),
+    credit_account VARCHAR(16) CHECK (credit_account IN ('issuance', 'shop')),
+    amount INT NOT NULL CHECK (amount > 0),
+    transaction_id INT REFERENCES coin_transactions(id),
+    item_type VARCHAR(255),
+    quantity INT,
+    reverses_entry_id BIGINT UNIQUE REFERENCES journal_entries(id),
+    description TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((debit_user_id IS NULL) <> (debit_account IS NULL)),
+    CHECK ((credit_user_id IS NULL) <> (credit_account IS NULL))
+);
+
+CREATE INDEX idx_journal_entries_debit_user ON journal_entries(debit_user_id, created_at);
+CREATE INDEX idx_journal_entries_credit_user ON journal_entries(credit_user_id, created_at);
+
+-- The balances so far have no entries behind them, so each one is opened
+-- from the issuance account.
+INSERT INTO journal_entries (kind, debit_account, credit_user_id, amount, description)
+SELECT 'opening', 'issuance', id, coins, 'opening balance'
+FROM users
+WHERE coins > 0;
+
+CREATE OR REPLACE FUNCTION transfer_coins(sender_name VARCHAR, receiver_name VARCHAR, transfer_amount INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    sender users%ROWTYPE;
+    receiver users%ROWTYPE;
+    transfer_id INT;
+BEGIN
+    PERFORM 1 FROM users
+    WHERE username IN (sender_name, receiver_name)
+    ORDER BY id
+    FOR UPDATE;
+
+    SELECT * INTO sender FROM users WHERE username = sender_name;
+    IF NOT FOUND THEN
+        RETURN 'sender_not_found';
+    END IF;
+    IF sender.status <> 'active' THEN
+        RETURN sender.status;
+    END IF;
+
+    SELECT * INTO receiver FROM users WHERE username = receiver_name;
+    IF NOT FOUND THEN
+        RETURN 'receiver_not_found';
+    END IF;
+    IF receiver.status = 'closed' THEN
+        RETURN 'receiver_closed';
+    END IF;
+
+    IF sender.coins < transfer_amount THEN
+        RETURN 'insufficient_funds';
+    END IF;
+
+    INSERT INTO coin_transactions (from_user_id, to_user_id, amount, created_at)
+    VALUES (sender.id, receiver.id, transfer_amount, NOW())
+    RETURNING id INTO transfer_id;
+
+    INSERT INTO journal_entries (kind, debit_user_id, credit_user_id, amount, transaction_id)
+    VALUES ('transfer', sender.id, receiver.id, transfer_amount, transfer_id);
+
+    UPDATE users SET coins = coins - transfer_amount WHERE id = sender.id;
+    UPDATE users SET coins = coins + transfer_amount WHERE id = receiver.id;
+
+    RETURN 'ok';
+END;
+$$;
@@ -0,0 +1,3 @@
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_kind_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_kind_check
+    CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund'));
@@ -0,0 +1,5 @@
+-- Adjustments are written by the reconciliation tool to correct balances
+-- that do not match the activity of the user.
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_kind_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_kind_check
+    CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund', 'adjustment'));
@@ -0,0 +1,37 @@
+-- A scheduled transfer runs once at run_at or whenever the cron rule fires.
+-- next_run_at is the next time it is due and NULL once it will not run
+-- again.
+CREATE TABLE scheduled_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    message VARCHAR(200),
+    category VARCHAR(32),
+    run_at TIMESTAMP WITH TIME ZONE,
+    rule VARCHAR(100),
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((run_at IS NULL) <> (rule IS NULL))
+);
+
+CREATE INDEX idx_scheduled_transfers_user ON scheduled_transfers(user_id);
+CREATE INDEX idx_scheduled_transfers_due ON scheduled_transfers(next_run_at)
+    WHERE next_run_at IS NOT NULL;
+
+-- Every time a scheduled transfer is due, a run is recorded as pending and
+-- then finished as ok or failed, with the reason of the failure.
+CREATE TABLE scheduled_transfer_runs (
+    id BIGSERIAL PRIMARY KEY,
+    scheduled_transfer_id UUID NOT NULL REFERENCES scheduled_transfers(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'ok', 'failed')),
+    error TEXT,
+    UNIQUE (scheduled_transfer_id, scheduled_for)
+);
+
+CREATE INDEX idx_scheduled_transfer_runs_pending ON scheduled_transfer_runs(started_at)
+    WHERE status = 'pending';
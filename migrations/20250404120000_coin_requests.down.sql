@@ -0,0 +1 @@
+DROP TABLE coin_requests;
@@ -0,0 +1,17 @@
+-- A coin request asks the payer to send coins to the requester. A request
+-- that is still pending after expires_at has expired.
+CREATE TABLE coin_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    reason VARCHAR(200) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'declined')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_coin_requests_requester ON coin_requests(requester_id, created_at);
+CREATE INDEX idx_coin_requests_payer_pending ON coin_requests(payer_id, expires_at)
+    WHERE status = 'pending';
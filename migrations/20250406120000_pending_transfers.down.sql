@@ -0,0 +1,27 @@
+-- Coins still held are given back to their senders first, so that no
+-- balance is lost with the escrow account.
+UPDATE users u
+SET coins = u.coins + p.held
+FROM (
+    SELECT from_user_id, SUM(amount) AS held
+    FROM pending_transfers
+    WHERE status = 'pending'
+    GROUP BY from_user_id
+) p
+WHERE u.id = p.from_user_id;
+
+DELETE FROM journal_entries WHERE kind IN ('hold', 'release');
+
+DROP TABLE pending_transfers;
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_credit_account_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_credit_account_check
+    CHECK (credit_account IN ('issuance', 'shop'));
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_debit_account_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_debit_account_check
+    CHECK (debit_account IN ('issuance', 'shop'));
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_kind_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_kind_check
+    CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund', 'adjustment'));
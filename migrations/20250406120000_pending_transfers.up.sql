@@ -0,0 +1,36 @@
+-- A delayed transfer holds the coins of the sender until settles_at, during
+-- which the sender can cancel it. The held coins sit on the "escrow" system
+-- account: a 'hold' entry moves them there and a 'release' entry moves them
+-- back to the sender, either when the transfer is cancelled or right before
+-- it is made as a regular transfer.
+CREATE TABLE pending_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    message VARCHAR(200),
+    category VARCHAR(32),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'settled', 'cancelled', 'returned')),
+    transaction_id INT REFERENCES coin_transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    settles_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_pending_transfers_due ON pending_transfers(settles_at)
+    WHERE status = 'pending';
+CREATE INDEX idx_pending_transfers_from_user ON pending_transfers(from_user_id, created_at);
+CREATE INDEX idx_pending_transfers_to_user ON pending_transfers(to_user_id, created_at);
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_kind_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_kind_check
+    CHECK (kind IN ('opening', 'grant', 'transfer', 'purchase', 'refund', 'adjustment', 'hold', 'release'));
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_debit_account_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_debit_account_check
+    CHECK (debit_account IN ('issuance', 'shop', 'escrow'));
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_credit_account_check;
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_credit_account_check
+    CHECK (credit_account IN ('issuance', 'shop', 'escrow'));
@@ -0,0 +1,6 @@
+DELETE FROM auth_failures WHERE scope IN ('reset_username', 'reset_ip');
+DELETE FROM auth_lockout_events WHERE scope IN ('reset_username', 'reset_ip');
+
+ALTER TABLE auth_failures DROP CONSTRAINT auth_failures_scope_check;
+ALTER TABLE auth_failures ADD CONSTRAINT auth_failures_scope_check
+    CHECK (scope IN ('username', 'ip'));
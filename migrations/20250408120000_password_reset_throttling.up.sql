@@ -0,0 +1,5 @@
+-- Password reset requests are counted per username and per client address
+-- like failed logins, under scopes of their own.
+ALTER TABLE auth_failures DROP CONSTRAINT auth_failures_scope_check;
+ALTER TABLE auth_failures ADD CONSTRAINT auth_failures_scope_check
+    CHECK (scope IN ('username', 'ip', 'reset_username', 'reset_ip'));
@@ -0,0 +1,8 @@
+-- Every challenge token handed out after a correct password is recorded, so
+-- that it can complete a single login.
+CREATE TABLE two_factor_challenges (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
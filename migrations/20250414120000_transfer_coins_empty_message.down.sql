@@ -0,0 +1,53 @@
+CREATE OR REPLACE FUNCTION transfer_coins(
+    sender_name VARCHAR,
+    receiver_name VARCHAR,
+    transfer_amount INT,
+    transfer_message VARCHAR,
+    transfer_category VARCHAR
+)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    sender users%ROWTYPE;
+    receiver users%ROWTYPE;
+    transfer_id INT;
+BEGIN
+    PERFORM 1 FROM users
+    WHERE LOWER(username) IN (LOWER(sender_name), LOWER(receiver_name))
+    ORDER BY id
+    FOR UPDATE;
+
+    SELECT * INTO sender FROM users WHERE LOWER(username) = LOWER(sender_name);
+    IF NOT FOUND THEN
+        RETURN 'sender_not_found';
+    END IF;
+    IF sender.status <> 'active' THEN
+        RETURN sender.status;
+    END IF;
+
+    SELECT * INTO receiver FROM users WHERE LOWER(username) = LOWER(receiver_name);
+    IF NOT FOUND THEN
+        RETURN 'receiver_not_found';
+    END IF;
+    IF receiver.status = 'closed' THEN
+        RETURN 'receiver_closed';
+    END IF;
+
+    IF sender.coins < transfer_amount THEN
+        RETURN 'insufficient_funds';
+    END IF;
+
+    INSERT INTO coin_transactions (from_user_id, to_user_id, amount, message, category, created_at)
+    VALUES (sender.id, receiver.id, transfer_amount, transfer_message, transfer_category, NOW())
+    RETURNING id INTO transfer_id;
+
+    INSERT INTO journal_entries (kind, debit_user_id, credit_user_id, amount, transaction_id, description)
+    VALUES ('transfer', sender.id, receiver.id, transfer_amount, transfer_id, transfer_message);
+
+    UPDATE users SET coins = coins - transfer_amount WHERE id = sender.id;
+    UPDATE users SET coins = coins + transfer_amount WHERE id = receiver.id;
+
+    RETURN 'ok';
+END;
+$$;
@@ -0,0 +1,2 @@
+-- The names dropped from the descriptions cannot be restored.
+SELECT 1;
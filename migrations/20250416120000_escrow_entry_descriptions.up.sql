@@ -0,0 +1,15 @@
+-- Hold and release entries named the other party of the delayed transfer in
+-- their description, which outlived the anonymisation of offboarded users.
+-- New entries refer to the transfer by id; the names are dropped from the
+-- existing ones.
+UPDATE journal_entries SET description = 'held for delayed transfer'
+WHERE kind = 'hold' AND description LIKE 'held for %';
+
+UPDATE journal_entries SET description = 'cancelled delayed transfer'
+WHERE kind = 'release' AND description LIKE 'cancelled transfer to %';
+
+UPDATE journal_entries SET description = 'returned delayed transfer'
+WHERE kind = 'release' AND description LIKE 'returned transfer to %';
+
+UPDATE journal_entries SET description = 'settled delayed transfer'
+WHERE kind = 'release' AND description LIKE 'settled transfer to %';
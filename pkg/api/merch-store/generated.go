@@ -6,17 +6,116 @@
 package merch_store_api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/oapi-codegen/runtime"
 )
 
 const (
+	ApiKeyAuthScopes = "ApiKeyAuth.Scopes"
 	BearerAuthScopes = "BearerAuth.Scopes"
 )
 
+// Defines values for APIKeyScope.
+const (
+	CoinsSend APIKeyScope = "coins:send"
+	InfoRead  APIKeyScope = "info:read"
+	ShopBuy   APIKeyScope = "shop:buy"
+)
+
+// Defines values for AccountStatus.
+const (
+	Active    AccountStatus = "active"
+	Closed    AccountStatus = "closed"
+	Frozen    AccountStatus = "frozen"
+	Suspended AccountStatus = "suspended"
+)
+
+// Defines values for CoinRequestStatus.
+const (
+	CoinRequestStatusDeclined CoinRequestStatus = "declined"
+	CoinRequestStatusExpired  CoinRequestStatus = "expired"
+	CoinRequestStatusPaid     CoinRequestStatus = "paid"
+	CoinRequestStatusPending  CoinRequestStatus = "pending"
+)
+
+// Defines values for LedgerEntryKind.
+const (
+	Adjustment LedgerEntryKind = "adjustment"
+	Grant      LedgerEntryKind = "grant"
+	Hold       LedgerEntryKind = "hold"
+	Opening    LedgerEntryKind = "opening"
+	Purchase   LedgerEntryKind = "purchase"
+	Refund     LedgerEntryKind = "refund"
+	Release    LedgerEntryKind = "release"
+	Transfer   LedgerEntryKind = "transfer"
+)
+
+// Defines values for LockoutEventScope.
+const (
+	Ip            LockoutEventScope = "ip"
+	ResetIp       LockoutEventScope = "reset_ip"
+	ResetUsername LockoutEventScope = "reset_username"
+	Username      LockoutEventScope = "username"
+)
+
+// Defines values for PendingTransferStatus.
+const (
+	PendingTransferStatusCancelled PendingTransferStatus = "cancelled"
+	PendingTransferStatusPending   PendingTransferStatus = "pending"
+	PendingTransferStatusReturned  PendingTransferStatus = "returned"
+	PendingTransferStatusSettled   PendingTransferStatus = "settled"
+)
+
+// Defines values for ScheduledTransferRunStatus.
+const (
+	Failed  ScheduledTransferRunStatus = "failed"
+	Ok      ScheduledTransferRunStatus = "ok"
+	Pending ScheduledTransferRunStatus = "pending"
+)
+
+// Defines values for SetRoleRequestRole.
+const (
+	Admin SetRoleRequestRole = "admin"
+	User  SetRoleRequestRole = "user"
+)
+
+// APIKey defines model for APIKey.
+type APIKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Id        string    `json:"id"`
+
+	// Key Сам ключ. Возвращается только при создании.
+	Key        *string    `json:"key,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	Name       string     `json:"name"`
+
+	// Prefix Начало ключа, чтобы отличать ключи друг от друга.
+	Prefix string `json:"prefix"`
+
+	// Quota Лимит запросов в сутки (UTC).
+	Quota  *int          `json:"quota,omitempty"`
+	Scopes []APIKeyScope `json:"scopes"`
+}
+
+// APIKeyScope Область доступа API-ключа: info:read - /api/info, coins:send - /api/sendCoin,
+// shop:buy - /api/buy/{item}.
+type APIKeyScope string
+
+// AccountStatus active — обычная учетная запись; frozen — может получать монеты, но не тратить;
+// suspended — вход запрещен; closed — учетная запись закрыта и не принимает монеты.
+type AccountStatus string
+
 // AuthRequest defines model for AuthRequest.
 type AuthRequest struct {
 	// Password Пароль для аутентификации.
@@ -26,99 +125,1579 @@ type AuthRequest struct {
 	Username string `json:"username"`
 }
 
-// AuthResponse defines model for AuthResponse.
-type AuthResponse struct {
-	// Token JWT-токен для доступа к защищенным ресурсам.
-	Token *string `json:"token,omitempty"`
+// AuthResponse defines model for AuthResponse.
+type AuthResponse struct {
+	// ExpiresIn Время жизни JWT-токена в секундах.
+	ExpiresIn *int `json:"expiresIn,omitempty"`
+
+	// RefreshToken Одноразовый токен для получения новой пары токенов.
+	RefreshToken *string `json:"refreshToken,omitempty"`
+
+	// Token JWT-токен для доступа к защищенным ресурсам.
+	Token *string `json:"token,omitempty"`
+}
+
+// ChangePasswordRequest defines model for ChangePasswordRequest.
+type ChangePasswordRequest struct {
+	// NewPassword Новый пароль.
+	NewPassword string `json:"newPassword"`
+
+	// OldPassword Текущий пароль.
+	OldPassword string `json:"oldPassword"`
+}
+
+// ChangeUsernameRequest defines model for ChangeUsernameRequest.
+type ChangeUsernameRequest struct {
+	// Username Новое имя пользователя.
+	Username string `json:"username"`
+}
+
+// CoinRequest defines model for CoinRequest.
+type CoinRequest struct {
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Id        string    `json:"id"`
+
+	// Payer Пользователь, у которого запрошены монеты.
+	Payer  string `json:"payer"`
+	Reason string `json:"reason"`
+
+	// Requester Пользователь, который запросил монеты и получит их.
+	Requester string `json:"requester"`
+
+	// ResolvedAt Когда запрос был оплачен или отклонен.
+	ResolvedAt *time.Time        `json:"resolvedAt,omitempty"`
+	Status     CoinRequestStatus `json:"status"`
+}
+
+// CoinRequestStatus defines model for CoinRequest.Status.
+type CoinRequestStatus string
+
+// CreateAPIKeyRequest defines model for CreateAPIKeyRequest.
+type CreateAPIKeyRequest struct {
+	// Name Название ключа, до 64 символов.
+	Name string `json:"name"`
+
+	// Quota Сколько запросов в сутки (UTC) можно выполнить с ключом. Без ограничения, если не задано.
+	Quota  *int          `json:"quota,omitempty"`
+	Scopes []APIKeyScope `json:"scopes"`
+}
+
+// CreateCoinRequestRequest defines model for CreateCoinRequestRequest.
+type CreateCoinRequestRequest struct {
+	// Amount Количество запрашиваемых монет.
+	Amount int `json:"amount"`
+
+	// Payer Имя пользователя, у которого запрашиваются монеты.
+	Payer string `json:"payer"`
+
+	// Reason Причина запроса. При оплате становится сообщением перевода.
+	Reason string `json:"reason"`
+}
+
+// CreateScheduledTransferRequest Должно быть указано ровно одно из полей runAt и rule.
+type CreateScheduledTransferRequest struct {
+	// Amount Количество монет, которые необходимо отправлять.
+	Amount int `json:"amount"`
+
+	// Category Категория перевода.
+	Category *string `json:"category,omitempty"`
+
+	// Message Сообщение получателю.
+	Message *string `json:"message,omitempty"`
+
+	// Rule Правило повторения в формате cron, время UTC.
+	Rule *string `json:"rule,omitempty"`
+
+	// RunAt Момент однократного перевода.
+	RunAt *time.Time `json:"runAt,omitempty"`
+
+	// ToUser Имя пользователя, которому нужно отправить монеты.
+	ToUser string `json:"toUser"`
+}
+
+// ErrorResponse defines model for ErrorResponse.
+type ErrorResponse struct {
+	// Errors Сообщение об ошибке, описывающее проблему.
+	Errors *string `json:"errors,omitempty"`
+}
+
+// GrantRequest defines model for GrantRequest.
+type GrantRequest struct {
+	Amount int `json:"amount"`
+
+	// Reason Причина начисления, например номер приказа о премии.
+	Reason string `json:"reason"`
+}
+
+// ImpersonateRequest defines model for ImpersonateRequest.
+type ImpersonateRequest struct {
+	// AllowWrites Разрешить изменяющие запросы, например отправку монет.
+	AllowWrites *bool `json:"allowWrites,omitempty"`
+
+	// Reason Причина, например номер обращения в поддержку.
+	Reason string `json:"reason"`
+}
+
+// ImpersonationToken defines model for ImpersonationToken.
+type ImpersonationToken struct {
+	// ExpiresIn Время жизни токена в секундах.
+	ExpiresIn int `json:"expiresIn"`
+
+	// ReadOnly Токен позволяет только читать данные.
+	ReadOnly bool `json:"readOnly"`
+
+	// Token JWT-токен с claim act, указывающим администратора.
+	Token string `json:"token"`
+}
+
+// InfoResponse defines model for InfoResponse.
+type InfoResponse struct {
+	CoinHistory *struct {
+		Received *[]struct {
+			// Amount Количество полученных монет.
+			Amount *int `json:"amount,omitempty"`
+
+			// Category Категория перевода.
+			Category *string `json:"category,omitempty"`
+
+			// FromUser Имя пользователя, который отправил монеты.
+			FromUser *string `json:"fromUser,omitempty"`
+
+			// Message Сообщение отправителя.
+			Message *string `json:"message,omitempty"`
+		} `json:"received,omitempty"`
+		Sent *[]struct {
+			// Amount Количество отправленных монет.
+			Amount *int `json:"amount,omitempty"`
+
+			// Category Категория перевода.
+			Category *string `json:"category,omitempty"`
+
+			// Message Сообщение получателю.
+			Message *string `json:"message,omitempty"`
+
+			// ToUser Имя пользователя, которому отправлены монеты.
+			ToUser *string `json:"toUser,omitempty"`
+		} `json:"sent,omitempty"`
+	} `json:"coinHistory,omitempty"`
+
+	// Coins Количество доступных монет.
+	Coins *int `json:"coins,omitempty"`
+
+	// HeldCoins Монеты, удержанные в отложенных переводах пользователя. Не входят в coins.
+	HeldCoins *int `json:"heldCoins,omitempty"`
+
+	// Impersonations Последние случаи, когда администратор действовал от имени пользователя.
+	Impersonations *[]struct {
+		// Admin Имя администратора.
+		Admin     *string    `json:"admin,omitempty"`
+		CreatedAt *time.Time `json:"createdAt,omitempty"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+		// ReadOnly Администратор мог только читать данные.
+		ReadOnly *bool `json:"readOnly,omitempty"`
+
+		// Reason Указанная администратором причина.
+		Reason *string `json:"reason,omitempty"`
+	} `json:"impersonations,omitempty"`
+	Inventory *[]struct {
+		// Quantity Количество предметов.
+		Quantity *int `json:"quantity,omitempty"`
+
+		// Type Тип предмета.
+		Type *string `json:"type,omitempty"`
+	} `json:"inventory,omitempty"`
+}
+
+// InventoryItem defines model for InventoryItem.
+type InventoryItem struct {
+	Quantity int    `json:"quantity"`
+	Type     string `json:"type"`
+}
+
+// JWK defines model for JWK.
+type JWK struct {
+	// Alg Алгоритм подписи (EdDSA или RS256).
+	Alg *string `json:"alg,omitempty"`
+
+	// Crv Кривая для ключей OKP.
+	Crv *string `json:"crv,omitempty"`
+
+	// E Экспонента RSA-ключа в base64url.
+	E *string `json:"e,omitempty"`
+
+	// Kid Идентификатор ключа, совпадает с заголовком kid токена.
+	Kid string `json:"kid"`
+
+	// Kty Тип ключа (OKP или RSA).
+	Kty string `json:"kty"`
+
+	// N Модуль RSA-ключа в base64url.
+	N *string `json:"n,omitempty"`
+
+	// Use Назначение ключа.
+	Use *string `json:"use,omitempty"`
+
+	// X Открытый ключ OKP в base64url.
+	X *string `json:"x,omitempty"`
+}
+
+// JWKSet defines model for JWKSet.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LedgerEntry Проводка журнала с точки зрения счета пользователя.
+type LedgerEntry struct {
+	// Amount Изменение баланса пользователя, отрицательное при списании.
+	Amount int `json:"amount"`
+
+	// Counterparty Имя пользователя или системный счет (issuance, shop, escrow) на другой стороне проводки.
+	Counterparty string    `json:"counterparty"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	// CreatedBy Администратор, сделавший начисление или возврат.
+	CreatedBy   *string `json:"createdBy,omitempty"`
+	Description string  `json:"description"`
+	Id          int64   `json:"id"`
+	Item        *string `json:"item,omitempty"`
+
+	// Kind opening — остаток на момент появления журнала, grant — начисление, transfer — перевод,
+	// purchase — покупка, refund — возврат покупки, adjustment — исправление баланса при сверке,
+	// hold — удержание монет отложенного перевода, release — возврат удержанных монет перед
+	// зачислением или при отмене.
+	Kind     LedgerEntryKind `json:"kind"`
+	Quantity *int            `json:"quantity,omitempty"`
+
+	// RefundOf Проводка покупки, которую отменяет возврат.
+	RefundOf *int64 `json:"refundOf,omitempty"`
+}
+
+// LedgerEntryKind opening — остаток на момент появления журнала, grant — начисление, transfer — перевод,
+// purchase — покупка, refund — возврат покупки, adjustment — исправление баланса при сверке,
+// hold — удержание монет отложенного перевода, release — возврат удержанных монет перед
+// зачислением или при отмене.
+type LedgerEntryKind string
+
+// LockoutEvent defines model for LockoutEvent.
+type LockoutEvent struct {
+	// CreatedAt Время блокировки.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Failures Количество неудачных попыток к моменту блокировки.
+	Failures int `json:"failures"`
+
+	// LockedUntil Время окончания блокировки.
+	LockedUntil time.Time `json:"lockedUntil"`
+
+	// Scope Что заблокировано - имя пользователя или IP-адрес; reset_username и reset_ip —
+	// запросы сброса пароля для имени пользователя или с IP-адреса.
+	Scope LockoutEventScope `json:"scope"`
+
+	// Subject Имя пользователя или IP-адрес.
+	Subject string `json:"subject"`
+}
+
+// LockoutEventScope Что заблокировано - имя пользователя или IP-адрес; reset_username и reset_ip —
+// запросы сброса пароля для имени пользователя или с IP-адреса.
+type LockoutEventScope string
+
+// OffboardRequest defines model for OffboardRequest.
+type OffboardRequest struct {
+	// Reason Причина закрытия, например номер заявки на увольнение.
+	Reason string `json:"reason"`
+}
+
+// OffboardingSummary defines model for OffboardingSummary.
+type OffboardingSummary struct {
+	// AnonymizedUsername Имя, под которым учетная запись хранится после закрытия.
+	AnonymizedUsername string    `json:"anonymizedUsername"`
+	ApiKeysRevoked     int       `json:"apiKeysRevoked"`
+	ClosedAt           time.Time `json:"closedAt"`
+
+	// ClosedBy Имя администратора, закрывшего учетную запись.
+	ClosedBy string `json:"closedBy"`
+
+	// Inventory Предметы, купленные пользователем.
+	Inventory []InventoryItem `json:"inventory"`
+	Reason    string          `json:"reason"`
+
+	// ReceivedTransactions Количество полученных переводов до закрытия.
+	ReceivedTransactions int `json:"receivedTransactions"`
+
+	// SentTransactions Количество отправленных переводов до закрытия.
+	SentTransactions int `json:"sentTransactions"`
+	SessionsRevoked  int `json:"sessionsRevoked"`
+
+	// SinkAccount Счет, на который переведен остаток монет.
+	SinkAccount string `json:"sinkAccount"`
+
+	// SweptCoins Сколько монет переведено, включая удержанные в отмененных отложенных переводах.
+	SweptCoins int    `json:"sweptCoins"`
+	UserId     string `json:"userId"`
+
+	// Username Исходное имя пользователя.
+	Username string `json:"username"`
+}
+
+// PasswordResetConfirmRequest defines model for PasswordResetConfirmRequest.
+type PasswordResetConfirmRequest struct {
+	// NewPassword Новый пароль.
+	NewPassword string `json:"newPassword"`
+
+	// Token Токен сброса пароля.
+	Token string `json:"token"`
+}
+
+// PasswordResetRequest defines model for PasswordResetRequest.
+type PasswordResetRequest struct {
+	// Username Имя пользователя.
+	Username string `json:"username"`
+}
+
+// PendingTransfer defines model for PendingTransfer.
+type PendingTransfer struct {
+	Amount    int       `json:"amount"`
+	Category  *string   `json:"category,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	FromUser  string    `json:"fromUser"`
+	Id        string    `json:"id"`
+	Message   *string   `json:"message,omitempty"`
+
+	// ResolvedAt Когда перевод был зачислен, отменен или возвращен.
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+
+	// SettlesAt Когда монеты будут зачислены. До этого момента перевод можно отменить.
+	SettlesAt time.Time `json:"settlesAt"`
+
+	// Status returned — до зачисления счет отправителя был заморожен, приостановлен или закрыт
+	// либо счет получателя закрыт; монеты возвращены отправителю.
+	Status PendingTransferStatus `json:"status"`
+	ToUser string                `json:"toUser"`
+}
+
+// PendingTransferStatus returned — до зачисления счет отправителя был заморожен, приостановлен или закрыт
+// либо счет получателя закрыт; монеты возвращены отправителю.
+type PendingTransferStatus string
+
+// RecoveryCodes defines model for RecoveryCodes.
+type RecoveryCodes struct {
+	// RecoveryCodes Одноразовые коды восстановления.
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// RefreshRequest defines model for RefreshRequest.
+type RefreshRequest struct {
+	// RefreshToken Refresh-токен, полученный при аутентификации или предыдущем обновлении.
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefundRequest defines model for RefundRequest.
+type RefundRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ScheduledTransfer defines model for ScheduledTransfer.
+type ScheduledTransfer struct {
+	Amount      int                   `json:"amount"`
+	CancelledAt *time.Time            `json:"cancelledAt,omitempty"`
+	Category    *string               `json:"category,omitempty"`
+	CreatedAt   time.Time             `json:"createdAt"`
+	Id          string                `json:"id"`
+	LastRun     *ScheduledTransferRun `json:"lastRun,omitempty"`
+	Message     *string               `json:"message,omitempty"`
+
+	// NextRunAt Когда перевод будет выполнен в следующий раз. Отсутствует, если он больше не будет выполняться.
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+	Rule      *string    `json:"rule,omitempty"`
+	RunAt     *time.Time `json:"runAt,omitempty"`
+	ToUser    string     `json:"toUser"`
+}
+
+// ScheduledTransferRun defines model for ScheduledTransferRun.
+type ScheduledTransferRun struct {
+	// Error Причина, по которой перевод не выполнен, например insufficient funds.
+	Error      *string    `json:"error,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	// ScheduledFor На какое время был запланирован запуск.
+	ScheduledFor time.Time `json:"scheduledFor"`
+	StartedAt    time.Time `json:"startedAt"`
+
+	// Status pending — выполняется, ok — монеты переведены, failed — перевод не выполнен.
+	Status ScheduledTransferRunStatus `json:"status"`
+}
+
+// ScheduledTransferRunStatus pending — выполняется, ok — монеты переведены, failed — перевод не выполнен.
+type ScheduledTransferRunStatus string
+
+// SendCoinRequest defines model for SendCoinRequest.
+type SendCoinRequest struct {
+	// Amount Количество монет, которые необходимо отправить.
+	Amount int `json:"amount"`
+
+	// Category Категория перевода.
+	Category *string `json:"category,omitempty"`
+
+	// Delayed Удержать монеты на время окна отмены вместо немедленного перевода.
+	Delayed *bool `json:"delayed,omitempty"`
+
+	// Message Сообщение получателю, например «спасибо за ревью».
+	Message *string `json:"message,omitempty"`
+
+	// ToUser Имя пользователя, которому нужно отправить монеты.
+	ToUser string `json:"toUser"`
+}
+
+// Session defines model for Session.
+type Session struct {
+	// CreatedAt Время входа.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Current Сессия, с которой выполнен запрос.
+	Current bool `json:"current"`
+
+	// ExpiresAt Время окончания сессии, если она не будет продлена.
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Id Идентификатор сессии, совпадает с claim sid токена.
+	Id string `json:"id"`
+
+	// Ip IP-адрес клиента при последнем запросе.
+	Ip string `json:"ip"`
+
+	// LastSeenAt Время последнего запроса с точностью до минуты.
+	LastSeenAt time.Time `json:"lastSeenAt"`
+
+	// UserAgent User-Agent клиента при последнем запросе.
+	UserAgent string `json:"userAgent"`
+}
+
+// SetRoleRequest defines model for SetRoleRequest.
+type SetRoleRequest struct {
+	// Role Новая роль пользователя.
+	Role SetRoleRequestRole `json:"role"`
+}
+
+// SetRoleRequestRole Новая роль пользователя.
+type SetRoleRequestRole string
+
+// SetStatusRequest defines model for SetStatusRequest.
+type SetStatusRequest struct {
+	// Reason Причина изменения статуса.
+	Reason string `json:"reason"`
+
+	// Status active — обычная учетная запись; frozen — может получать монеты, но не тратить;
+	// suspended — вход запрещен; closed — учетная запись закрыта и не принимает монеты.
+	Status AccountStatus `json:"status"`
+}
+
+// StatusChange defines model for StatusChange.
+type StatusChange struct {
+	// Admin Имя администратора, изменившего статус.
+	Admin     *string   `json:"admin,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Reason    string    `json:"reason"`
+
+	// Status active — обычная учетная запись; frozen — может получать монеты, но не тратить;
+	// suspended — вход запрещен; closed — учетная запись закрыта и не принимает монеты.
+	Status AccountStatus `json:"status"`
+}
+
+// TwoFactorChallenge defines model for TwoFactorChallenge.
+type TwoFactorChallenge struct {
+	// ChallengeToken Токен подтверждения для /api/auth/2fa.
+	ChallengeToken string `json:"challengeToken"`
+
+	// ExpiresIn Время жизни токена подтверждения в секундах.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+// TwoFactorCodeRequest defines model for TwoFactorCodeRequest.
+type TwoFactorCodeRequest struct {
+	// Code Код TOTP или, при выключении, код восстановления.
+	Code string `json:"code"`
+}
+
+// TwoFactorEnrollment defines model for TwoFactorEnrollment.
+type TwoFactorEnrollment struct {
+	// OtpauthUrl Ссылка otpauth:// для приложения-аутентификатора, обычно показывается как QR-код.
+	OtpauthUrl string `json:"otpauthUrl"`
+
+	// Secret Секрет TOTP в base32.
+	Secret string `json:"secret"`
+}
+
+// TwoFactorLoginRequest defines model for TwoFactorLoginRequest.
+type TwoFactorLoginRequest struct {
+	// ChallengeToken Токен подтверждения из ответа /api/auth.
+	ChallengeToken string `json:"challengeToken"`
+
+	// Code Код TOTP или код восстановления.
+	Code string `json:"code"`
+}
+
+// UserStatus defines model for UserStatus.
+type UserStatus struct {
+	// History Последние изменения статуса, начиная с самого нового.
+	History []StatusChange `json:"history"`
+
+	// Status active — обычная учетная запись; frozen — может получать монеты, но не тратить;
+	// suspended — вход запрещен; closed — учетная запись закрыта и не принимает монеты.
+	Status AccountStatus `json:"status"`
+}
+
+// GetApiBuyItemParams defines parameters for GetApiBuyItem.
+type GetApiBuyItemParams struct {
+	// IdempotencyKey Ключ идемпотентности. Повторный запрос с тем же ключом в течение 24 часов не выполняется снова, а получает сохраненный ответ первого успешного запроса.
+	IdempotencyKey *string `json:"Idempotency-Key,omitempty"`
+}
+
+// PostApiSendCoinParams defines parameters for PostApiSendCoin.
+type PostApiSendCoinParams struct {
+	// IdempotencyKey Ключ идемпотентности. Повторный запрос с тем же ключом в течение 24 часов не выполняется снова, а получает сохраненный ответ первого успешного запроса.
+	IdempotencyKey *string `json:"Idempotency-Key,omitempty"`
+}
+
+// PostApi2faConfirmJSONRequestBody defines body for PostApi2faConfirm for application/json ContentType.
+type PostApi2faConfirmJSONRequestBody = TwoFactorCodeRequest
+
+// PostApi2faDisableJSONRequestBody defines body for PostApi2faDisable for application/json ContentType.
+type PostApi2faDisableJSONRequestBody = TwoFactorCodeRequest
+
+// PostApiAdminLedgerIdRefundJSONRequestBody defines body for PostApiAdminLedgerIdRefund for application/json ContentType.
+type PostApiAdminLedgerIdRefundJSONRequestBody = RefundRequest
+
+// PostApiAdminUsersUsernameGrantJSONRequestBody defines body for PostApiAdminUsersUsernameGrant for application/json ContentType.
+type PostApiAdminUsersUsernameGrantJSONRequestBody = GrantRequest
+
+// PostApiAdminUsersUsernameImpersonateJSONRequestBody defines body for PostApiAdminUsersUsernameImpersonate for application/json ContentType.
+type PostApiAdminUsersUsernameImpersonateJSONRequestBody = ImpersonateRequest
+
+// PostApiAdminUsersUsernameOffboardJSONRequestBody defines body for PostApiAdminUsersUsernameOffboard for application/json ContentType.
+type PostApiAdminUsersUsernameOffboardJSONRequestBody = OffboardRequest
+
+// PutApiAdminUsersUsernameRoleJSONRequestBody defines body for PutApiAdminUsersUsernameRole for application/json ContentType.
+type PutApiAdminUsersUsernameRoleJSONRequestBody = SetRoleRequest
+
+// PutApiAdminUsersUsernameStatusJSONRequestBody defines body for PutApiAdminUsersUsernameStatus for application/json ContentType.
+type PutApiAdminUsersUsernameStatusJSONRequestBody = SetStatusRequest
+
+// PostApiAuthJSONRequestBody defines body for PostApiAuth for application/json ContentType.
+type PostApiAuthJSONRequestBody = AuthRequest
+
+// PostApiAuth2faJSONRequestBody defines body for PostApiAuth2fa for application/json ContentType.
+type PostApiAuth2faJSONRequestBody = TwoFactorLoginRequest
+
+// PostApiAuthRefreshJSONRequestBody defines body for PostApiAuthRefresh for application/json ContentType.
+type PostApiAuthRefreshJSONRequestBody = RefreshRequest
+
+// PostApiCoinRequestsJSONRequestBody defines body for PostApiCoinRequests for application/json ContentType.
+type PostApiCoinRequestsJSONRequestBody = CreateCoinRequestRequest
+
+// PostApiKeysJSONRequestBody defines body for PostApiKeys for application/json ContentType.
+type PostApiKeysJSONRequestBody = CreateAPIKeyRequest
+
+// PostApiPasswordJSONRequestBody defines body for PostApiPassword for application/json ContentType.
+type PostApiPasswordJSONRequestBody = ChangePasswordRequest
+
+// PostApiPasswordResetJSONRequestBody defines body for PostApiPasswordReset for application/json ContentType.
+type PostApiPasswordResetJSONRequestBody = PasswordResetRequest
+
+// PostApiPasswordResetConfirmJSONRequestBody defines body for PostApiPasswordResetConfirm for application/json ContentType.
+type PostApiPasswordResetConfirmJSONRequestBody = PasswordResetConfirmRequest
+
+// PostApiRegisterJSONRequestBody defines body for PostApiRegister for application/json ContentType.
+type PostApiRegisterJSONRequestBody = AuthRequest
+
+// PostApiScheduledTransfersJSONRequestBody defines body for PostApiScheduledTransfers for application/json ContentType.
+type PostApiScheduledTransfersJSONRequestBody = CreateScheduledTransferRequest
+
+// PostApiSendCoinJSONRequestBody defines body for PostApiSendCoin for application/json ContentType.
+type PostApiSendCoinJSONRequestBody = SendCoinRequest
+
+// PutApiUsernameJSONRequestBody defines body for PutApiUsername for application/json ContentType.
+type PutApiUsernameJSONRequestBody = ChangeUsernameRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// Публичные ключи для проверки JWT-токенов (JWKS).
+	// (GET /.well-known/jwks.json)
+	GetWellKnownJwksJson(w http.ResponseWriter, r *http.Request)
+	// Подтвердить секрет TOTP кодом и включить двухфакторную аутентификацию.
+	// (POST /api/2fa/confirm)
+	PostApi2faConfirm(w http.ResponseWriter, r *http.Request)
+	// Выключить двухфакторную аутентификацию.
+	// (POST /api/2fa/disable)
+	PostApi2faDisable(w http.ResponseWriter, r *http.Request)
+	// Создать секрет TOTP для двухфакторной аутентификации.
+	// (POST /api/2fa/enroll)
+	PostApi2faEnroll(w http.ResponseWriter, r *http.Request)
+	// Вернуть деньги за покупку. Доступно только администраторам.
+	// (POST /api/admin/ledger/{id}/refund)
+	PostApiAdminLedgerIdRefund(w http.ResponseWriter, r *http.Request, id int64)
+	// Последние блокировки входа после неудачных попыток. Доступно только администраторам.
+	// (GET /api/admin/lockouts)
+	GetApiAdminLockouts(w http.ResponseWriter, r *http.Request)
+	// Начислить пользователю монеты. Доступно только администраторам.
+	// (POST /api/admin/users/{username}/grant)
+	PostApiAdminUsersUsernameGrant(w http.ResponseWriter, r *http.Request, username string)
+	// Получить токен для работы от имени пользователя. Доступно только администраторам.
+	// (POST /api/admin/users/{username}/impersonate)
+	PostApiAdminUsersUsernameImpersonate(w http.ResponseWriter, r *http.Request, username string)
+	// Журнал движения монет пользователя. Доступно только администраторам.
+	// (GET /api/admin/users/{username}/ledger)
+	GetApiAdminUsersUsernameLedger(w http.ResponseWriter, r *http.Request, username string)
+	// Закрыть учетную запись уходящего сотрудника. Доступно только администраторам.
+	// (POST /api/admin/users/{username}/offboard)
+	PostApiAdminUsersUsernameOffboard(w http.ResponseWriter, r *http.Request, username string)
+	// Изменить роль пользователя. Доступно только администраторам.
+	// (PUT /api/admin/users/{username}/role)
+	PutApiAdminUsersUsernameRole(w http.ResponseWriter, r *http.Request, username string)
+	// Получить статус учетной записи и историю его изменений. Доступно только администраторам.
+	// (GET /api/admin/users/{username}/status)
+	GetApiAdminUsersUsernameStatus(w http.ResponseWriter, r *http.Request, username string)
+	// Изменить статус учетной записи. Доступно только администраторам.
+	// (PUT /api/admin/users/{username}/status)
+	PutApiAdminUsersUsernameStatus(w http.ResponseWriter, r *http.Request, username string)
+	// Аутентификация и получение JWT-токена. Если включена настройка AUTH_AUTO_REGISTER, при первой аутентификации пользователь создается автоматически.
+	// (POST /api/auth)
+	PostApiAuth(w http.ResponseWriter, r *http.Request)
+	// Завершить вход с двухфакторной аутентификацией и получить JWT-токен.
+	// (POST /api/auth/2fa)
+	PostApiAuth2fa(w http.ResponseWriter, r *http.Request)
+	// Завершить текущую сессию. Токены сессии перестают приниматься.
+	// (POST /api/auth/logout)
+	PostApiAuthLogout(w http.ResponseWriter, r *http.Request)
+	// Обменять refresh-токен на новую пару токенов. Каждый refresh-токен можно использовать только один раз.
+	// (POST /api/auth/refresh)
+	PostApiAuthRefresh(w http.ResponseWriter, r *http.Request)
+	// Купить предмет за монеты.
+	// (GET /api/buy/{item})
+	GetApiBuyItem(w http.ResponseWriter, r *http.Request, item string, params GetApiBuyItemParams)
+	// Попросить у другого пользователя монеты.
+	// (POST /api/coinRequests)
+	PostApiCoinRequests(w http.ResponseWriter, r *http.Request)
+	// Ожидающие запросы монет к текущему пользователю, старые первыми.
+	// (GET /api/coinRequests/incoming)
+	GetApiCoinRequestsIncoming(w http.ResponseWriter, r *http.Request)
+	// Последние 100 запросов монет, созданных текущим пользователем, новые первыми.
+	// (GET /api/coinRequests/outgoing)
+	GetApiCoinRequestsOutgoing(w http.ResponseWriter, r *http.Request)
+	// Оплатить запрос монет. Перевод выполняется, и запрос отмечается оплаченным атомарно.
+	// (POST /api/coinRequests/{id}/approve)
+	PostApiCoinRequestsIdApprove(w http.ResponseWriter, r *http.Request, id string)
+	// Отклонить запрос монет.
+	// (POST /api/coinRequests/{id}/decline)
+	PostApiCoinRequestsIdDecline(w http.ResponseWriter, r *http.Request, id string)
+	// Получить информацию о монетах, инвентаре и истории транзакций.
+	// (GET /api/info)
+	GetApiInfo(w http.ResponseWriter, r *http.Request)
+	// Список API-ключей текущего пользователя.
+	// (GET /api/keys)
+	GetApiKeys(w http.ResponseWriter, r *http.Request)
+	// Создать API-ключ для ботов и интеграций.
+	// (POST /api/keys)
+	PostApiKeys(w http.ResponseWriter, r *http.Request)
+	// Отозвать API-ключ.
+	// (DELETE /api/keys/{id})
+	DeleteApiKeysId(w http.ResponseWriter, r *http.Request, id string)
+	// Сменить пароль.
+	// (POST /api/password)
+	PostApiPassword(w http.ResponseWriter, r *http.Request)
+	// Запросить сброс пароля.
+	// (POST /api/password/reset)
+	PostApiPasswordReset(w http.ResponseWriter, r *http.Request)
+	// Установить новый пароль по токену сброса.
+	// (POST /api/password/reset/confirm)
+	PostApiPasswordResetConfirm(w http.ResponseWriter, r *http.Request)
+	// Список отложенных переводов пользователя, монеты по которым еще удержаны.
+	// (GET /api/pendingTransfers)
+	GetApiPendingTransfers(w http.ResponseWriter, r *http.Request)
+	// Отменить отложенный перевод и вернуть удержанные монеты.
+	// (POST /api/pendingTransfers/{id}/cancel)
+	PostApiPendingTransfersIdCancel(w http.ResponseWriter, r *http.Request, id string)
+	// Регистрация нового пользователя и получение JWT-токена.
+	// (POST /api/register)
+	PostApiRegister(w http.ResponseWriter, r *http.Request)
+	// Список запланированных переводов текущего пользователя, новые первыми.
+	// (GET /api/scheduledTransfers)
+	GetApiScheduledTransfers(w http.ResponseWriter, r *http.Request)
+	// Запланировать перевод монет.
+	// (POST /api/scheduledTransfers)
+	PostApiScheduledTransfers(w http.ResponseWriter, r *http.Request)
+	// Отменить запланированный перевод.
+	// (DELETE /api/scheduledTransfers/{id})
+	DeleteApiScheduledTransfersId(w http.ResponseWriter, r *http.Request, id string)
+	// Последние 50 запусков запланированного перевода, новые первыми.
+	// (GET /api/scheduledTransfers/{id}/runs)
+	GetApiScheduledTransfersIdRuns(w http.ResponseWriter, r *http.Request, id string)
+	// Отправить монеты другому пользователю.
+	// (POST /api/sendCoin)
+	PostApiSendCoin(w http.ResponseWriter, r *http.Request, params PostApiSendCoinParams)
+	// Завершить все сессии текущего пользователя, кроме текущей.
+	// (DELETE /api/sessions)
+	DeleteApiSessions(w http.ResponseWriter, r *http.Request)
+	// Список активных сессий текущего пользователя.
+	// (GET /api/sessions)
+	GetApiSessions(w http.ResponseWriter, r *http.Request)
+	// Завершить одну из сессий текущего пользователя.
+	// (DELETE /api/sessions/{id})
+	DeleteApiSessionsId(w http.ResponseWriter, r *http.Request, id string)
+	// Сменить имя пользователя.
+	// (PUT /api/username)
+	PutApiUsername(w http.ResponseWriter, r *http.Request)
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// GetWellKnownJwksJson operation middleware
+func (siw *ServerInterfaceWrapper) GetWellKnownJwksJson(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetWellKnownJwksJson(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApi2faConfirm operation middleware
+func (siw *ServerInterfaceWrapper) PostApi2faConfirm(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApi2faConfirm(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApi2faDisable operation middleware
+func (siw *ServerInterfaceWrapper) PostApi2faDisable(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApi2faDisable(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApi2faEnroll operation middleware
+func (siw *ServerInterfaceWrapper) PostApi2faEnroll(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApi2faEnroll(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAdminLedgerIdRefund operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAdminLedgerIdRefund(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAdminLedgerIdRefund(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiAdminLockouts operation middleware
+func (siw *ServerInterfaceWrapper) GetApiAdminLockouts(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiAdminLockouts(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAdminUsersUsernameGrant operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAdminUsersUsernameGrant(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAdminUsersUsernameGrant(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAdminUsersUsernameImpersonate operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAdminUsersUsernameImpersonate(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAdminUsersUsernameImpersonate(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiAdminUsersUsernameLedger operation middleware
+func (siw *ServerInterfaceWrapper) GetApiAdminUsersUsernameLedger(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiAdminUsersUsernameLedger(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAdminUsersUsernameOffboard operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAdminUsersUsernameOffboard(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAdminUsersUsernameOffboard(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PutApiAdminUsersUsernameRole operation middleware
+func (siw *ServerInterfaceWrapper) PutApiAdminUsersUsernameRole(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PutApiAdminUsersUsernameRole(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiAdminUsersUsernameStatus operation middleware
+func (siw *ServerInterfaceWrapper) GetApiAdminUsersUsernameStatus(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiAdminUsersUsernameStatus(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PutApiAdminUsersUsernameStatus operation middleware
+func (siw *ServerInterfaceWrapper) PutApiAdminUsersUsernameStatus(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", r.PathValue("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "username", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PutApiAdminUsersUsernameStatus(w, r, username)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAuth operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAuth(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAuth(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAuth2fa operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAuth2fa(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAuth2fa(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAuthLogout operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAuthLogout(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAuthLogout(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiAuthRefresh operation middleware
+func (siw *ServerInterfaceWrapper) PostApiAuthRefresh(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiAuthRefresh(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiBuyItem operation middleware
+func (siw *ServerInterfaceWrapper) GetApiBuyItem(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "item" -------------
+	var item string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "item", r.PathValue("item"), &item, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "item", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"shop:buy"})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetApiBuyItemParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Idempotency-Key", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "Idempotency-Key", valueList[0], &IdempotencyKey, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Idempotency-Key", Err: err})
+			return
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiBuyItem(w, r, item, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiCoinRequests operation middleware
+func (siw *ServerInterfaceWrapper) PostApiCoinRequests(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiCoinRequests(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiCoinRequestsIncoming operation middleware
+func (siw *ServerInterfaceWrapper) GetApiCoinRequestsIncoming(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"info:read"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiCoinRequestsIncoming(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiCoinRequestsOutgoing operation middleware
+func (siw *ServerInterfaceWrapper) GetApiCoinRequestsOutgoing(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"info:read"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiCoinRequestsOutgoing(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiCoinRequestsIdApprove operation middleware
+func (siw *ServerInterfaceWrapper) PostApiCoinRequestsIdApprove(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiCoinRequestsIdApprove(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiCoinRequestsIdDecline operation middleware
+func (siw *ServerInterfaceWrapper) PostApiCoinRequestsIdDecline(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiCoinRequestsIdDecline(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiInfo operation middleware
+func (siw *ServerInterfaceWrapper) GetApiInfo(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"info:read"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiInfo(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiKeys operation middleware
+func (siw *ServerInterfaceWrapper) GetApiKeys(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiKeys(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiKeys operation middleware
+func (siw *ServerInterfaceWrapper) PostApiKeys(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiKeys(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteApiKeysId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteApiKeysId(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteApiKeysId(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiPassword operation middleware
+func (siw *ServerInterfaceWrapper) PostApiPassword(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiPassword(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiPasswordReset operation middleware
+func (siw *ServerInterfaceWrapper) PostApiPasswordReset(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiPasswordReset(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiPasswordResetConfirm operation middleware
+func (siw *ServerInterfaceWrapper) PostApiPasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiPasswordResetConfirm(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
 }
 
-// ErrorResponse defines model for ErrorResponse.
-type ErrorResponse struct {
-	// Errors Сообщение об ошибке, описывающее проблему.
-	Errors *string `json:"errors,omitempty"`
-}
+// GetApiPendingTransfers operation middleware
+func (siw *ServerInterfaceWrapper) GetApiPendingTransfers(w http.ResponseWriter, r *http.Request) {
 
-// InfoResponse defines model for InfoResponse.
-type InfoResponse struct {
-	CoinHistory *struct {
-		Received *[]struct {
-			// Amount Количество полученных монет.
-			Amount *int `json:"amount,omitempty"`
+	ctx := r.Context()
 
-			// FromUser Имя пользователя, который отправил монеты.
-			FromUser *string `json:"fromUser,omitempty"`
-		} `json:"received,omitempty"`
-		Sent *[]struct {
-			// Amount Количество отправленных монет.
-			Amount *int `json:"amount,omitempty"`
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
-			// ToUser Имя пользователя, которому отправлены монеты.
-			ToUser *string `json:"toUser,omitempty"`
-		} `json:"sent,omitempty"`
-	} `json:"coinHistory,omitempty"`
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"info:read"})
 
-	// Coins Количество доступных монет.
-	Coins     *int `json:"coins,omitempty"`
-	Inventory *[]struct {
-		// Quantity Количество предметов.
-		Quantity *int `json:"quantity,omitempty"`
+	r = r.WithContext(ctx)
 
-		// Type Тип предмета.
-		Type *string `json:"type,omitempty"`
-	} `json:"inventory,omitempty"`
-}
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiPendingTransfers(w, r)
+	}))
 
-// SendCoinRequest defines model for SendCoinRequest.
-type SendCoinRequest struct {
-	// Amount Количество монет, которые необходимо отправить.
-	Amount int `json:"amount"`
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
 
-	// ToUser Имя пользователя, которому нужно отправить монеты.
-	ToUser string `json:"toUser"`
+	handler.ServeHTTP(w, r)
 }
 
-// PostApiAuthJSONRequestBody defines body for PostApiAuth for application/json ContentType.
-type PostApiAuthJSONRequestBody = AuthRequest
+// PostApiPendingTransfersIdCancel operation middleware
+func (siw *ServerInterfaceWrapper) PostApiPendingTransfersIdCancel(w http.ResponseWriter, r *http.Request) {
 
-// PostApiSendCoinJSONRequestBody defines body for PostApiSendCoin for application/json ContentType.
-type PostApiSendCoinJSONRequestBody = SendCoinRequest
+	var err error
 
-// ServerInterface represents all server handlers.
-type ServerInterface interface {
-	// Аутентификация и получение JWT-токена. При первой аутентификации пользователь создается автоматически.
-	// (POST /api/auth)
-	PostApiAuth(w http.ResponseWriter, r *http.Request)
-	// Купить предмет за монеты.
-	// (GET /api/buy/{item})
-	GetApiBuyItem(w http.ResponseWriter, r *http.Request, item string)
-	// Получить информацию о монетах, инвентаре и истории транзакций.
-	// (GET /api/info)
-	GetApiInfo(w http.ResponseWriter, r *http.Request)
-	// Отправить монеты другому пользователю.
-	// (POST /api/sendCoin)
-	PostApiSendCoin(w http.ResponseWriter, r *http.Request)
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiPendingTransfersIdCancel(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
 }
 
-// ServerInterfaceWrapper converts contexts to parameters.
-type ServerInterfaceWrapper struct {
-	Handler            ServerInterface
-	HandlerMiddlewares []MiddlewareFunc
-	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+// PostApiRegister operation middleware
+func (siw *ServerInterfaceWrapper) PostApiRegister(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiRegister(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
 }
 
-type MiddlewareFunc func(http.Handler) http.Handler
+// GetApiScheduledTransfers operation middleware
+func (siw *ServerInterfaceWrapper) GetApiScheduledTransfers(w http.ResponseWriter, r *http.Request) {
 
-// PostApiAuth operation middleware
-func (siw *ServerInterfaceWrapper) PostApiAuth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"info:read"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiScheduledTransfers(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PostApiScheduledTransfers operation middleware
+func (siw *ServerInterfaceWrapper) PostApiScheduledTransfers(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
 	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.PostApiAuth(w, r)
+		siw.Handler.PostApiScheduledTransfers(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -128,17 +1707,17 @@ func (siw *ServerInterfaceWrapper) PostApiAuth(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
-// GetApiBuyItem operation middleware
-func (siw *ServerInterfaceWrapper) GetApiBuyItem(w http.ResponseWriter, r *http.Request) {
+// DeleteApiScheduledTransfersId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteApiScheduledTransfersId(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 
-	// ------------- Path parameter "item" -------------
-	var item string
+	// ------------- Path parameter "id" -------------
+	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "item", r.PathValue("item"), &item, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "item", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
 		return
 	}
 
@@ -146,10 +1725,12 @@ func (siw *ServerInterfaceWrapper) GetApiBuyItem(w http.ResponseWriter, r *http.
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
 	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetApiBuyItem(w, r, item)
+		siw.Handler.DeleteApiScheduledTransfersId(w, r, id)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -159,17 +1740,30 @@ func (siw *ServerInterfaceWrapper) GetApiBuyItem(w http.ResponseWriter, r *http.
 	handler.ServeHTTP(w, r)
 }
 
-// GetApiInfo operation middleware
-func (siw *ServerInterfaceWrapper) GetApiInfo(w http.ResponseWriter, r *http.Request) {
+// GetApiScheduledTransfersIdRuns operation middleware
+func (siw *ServerInterfaceWrapper) GetApiScheduledTransfersIdRuns(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
 
 	ctx := r.Context()
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"info:read"})
+
 	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetApiInfo(w, r)
+		siw.Handler.GetApiScheduledTransfersIdRuns(w, r, id)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -182,6 +1776,125 @@ func (siw *ServerInterfaceWrapper) GetApiInfo(w http.ResponseWriter, r *http.Req
 // PostApiSendCoin operation middleware
 func (siw *ServerInterfaceWrapper) PostApiSendCoin(w http.ResponseWriter, r *http.Request) {
 
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"coins:send"})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostApiSendCoinParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Idempotency-Key", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "Idempotency-Key", valueList[0], &IdempotencyKey, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Idempotency-Key", Err: err})
+			return
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApiSendCoin(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteApiSessions operation middleware
+func (siw *ServerInterfaceWrapper) DeleteApiSessions(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteApiSessions(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetApiSessions operation middleware
+func (siw *ServerInterfaceWrapper) GetApiSessions(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApiSessions(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteApiSessionsId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteApiSessionsId(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteApiSessionsId(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// PutApiUsername operation middleware
+func (siw *ServerInterfaceWrapper) PutApiUsername(w http.ResponseWriter, r *http.Request) {
+
 	ctx := r.Context()
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
@@ -189,7 +1902,7 @@ func (siw *ServerInterfaceWrapper) PostApiSendCoin(w http.ResponseWriter, r *htt
 	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.PostApiSendCoin(w, r)
+		siw.Handler.PutApiUsername(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -319,10 +2032,305 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	m.HandleFunc("GET "+options.BaseURL+"/.well-known/jwks.json", wrapper.GetWellKnownJwksJson)
+	m.HandleFunc("POST "+options.BaseURL+"/api/2fa/confirm", wrapper.PostApi2faConfirm)
+	m.HandleFunc("POST "+options.BaseURL+"/api/2fa/disable", wrapper.PostApi2faDisable)
+	m.HandleFunc("POST "+options.BaseURL+"/api/2fa/enroll", wrapper.PostApi2faEnroll)
+	m.HandleFunc("POST "+options.BaseURL+"/api/admin/ledger/{id}/refund", wrapper.PostApiAdminLedgerIdRefund)
+	m.HandleFunc("GET "+options.BaseURL+"/api/admin/lockouts", wrapper.GetApiAdminLockouts)
+	m.HandleFunc("POST "+options.BaseURL+"/api/admin/users/{username}/grant", wrapper.PostApiAdminUsersUsernameGrant)
+	m.HandleFunc("POST "+options.BaseURL+"/api/admin/users/{username}/impersonate", wrapper.PostApiAdminUsersUsernameImpersonate)
+	m.HandleFunc("GET "+options.BaseURL+"/api/admin/users/{username}/ledger", wrapper.GetApiAdminUsersUsernameLedger)
+	m.HandleFunc("POST "+options.BaseURL+"/api/admin/users/{username}/offboard", wrapper.PostApiAdminUsersUsernameOffboard)
+	m.HandleFunc("PUT "+options.BaseURL+"/api/admin/users/{username}/role", wrapper.PutApiAdminUsersUsernameRole)
+	m.HandleFunc("GET "+options.BaseURL+"/api/admin/users/{username}/status", wrapper.GetApiAdminUsersUsernameStatus)
+	m.HandleFunc("PUT "+options.BaseURL+"/api/admin/users/{username}/status", wrapper.PutApiAdminUsersUsernameStatus)
 	m.HandleFunc("POST "+options.BaseURL+"/api/auth", wrapper.PostApiAuth)
+	m.HandleFunc("POST "+options.BaseURL+"/api/auth/2fa", wrapper.PostApiAuth2fa)
+	m.HandleFunc("POST "+options.BaseURL+"/api/auth/logout", wrapper.PostApiAuthLogout)
+	m.HandleFunc("POST "+options.BaseURL+"/api/auth/refresh", wrapper.PostApiAuthRefresh)
 	m.HandleFunc("GET "+options.BaseURL+"/api/buy/{item}", wrapper.GetApiBuyItem)
+	m.HandleFunc("POST "+options.BaseURL+"/api/coinRequests", wrapper.PostApiCoinRequests)
+	m.HandleFunc("GET "+options.BaseURL+"/api/coinRequests/incoming", wrapper.GetApiCoinRequestsIncoming)
+	m.HandleFunc("GET "+options.BaseURL+"/api/coinRequests/outgoing", wrapper.GetApiCoinRequestsOutgoing)
+	m.HandleFunc("POST "+options.BaseURL+"/api/coinRequests/{id}/approve", wrapper.PostApiCoinRequestsIdApprove)
+	m.HandleFunc("POST "+options.BaseURL+"/api/coinRequests/{id}/decline", wrapper.PostApiCoinRequestsIdDecline)
 	m.HandleFunc("GET "+options.BaseURL+"/api/info", wrapper.GetApiInfo)
+	m.HandleFunc("GET "+options.BaseURL+"/api/keys", wrapper.GetApiKeys)
+	m.HandleFunc("POST "+options.BaseURL+"/api/keys", wrapper.PostApiKeys)
+	m.HandleFunc("DELETE "+options.BaseURL+"/api/keys/{id}", wrapper.DeleteApiKeysId)
+	m.HandleFunc("POST "+options.BaseURL+"/api/password", wrapper.PostApiPassword)
+	m.HandleFunc("POST "+options.BaseURL+"/api/password/reset", wrapper.PostApiPasswordReset)
+	m.HandleFunc("POST "+options.BaseURL+"/api/password/reset/confirm", wrapper.PostApiPasswordResetConfirm)
+	m.HandleFunc("GET "+options.BaseURL+"/api/pendingTransfers", wrapper.GetApiPendingTransfers)
+	m.HandleFunc("POST "+options.BaseURL+"/api/pendingTransfers/{id}/cancel", wrapper.PostApiPendingTransfersIdCancel)
+	m.HandleFunc("POST "+options.BaseURL+"/api/register", wrapper.PostApiRegister)
+	m.HandleFunc("GET "+options.BaseURL+"/api/scheduledTransfers", wrapper.GetApiScheduledTransfers)
+	m.HandleFunc("POST "+options.BaseURL+"/api/scheduledTransfers", wrapper.PostApiScheduledTransfers)
+	m.HandleFunc("DELETE "+options.BaseURL+"/api/scheduledTransfers/{id}", wrapper.DeleteApiScheduledTransfersId)
+	m.HandleFunc("GET "+options.BaseURL+"/api/scheduledTransfers/{id}/runs", wrapper.GetApiScheduledTransfersIdRuns)
 	m.HandleFunc("POST "+options.BaseURL+"/api/sendCoin", wrapper.PostApiSendCoin)
+	m.HandleFunc("DELETE "+options.BaseURL+"/api/sessions", wrapper.DeleteApiSessions)
+	m.HandleFunc("GET "+options.BaseURL+"/api/sessions", wrapper.GetApiSessions)
+	m.HandleFunc("DELETE "+options.BaseURL+"/api/sessions/{id}", wrapper.DeleteApiSessionsId)
+	m.HandleFunc("PUT "+options.BaseURL+"/api/username", wrapper.PutApiUsername)
 
 	return m
 }
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/+x9bW8bR7bmX2lw94Nz0ZZkJZm9o3wZxVEySnJjX1lGLhAFBk22JI6pbk53046uYcAS",
+	"43EC+1p3g9lNMJuXyeTD/TBYgJLFiHohDcwvqP4L+0sW55yq6qru6mZT75IJDAaxSHZXnTp1Xp9zzsNS",
+	"xVtpeK7jhkFp6mEpqCw7K2X8z+mbsx85q/BfDd9rOH5Yc/DvFd8ph051OoR/LHr+SjksTZWq5dC5GtZW",
+	"nJJdClcbTmmqFIR+zV0qPbJLtSp8N/Xne/T4qhNU/FojrHluaarEfmZtdmCxPbYfvYiejlnsG9ZnO2wr",
+	"esza0deszTrRerQWbVjROuuz/eg522N9i72KHrOuFa3hl7dZm/VYl3XHTMupl4PwdjDcFtzyimPcRMN3",
+	"FmtfGPbxA2tHT1mb7bO+3A1r21b0FBe+GT2zWD9aZ/usCx9E69Hz+Htdi21Hj6MWe4lfkv9ibeOO/tj0",
+	"wrJhDf+HddkB68IDdlgbadRHEm1ZbMuK1qJWtM72WNe6cnv++hvKo2tu6Cw5Pjw7qHgNOvla6Kzgf/x3",
+	"31ksTZX+23jMPOOcc8aJbW7Bj0qP5PPKvl9eLT16ZJd854/Nmu9US1OfAV9wyko6yvfZCqN9Lp/j3f2D",
+	"Uwnhwep70hv/kW2yfdaO1ois27DtaD1qsVesbU3fnL0an8iUVXMXvSnfKVetq9Z4uVEbhz/YVsWrucFU",
+	"4Ljy7/Df172aay+4wbLXmLrbXBUf3W2ujj8EAj0aW3BLdslxmyu4RfFs2JB8IGyTP6D0ueE8pysVr+mG",
+	"t8Jy2AzS2ytXwtp9x/p/j/9sESdFT1mPteFOtKKncEH4P+nUWTdai56/Yy363r87Lv3sgPXZr/BNi73C",
+	"a9SKmRA+68Fn0TPbYj3g3x7rWNE6XsF14Kfo+TsLbtAMGo5bdar0yK3oCeuz7ZjVOtHXrMN671iVuhfw",
+	"b+UskP6xFz2OnkXrrG2xLr2Xrjbe5wO6/toSdXoTaUp2iTYLhBarhBPAhZhJ3gyX55w/Np0gTMu8RjkI",
+	"Hnh+1cBpP7F29JgEEfDZPmyqjfeqw3pIrC9Zl+2xdvQnIZCkxJGPNaynGTi+EDqJV37HDqINcW7P2Q7c",
+	"ZzyYDr2+2CoSb0xcTfl6O16l8Roi2YKG5wZOmm7OF42a7wSzrmEX3wCH0E5+ZV22Awdsffjp/FUUj3uw",
+	"cNYmMcU6bC9qsR7I9eiJWUz5zqLvBMvz3j3HNQqEbeBk4GAiWPSM7VrxqyTV4tsAC+jCn3rwfdZnu/Bp",
+	"G/hT+SF8ZpTJoXkl+g7la3UJxfbwMkRfsy7dIdaLnrEDCy8VSO3H0RqoSfM5pg7p+nLZXXJu8nPM5HLX",
+	"eXAzm9F/kFQjKhD3Gbfu1as5D/obHSdsbvDDEmypPtnWFvx55r5vc1bO3HfOVfuBH33HAumTc+uGuFHG",
+	"lXo1N3N95RVQBor1obD9IawxfimPwYBrlFcd3ygTTTR6bltRC0wcsHr6eOYvWZ+kPlkmX6G0eqaLd9N6",
+	"fKcceK5xTT5Rcbh1xYsiBleNpS7b1xaEeimWEmhcdTWppC4m8Or3xfEkVvMXJMA2a2vvs0Cfwyv77BWa",
+	"ME9JTnTBUCSLcY/t03JYT1MnuUcYSFtCqErQivAhnCNaYlWnUq+5qCiJR0ya0mTCxTQXPGELppVnJVeg",
+	"8qzKjMZrgd8kUy9bbGVdXZD0W9wT6Gg2OIhb6zdvWXi8B3jD9zMFeZZx/TNwTeyAFDCwhdmFJhXIUmQj",
+	"VDNgeEVr8SL77GDMYv+TddgOsMJLVFw9dBW4XrIt0AVsX5pJsAByfPqwj5WaW1uBk752ylY9Nxv4G7KP",
+	"VZF5BUSf4fKQ5wQKcR2OMD6CdvQV6+LRg4HxLHqiXGCz9ZAlyfJNrQECTS4jesFd1iEEW1J0gQ0M4oas",
+	"IpXZ2mMWfRzLjHUw14EsbW68dPkCkDfBZSCrogsEgu110BiDe7BNLuZK+YuPHXcpXC5NTU5MIDeJf18b",
+	"JBOyREA2K9yqLDvVZt2pzvtlN1h0fIUhEoT4Mx48v0Pg+uDNaYFti1SBv+NRbNFX+mT4gQDd4QfJOmzX",
+	"8pvudAiy3G/WnbGSfSTGk8eaUCUdvJpIcPSNUNr0SYojh7At4CPYg5kvK+XQWfL8VeNCiA9fwrvIUM0/",
+	"xzcnDQy34gRBeckxircEpyQ8RbwCL0y8kubqZt3J4GmgQJdiJK/QwKSbJG3vLSv6Ev9ywNm64nuubWE4",
+	"iLyH2/PXE4u4lrEI16iGvwdZS26S5BbwQvF9PXGnDaQtpndDD+zPQ4gWTa4cgKDpRS3B+CoDdVNe+2Bj",
+	"lK9K3lHT1Zzxfc/P8e3g46AY4/TZpoX2XZdtguNjk6gCt/8ZF5Dwbe7r9zF4A6fbKujgfOCX3SJKJF8n",
+	"FhW+PbTKuqh8Y2Xc4yIZrngnekxuI/9P+jsXUZYIV3bYQTFnvIggnV1pOH7gueUw29Ep1+veg0/9Wujw",
+	"c1ssN+thaWqxXA8cO7nrv8JyYZnRV4LHwE2nu7KBR9YVdgfXRTxglCCEyqx7UUthVWXrdz2v7pTd4ucw",
+	"gOTAgRQxVmQJ3rZttg1fYb/CYgYTvxDNa54rIw9HDIEcNvxRrt5w66tGf1sGG15RKB1FzgZG0rQgOrkz",
+	"IgxItiQEHjrmcyoW4YjWrEq9XFuxypXQlppaufddMEHAdD2gKB/GQUj8YrymiDS7h6G+mNYKQYwH5y56",
+	"2ZIN4rS/rwUh17v6h75TcWr3napmOh/FdtADTr1C5uqRzYKUmlr0vZUjKypynjXdlHCex0pHtUESmi8v",
+	"+pI6dt1tsUuB44bHdo6aTXemZ3kcNt1J2jEpSg0O+Qw+TdM3MOFS9PzUCGyxk1t26tXrGa/4Xs2hRC2p",
+	"cqRIRXVE+T/MxMT8kjji6Ekmcccs9gM+ibwLcCTgsbht85JrqsYKzCEybtdscw6J1gR/sC6dpohaZcps",
+	"IGaH7QrawpIxooWhMjIgWDd7VyU780ZWV2puNg8Op0ROK3iao5r/M5uCwHsvj6SeM82oXxRnmafhsinX",
+	"xwCBangdUtjW3PuOK3Rqxvn+sVl2w1q4Wlh34kXZRptvPRHAU7ie/mIwjLrsVfIh7WOTPrNiw7Ohs5K/",
+	"1+wlDzB84FM7fpTJ1vnw049MnsCSkR33pa5Zx3NHEYSOGsQwZ6rv3ZoWoei5W5Nv/+aNjHt133SC8Fy8",
+	"6HGOUoQ7ISJz46ObxqeZju7/sr1oDZfX4xnOtjV3a1pJ64MgvFsOnN+81fTrxufeq1WNomQ7lTPlV1IF",
+	"cWCMF3JXeA/Rkl4jV+ilCCajqDyw7tWqmk1vXku4ms2g8Z6u3PjoZkz/aTP13Qx1tB21MEs9LJ2aQXZ4",
+	"vScSFIkQu/FBJpTMjxAfp5Q/WY/8ERbuNH9picsANKRTzbgGtxyDT3zPWS0eBoe7NCj8jQ80reBjp7rk",
+	"+DNu6K9m+LZ9rvP34Fh+hTQvEBgiusBdyIZPMZtAfjn3a6M1Dqlo56rUgrbtd8LFj091k9bAetFazjts",
+	"VPAoO/4k//ocXfKOAs8iYZKGZ6kWMSzN8RtlP1zNVvaZ8Ae6HiCxUFFAlKVH2X5OKOtKLQiaZbfi2BbA",
+	"cGwLXuA9eMMin1tgrfr0G6EHe3F8ShxT97jMCv6Td4cyElAMgbSCw9nC6NquITrFOoImbEuB0q0b1669",
+	"+2FWJlhuquaGv3nLbGlypWcQu65B7noNx625SxzTRLkLkpr8UA7UMO0r1o82pJ+FjpF2XWxrCSKC9DQD",
+	"QWwr5IkG+opmctsLbqPpV5bLgSM+7WMA5hVcTNvyncWmKzBPKkH1b3Ztq1z9QzMIVxyxEliD7iIa75e4",
+	"Klu4KgiXLrjLXl0AqDRfAh8g/ZSUR5ERvoZN1B25QX0XaW9F84Xih20vuGwnTVx2IPntFU9LgTWBH3d0",
+	"tBY/9ZJdwvMCRuLnAgKLHwIGc4DkJbsUU7Rkl4Am+CFuxYjqyjey6LE3FouI48TRKkGPVvRC2SKPq6Wv",
+	"2sA7Y8qs42VRMmmaaNRv6yDA5Mde5Z7XDGfu86BHDqo3O0y5icy1x7qcPHsJRFuukFss1+pN3ynskQO7",
+	"tdAFfhr7xn32KnomJMOeJheiVuYC02df9yr3nOptN6zV87cMT+uzHjrAXNYciQpBBmT1v2BTZEImn09Z",
+	"zauDMEji2s3evAp2KYHF3rF8J3DCOwJ+hClP/EutAbef7nAcwAfBs0n/YG0VnRUb7YNd+FgJ64thbf3+",
+	"KyjDWgOvsrpU+Ydaw3i5gyYx92FNBG1pg+1LOrj4tQpD6+w06CbeWFy865VzgHjDoAAEXnZwEgpE9QZx",
+	"K+nUqMVTAc9ja+9I+RCxsZq7dKu5slI2xc/LrueurtT+3aneHoBwtbn/qQeYD3IhxNETCZThiItXIqCV",
+	"IpfR/ik3ah85q8Gcc9+751QzMHeIIh7KvMNfvLt6qNCVrawczTwMCCtkIA2kkMG4My3+YuArGQLBJB6p",
+	"OhnHZp2M2wRSUgvY5blPekTEECPKxfVR3gURIuVKVvyyeIpFN4kQsbXN+gqtU1yiwqccNzzMSjKTBEdc",
+	"TRDAKnK5Nqi593hdgSkzQMxkc2s7kdKJV9eh8EjKRjeFyhVJ/cBphFmh8gSOzmBoyteyPkA/4jADVjzk",
+	"xNZjR5aTuVjA3UxlUEuzZiBsHlg/WuPYn97x4YhnwShU1KRBqOoHrh2BKgsMrJxx19JslpKWCthTSjxF",
+	"XJpURgxKD5zwuucu1vyVkweoZ2SulVR5phVUPBs9CJ2ubf0w4PTvThKRfpPQwQKQNyQqXclpHkd4RM1O",
+	"FwWnK1nQQ+OyNckgkdkJn9dOyBpjtOXrIdHaThjWnWDQ+jRY+iaIQgAcp1YYPRuzADhpRf+B8vqlkLMH",
+	"Mnie3KoCVI431xVAxWEh5/oOfCds+q4oGxNqLgmpisN15py/ehyw3OixkOu2AFz1NQzsvnY4imJdcOFP",
+	"bJP1lXem8+Ib2o/eSVA/ddiivjO58Be6CxSj8OnMsUwNYpP1OpeoRKzS57lJ+QIofXmJ7BT8LwOcH3Oh",
+	"SULMORXvvuOvXveqTmAEyugfF6jI6lB+eZsTNFpLH6CwgqTBmSZKXnReX5V5W1hIluOc5RWa8V8rACjb",
+	"YH+yXc6iuVV6WhitA1TB+/01hdgAJanRpQiSUFt8xu6bbhHP9NBOYgrrPaxq4XdjKP/rmPVRhsqBwvK5",
+	"pjvIDUqj3ZvuII3lOl/Ao4dWWGQdr+vlJigHEVmIQA/wH3k1Ht3GMQvyclS9Qr5L1OLegSw56cMzNsn0",
+	"AJ+UF6GY30gQd3DIi2sPARrPBnIPC8AuICPTgjE/lGM8SjNUugCe9RW1CojhUrupE+2xjkZZLmGScZ+a",
+	"GzQXF2uVGuQe4EYHZtRfza0Fy8PxfiC2/L5xTz+Q+9iGfdBa4+BxrK9fUcJDjXDyD6JWtMb2hjIy/CFv",
+	"b5ZdwlWxqGZXuFf0nLAt715cPy91f8pPhRAKBAeFjVPgEMeMNoF3j4cZi9TiaSejUkZu2cjCvKnBMdVg",
+	"HaUURtqX+Tj9Ey+KqTr18qpTLYCR/yUOPqSqMHgoZUtPJeDf4mwRGjoHnICU88BPtkV4KKcAJQ35Oiry",
+	"0yBJ/vF3TNq3ozVuImMJAy0leh69+Md+oRKgi1sGc4uiHodOmXFw5jA1Q5Wm7zvmMB1yyhpF+6leVNMW",
+	"KS2v5HbMLKMBGofJgkVrYi2smzAMWDttDeAiJFsPQYwhUVr6ssw4LapHCIoAs2qN9Nu1vBEilliXr0am",
+	"7+O8A7o5aLErZ2FMtJABectx3EGnkXz4y2QBsgoY6nFg8/PoBfe2MdEQtcQdKXYOEC2aXjKyJVyiq/jZ",
+	"MVLDZJ7Fa+AJQ9VbVYinsnV8ncy3O5zz6tklU75Xz+4LgfFn2fwlJw6n5jtLNocxD9Tn+O6MRVNroCNn",
+	"ELs62ouiLgQxbmG6Nt9+yi0Z13oYpWwVEW/I8xTxO9TE4zix4La6766aUlP2flzIrpyU1knQcZDDMv/A",
+	"e79cCT3/+nK5XneMlK2Ij+YHhskpQ4u2H5lB25KROFoAu2OVm+Hy+ORieSwHUn/YCrmcFRQrnktQNLF7",
+	"dX35BPWq2WKk4lWdDAt625q/MS9QvbaUmKDJY3R0j2tZjI4NjI3lixVcS+5WZlzfq9dXjEAhL2zAad72",
+	"68YsIhT07iNiin9xanw87q0EW1Pyb91o42pGACy+qnGDM57JVeoHZS9A/OOe9a9zV4lEZrnlVHwnw6rC",
+	"im8wD+gwCHj85uRgavKH2iphcon7sbeU42wd093DZgd9/hkoYnkNzXKtIHseEwMmb1gmR4JhETfB0ym1",
+	"HFdoDqxhGqjnbAkTFY301ixqsSX8L9EE7CXra+Hn3ECfqsBM9YfHqQAEPdJ0JNZv+rVwFeJVK0S/aczd",
+	"Qv+2NAnVDol51Wd/kV9JtugzdOjscx8Wo9vRBmhnG2X0Ogr5TQl160Yb0ZMFl22yDr6TvE9uxnImFHGF",
+	"6InW/MOKdwVaPrEC4aNQ1xZZANERbSK3QF0suMLX1jJSBCxAhufBd95fEnt1at3brlDrmzco0QNGSmnZ",
+	"KVfR9KNUbunfrgKJob9qzBW4cmCLd52y7/jiZO7iv94XxsaHn86XKAiHD6JP46csh2Gj9OgR4n0WPfh9",
+	"WAvrDp2pNX2/FnqIeS/ZpfuOT45t6drYxNgEvBnwuOVGrTRVehP/BI1xwmVkl/GxB069fvWe6z1wx//w",
+	"4F4w9gdu1yyRTIW7iWWFgJEofeCEnzr1+kfw9Q8f3As+JOPE50XX+MjJiQnSjW7IVU250ajXKviUcfF4",
+	"ugYFKjOgvAN3nozRYAADuhj04gLlLcKqPLJLbx/jKvSWGabFfMMdr8ecqTbQuZadMdrkvz7mMr09pt3e",
+	"0tRnnwP+kEPrwJpvISN2CSGrluB0Na3bl2DyVI9FwBpdAfK9QS9DTTG5WB6vEBoDJa9n7MVj6MwrW6gc",
+	"Kq9nsR9jXKneJ8AsUSCM2BNZi5KdYMKbXhBON2qTi2WOLIl7lb3rVVeP7diNNuAjXVKHftN5dIIXQM/G",
+	"HuIevHWq9+AHCpdHj8ViuNmGC7l2ygtpy75DXaHkWI+v5benuBbVDqWWu0o7a2mCbUM6LnoSfYlgBFq3",
+	"KOPNSiYTUO5XzDtobkX7AsrAh5qG/OzzRwmhqJnF27K5XsrI3+NYS6hXUenSFQXWBjoT1jabztg84Yur",
+	"Wp09Tx5owrVaC8p3606OcP0bWUWUeZWGN3UxG9qPGtJ+zxKj7/FVn0sxOhJ3F0zc/flwgoynTw1i7K3J",
+	"05XWYHh9xcu8oco09hXzC5dgqIFols7TgyLZl1kABNeSvAjk+Tkn9FevTi+aG93+F08W7oDYU9HVajxM",
+	"A/mpzfe4/IuXzPtkxYRLhdAeXTYl8o0SfjsNfeBgyC1HHSRsAxmroD+k+q2QztMnZMSVMDmRI0UpbllJ",
+	"X2BswWU/KZzChRmY+DtK0ULO4wmsGddJqmqZ/OUs3UNByZP0IU3xz0MZ0iOBPrJMTULlZ2HNZ1mkchiA",
+	"iY6Y4B8w1GGApMGc2XgdG1GMP6xVH43zyuohffy4GFpCR7DZNTsAiIat93ngMlLvnMCxbXpNNUTm2CZq",
+	"Pf4iUxmaXqu24JJNzJPgIBO3RPo5esyjlG0QQnwZ8TujlqYChX3WMsjORKQhW1BNV7FTMhB4tjonytYb",
+	"Zb+84oSouz8zJpOVRqExkboJEnHzX2t1MCZCjBCpiwOMsi97bDarCnxwMfrnJ2Pj6/jiU46RqB1YzPIL",
+	"GOc5e2ms3oiecZF6yo6D3t26nerGde5UzpunvJZttRhRZgoJFsaX9NYpLukntV0I91bgsu5yO6h9BppZ",
+	"X1OscBMsTijkZFO1NM6b+gwmhW3caKb4lKfLp+S/0dQI21Zlyk6ik0jUosKsuPEk+Gia5snBsVDl9RdX",
+	"AScUYJ9owhUl1T01/gjycjVSc4nvHlEKF0qPah1J0oU7F9zwPndS8LIFeZM5fkNXFgV9q/m/BQI0J30r",
+	"AQoYjD8UNbiPxqkDUrYh/r1WbQg4XyqVULNja0oJI2tbos2ZGBCSmB8S99+PcTRbmnk5lmvmAjojEAXn",
+	"H/AGTglT12Caau1dsgzUJILkhMxRbXDAObNGv0+UEiQqes80iM0xMuTxKemF3shcvYjmqmEoWtpwPQOz",
+	"9ZfX14z8QbnwSkA+fVIv9JqXM9BcccgpN5+pzryU0Wqe3rz2tlKVYMnWgWwr2uDALranlXbkN/u2gKst",
+	"lE6wdVG08kKBDiPM63DTMKwrH8zMX9Xbpr2hxJmSIwRFLYYE3BxkKuAFN6GBbSu75Sog1liXMtyWCBZq",
+	"Jg92XJeRMdm1bSsec4z8YsodwG8XXPVXeT3Ct1Bc0BqjjUHxMc1wUAbXXDjzwTB055SNCMMImouA/ck3",
+	"EuLGB1kN/BRmI85GyCbCZZSiYionHRkcF9nguHTQJDk2NnquaAWR+8FT2WR9svqLTsw4A6VPSSQlppSu",
+	"xMbMLzYB2EZF9Wtce6w0ect0RVMJI60YmByQ5C+VLBRoXdl37aXuGLOu3rqYq/9U++8xSzTGo97L/0FF",
+	"2pnqeGDr8Snpk1tXVCd+wY1J8oZ4DuC0rSuIqH+JsrLLemBnZKXkEgGRaxMTOhGxUd8rhRJRK2svG/aC",
+	"S8Aw0fsRuR76b7KuSb0rMURNu5OTe8KK/cQDlaqnPopTjrTR5dBG/zt2MxIiWhtVfK7UjsdbDOchFmKx",
+	"v0vFRWBjUriA99VKdWtP9QWNhR5vuUF9XrKaVGhfRX8ss0EquHiy40cHfcN0C9MDctOQck9ILwmhbauT",
+	"vkm+3Hj//XdvTM+9N/vJB3duzX7y0Z3bt2bmPpn+lxmb1Jy+10FNxAkRTLVcuBa9AbFQtNnthy1B4h1N",
+	"MSt9GRZctcIMvt49NL7Owhar61kNX/pUBdwXnaHjZj4AZvtRCGb8jjyGLv2B2x5RS1hnCCl5QlOMcM0d",
+	"woVw7znu7kY4EXxGtKHRQSev2hIW7JVu3C0WKD+UIy26b184LzrZD/2UXWhD1/KjxyIvtket7GfkQY9C",
+	"9scUshewE+22xE4T/VCK2pjiGoyGGxJ92fhO37Gpv+vls9y+Ve9nriqOWqRPog1E+JAfTIO60OzokS49",
+	"C1NOtNZpNE1W3F/jtjp82pCepk5UsXaofSJ56Oj+85KGZ9jdhYcdtuQO2wLEhBbJPn5fzF1Sm2J22MGC",
+	"a+q1qnUhiaP0PT3OAUeBZxx9JV8JR5/IZWQZ2Cb13zRrf+hidOE0f6L70iWt9dJ6sI106cj/PydaJB71",
+	"yKPRA1uZnYGWiHukDMIuauKQd0s5t3HHPMZQut6Moosj6XJZcl1KwyUVIN6XKpKPmcb/KSGdFwqEQZ9N",
+	"u3siAsnOsEi/Tc6ZkJ38sw6Zios6KiiDaBHbfLz9bqJzLTo2fc4lylMgGCh3gY/CisysYRcDF5h8PgS8",
+	"duHRZNBqjlq0oZ5aOgbSAWvZ4iBAQNckW0IlcUqmZe/xuJr65rQhHb0gkp6IIX0qmuNETGm9J+jlNKY1",
+	"KTJCnY4U4yUwuwsqxpO1vnm3OZFTM+ccmij9TkJ8waPPKP5Pr84NaUqBOLCYHdl1cmLyBJriyIa95vvU",
+	"jt03RWBySyIH6ZGquz9shykwRYp1J1U7+LNNkZ0kKwgWoMf2rGS+1NCjQjYYXnBHAZ9zpHnykmaZM9qU",
+	"dJUhsXZBevxoOGyO7VOm7w0zmhqnZh+iY9CCO+oZdILNN/8zr0dVNzn1DbyYRM4Csi7/S4zNSAlhNde1",
+	"i6ubvj3/+zvTt+dv3Jmb+WD21vzMnK0MWKCFD2oSku0MKr0GZZqFixHw4PBROOmHxt1rlgOI3hxEzk8p",
+	"V1CV7odqY20pzewO2RMPqxv4yBLq7kFP2otHMOf3KtXqPfhaijVCLtpMpBkuA2VPuN2e1pH8whtfr73u",
+	"T/cZlDn2IrduZCWMOgG+xlr9WyXQ2BVt4emEorUs12iA1u3Ax5pFQE/W7YGkTq17S14zLOSUf0xfPZ7w",
+	"3vnxVS4dZkdnLOSWPZjoi7idOJKNqFbpSkfPUkHuDjkG0bqIhWtmhzprdVBDOGA0PhS4EKfx+calE+sO",
+	"ps5ePse2yAhmMmjuNSo/rTEpn4DOkXuiRGovbhpF8MButMYl5fMLLQ/06/9jItDlGwnW5oNmRK9CwJu3",
+	"9OLxrYTnYXqQos4N1Bzc3zDWRHebq+MPoUjq0QAIxrvN1dnQWSmUN6vRF4vnzGxDeSGfPNNFFoMC4L7U",
+	"vnJUDDUCSLarTZfm40DCX9XxGTy4CJ8pDvzkWxbma9dkyXvGoGCLBtYAtdUKfsz2ynIDUZKgpmqlRFFd",
+	"ekRtthRHqG+Y+DiWNWtmtuqsNLzQcSurfOJMTGh1buvbb9tFYS4juXgho7GiljU7+riTxDjYOR5YJtb6",
+	"rcnJU9xVMVGQr14k6jw1RFWWA3fZgcUVWJ8dXEB9lLJPbX0I2GclKDieuttcLSVt179gxlF4g2p/SN7T",
+	"MDF1+YurK82wHIIMIatNqJNKPGg8yAkYfqtIaOTErhzfq4UDaY68CGFGX5PPpSCIOvBv/B2yPnnC8Uhu",
+	"vZaOirGScdc+211wr9+Y/eTO3My/3p65NX9nfv5j6woVUqc7zPyPSaEi2lCr/UvOZVOAN5scys/HJivt",
+	"NicnFtyYBIhJx5JAlVHBKMiOIl5XaX4ydvt1nDmqvGgoC/74xLGyAuMV+lbT/PFsnZGauihJQ1Ol3em2",
+	"uOf6hL0Uzar20eummt/B9/Qy6g2Q6sFU4LjVkgkFKrbPox4ttcVGfjJUUytGJTJecyveCiqaXPdEFYKz",
+	"4ien0TtCE0mXq3fEZWNj6Ic25TvlNBf/qN9rAUiV3d+0bgl7SmAPzYxM7M0LW4C/HpvarGRwvNcMl7zh",
+	"OP6G+MmI40ccX4jjfzK3E0poM4Xvbc2g4sku5SKA+2S+BnBHbCu721DGNcDxJeVGw/fuOwND15r4r07z",
+	"XxWKVVXPTV3QIOv23IVhjJhjRVImK79V7uqyLazclYEuLcAwCuFkeL7HFMA5VSy45u1nFvTHcLg4ZiPN",
+	"yDwlewZegrojEXfq8yN7yk+lj50N9uk2KPuUgYvXzVX4UfI0DzSpcfpYbGBMX+nwkxGBt2UdkXwE7/ok",
+	"YvAEcdOOhbruWCSwMJWK2f1cFVR1KvWaO6wKeo//6nVTQedJbo9k3EjGnbaMUwiSL+VikQNG+gBfbxa+",
+	"cpJdpt1Fb4SMGLmUR3UpNfQbNs77EolyILr5WZqHwNrRE9s8ZkwrEUdoa6rP465yh+45q4N6SHwEXzmN",
+	"+Mj0zVlIw49CI+d5UCvVXEIdj9apsoMtRZXoXl78GumSkeGUSeuM9lbIx6D193ldfMf6t6uwlI+cVSs9",
+	"njKr1GBLYRludPAypIS/DRVGck3p+Rzpp/KFc+Q93FN+HJtRK85+gGmuBoREPLSPwOY2NwN2LREqoN5c",
+	"OclMeUtPKolJt/OM8pdCNOSgHEZpy9dWJqnDo1WpJMErm9QdH0dzknqF2/VSXFO2S9iMXEQwaEp0bElo",
+	"1R0a6qNfxvfw7/w6zlZP0YvNEqO08R3Nv3tdfU1JlMvfS+HH+NhT16IAszfKQfDAy+0m/jeh1IQejBMa",
+	"uxyhS6pxzEoLR+VjS1q+iTEXe2CwJiqFCPiglN2wtlJPTNXAa+hw86AuqGbqWl6oH46hnw7viJ2ld28K",
+	"Sp2Q7l0uu0uOeMmRW9ho3RC0rlHnQGXm8FDcphngvsjbu3FmLMFv507IjWruRjV3x2XrqA1ydK4vLNTH",
+	"fSdw8ibqgvLgHZDiEUCZQxb5TAn8/o68kUrVRbTGNiVyWFkzpCI0j4qDSHatNyeUqYf6OALS3SSlu4Sd",
+	"onFQooUEQkfQB9Uctbwu78+xgxA111bmIhUYMLXgqtyqpWmTsFXWYR3rTZUYVEnPUbF2aixTotUFDlpK",
+	"Pu/aRAHVNIfHfTL6SXvHUOppchC8mZfuQVnQufTnzolUTyJP9Nt25AYrI/l+wjXVCRCoPD1NUo6VMqT4",
+	"eMVzF2v+ymHnyyYL3CxuQB/JWi4kkK7zhZ+CXOKvutzWc2whI/vg/z9VJ+9IhWwnzOik7XyRb9QvGphH",
+	"KmGTn8BH/kmyRC3l9rG2euUcF4bgzPtlN1h0fDVhktyMMlSiq+RjEn9NDNCy5Rh7OUDeQmO7i5OvdoEY",
+	"YK3vWepsRm4IvhjLmDl4M7nq08jhJF46SuZc1KSkmulJT4Ej3zE5TixnDpxSsED3Lp5YSsgi6pKsT3yL",
+	"nuVcQgIZVWBkaH0gxih5F2ar1+mHFwxmlLpeI6jRMK11NXRcJtior7q/rKOCjfLB2mcANtL3pAySUrWE",
+	"tjNps9hUobVJbQ72eNtR8Y3oGWkuKhXdZ/1LKf8GIJK0YEtKCJI9ofFUN+4x2+JFXakRlp2s8i3fWaoF",
+	"3LPKsOW/GzCiEuTwvnCuZO3sNh4078YEsZI38ZvWm5M05ekAl48JdapW4AiSDQFH2bEEAJX1qM8hKIBN",
+	"bC2+ZS+4mOv+MnpsWxzQ3mF7FNyBdk8i1NEVzdWo4niPxkXSwAL8VDWCeKseMoXoRciSyMjifdgu/zt+",
+	"TG0LHUzEe4u4/4LLNskhbckZ13heL+NW0DaPwIjNHtCV2SFIDdvhLKYEPXMT8XPiFM9JP+hrp9cG6KfB",
+	"HTTPW2pebZWPt0d4Rl28pJ1BU2HPQOQPkAGKDqDg2YUU3bos/qt+Y0Uv2R6f4D4grlWo42wshmGT1Wbd",
+	"qZr8PZOjdSv9g9NwtVKvHTlbl8LZQukE+pb1NKWT6XYVxt3lFxNmQvKKVJUkA4mWqIEkY3Ld8pvudCil",
+	"7Z7Sp0v84JUyeIOPnvSbdQeepKBh11nHqvieu+BeiVNEsRLnKaUezQ2CEOZG9Cf4OP6X+hXRiW2fdd9R",
+	"2p9Yt+evv0FRGZ6GgF8/tv7x9wnrt9Y165+sf/rHPiRkYGa59dupiQlJTi6OpPndlpvln6jL4m24tuP5",
+	"2mwrjqPCwbbZHorz3614brhcX7Wt3z1wnHv4H9VyrY6Yx98te02/voojj4qcFT0XG3vZAmdBos9xq1DJ",
+	"8g4ZSTtRC9iIChVT+yAt2kJ7EAi8RbG0g3jvMO1IvUFIYNguOl6d6Alnzj3WJWZRCmYNY8lPumnMEHcu",
+	"x/7L0AYnBctMve6MjEODNhrsgmeQfQTfPJeVtWpcpHvsrdEuQLeaYQTEaxYt+dZEH5HQ1e68qZIrbXIb",
+	"kLZJQ5bcHIoZtKN19dqiUuLiP45oxZqkZGfhdtPS+yxRvAlxqYXvRnWjWRfSEJc7B/P/zZFaxUDLjtS+",
+	"9qHXnSEOe7BYGfeb7vA+/Wx1Dn52DhNGh4sWzDXdyxYwuHAS6HVum/T2hO5C9kVxn5GmcZRPoSq6nQXa",
+	"Iwm3Niex8jPEGPZ5aeGBVXXq5VWnmjrFjNhHDxFTFMiIWlN6ujtqZbj3rCd8Ygo68CxcW8vCLbhX5uem",
+	"P7n1/szcnd/f+Pi9O5/OfvLejU/fsA1duOOcuiCXyWTX5y/3k7JW33HcHXbBHZiGp3CGzBvKlXWVramU",
+	"UfOU0UZMF20CNO/jwEHAymYSo6GBe9ERwUJKYGVI/eDZUMAg2hA004MU6oRchE9uaYH/La3VRDJ71MmL",
+	"BAi2S+mMUc/2M+vZfhIDpumYT3q+tBky/b1Z0rQpg78tKCtAmnKCXCKbnSFfR5GYUSTmNOZdq7JehuVh",
+	"dz1Cj3MNxfa5WBZySINR8ip+3jhUE+K8344YI4m4gW8oL91nm9HX/PFdfVp6P04xikeN2vhfXpczx7Yo",
+	"3m0qtjmDoOa5gR7Dyoo6iS8X0hM/q9D8hHCPnvE7PErjntKwMraVLpgYJiO7h6LqAB6h/iqvJYGdG7nI",
+	"56VjjirQy0bIgwvSs4cPZwQ/lDcijtl2uAY+STk3OGD/vV4ombhF3bypfzJLLaY6b8HiKZWcF8znazvL",
+	"EL4U1slyKQE7ep1DaBpxkhEy1r58d9GgPrDkN2oRzPaw13Fg7XczcHxi84elRjMHWqwUZ6pT+fB00Ej/",
+	"mnUQbNNhBxK6s2XdvjUz98n0v8zcuf776U8+mLlz/caNj9+78eknYxb72WCPJ4MRC658wAdz09dn7tyc",
+	"mZu98Z50TlgHw4IdKT7yW6abQPwCe81nCXYEGEcaeQsue0XWNDXbogGi+HX6sujHjUWSSvsu1iFHW/gp",
+	"hFMWpeNKgdmzRDuv4hNLbZjJDonmHpr5BNVnm1qZmzLIKTXC1BilaoK5cFswxkn2LxEvOXKIhDOpVnvJ",
+	"+mcUpTg8XPg8yfszgC4noclvTZ7+IoS7zIUceL00SEmLPZxs+Xsc2JC1ByTMX+OWJoPuT7amK/BWx78v",
+	"7L+mX4fwchg2psbH616lXF/2gnDqnyf+eaL06PNH/38A8F5SEAAwAQA=",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	res := make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	resolvePath := PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		pathToFile := url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}